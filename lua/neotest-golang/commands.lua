@@ -0,0 +1,123 @@
+--- The `:NeotestGolang {subcommand}` user command.
+
+local logger = require("neotest-golang.lib.logging")
+
+local M = {}
+
+--- @class NeotestGolangSubcommand
+--- @field desc string Description, shown when the subcommand is unknown
+--- @field run fun(args: string[]) Execute the subcommand with its arguments
+
+--- Available subcommands. Features are required lazily, so that registering
+--- the command is cheap.
+--- @type table<string, NeotestGolangSubcommand>
+M.subcommands = {
+  attach = {
+    desc = "Attach the debugger to the running attachable test binary",
+    run = function()
+      require("neotest-golang.features.dap.attach").attach()
+    end,
+  },
+  attachable = {
+    desc = "Run the nearest test as a test binary the debugger can attach to",
+    run = function(args)
+      local pos_id = nil
+      if args[1] == "file" then
+        pos_id = vim.fn.expand("%:p")
+      end
+      require("neotest-golang.features.dap.attach").run(pos_id)
+    end,
+  },
+  ["debug-failure"] = {
+    desc = "Debug the nearest test with a breakpoint where it last failed",
+    run = function()
+      require("neotest-golang.features.dap.failure_breakpoint").debug()
+    end,
+  },
+  diff = {
+    desc = "Diff the expected and actual values of the failed assertion",
+    run = function()
+      require("neotest-golang.features.diff").open()
+    end,
+  },
+  ["golden-update"] = {
+    desc = "Run the nearest test with its update flag to regenerate golden files",
+    run = function(args)
+      local pos_id = nil
+      if args[1] == "file" then
+        pos_id = vim.fn.expand("%:p")
+      end
+      require("neotest-golang.features.golden").update(pos_id)
+    end,
+  },
+  replay = {
+    desc = "Replay a saved `go test -json` log into the test tree",
+    run = function(args)
+      local filepath = table.remove(args, 1)
+      require("neotest-golang.features.replay").replay(filepath, args)
+    end,
+  },
+  shuffle = {
+    desc = "Run the tests of the current file in random order",
+    run = function()
+      require("neotest-golang.features.shuffle").run()
+    end,
+  },
+  ["shuffle-rerun"] = {
+    desc = "Re-run the last shuffled run with the same seed",
+    run = function()
+      require("neotest-golang.features.shuffle").rerun()
+    end,
+  },
+  timeline = {
+    desc = "Show the parallel execution timeline of the last run",
+    run = function()
+      require("neotest-golang.features.timeline").open()
+    end,
+  },
+}
+
+--- Execute the `:NeotestGolang` command.
+--- @param opts table Arguments as passed by `nvim_create_user_command`
+function M.run(opts)
+  local args = vim.deepcopy(opts.fargs)
+  local name = table.remove(args, 1)
+  local subcommand = name and M.subcommands[name]
+  if not subcommand then
+    local lines = { "Usage: :NeotestGolang {subcommand}" }
+    local names = vim.tbl_keys(M.subcommands)
+    table.sort(names)
+    for _, key in ipairs(names) do
+      table.insert(lines, "  " .. key .. ": " .. M.subcommands[key].desc)
+    end
+    logger.warn(table.concat(lines, "\n"), true)
+    return
+  end
+  subcommand.run(args)
+end
+
+--- Complete subcommand names.
+--- @param arg_lead string The leading portion of the argument being completed
+--- @param cmdline string The entire command line
+--- @return string[]
+function M.complete(arg_lead, cmdline)
+  if cmdline:match("^%s*%S+%s+%S+%s") then
+    return {} -- only the subcommand itself is completed
+  end
+  local names = vim.tbl_filter(function(name)
+    return vim.startswith(name, arg_lead)
+  end, vim.tbl_keys(M.subcommands))
+  table.sort(names)
+  return names
+end
+
+--- Register the `:NeotestGolang` user command.
+function M.setup()
+  vim.api.nvim_create_user_command("NeotestGolang", M.run, {
+    nargs = "*",
+    complete = M.complete,
+    desc = "neotest-golang commands",
+  })
+end
+
+return M
@@ -0,0 +1,115 @@
+--- Diagnostics of github.com/onsi/gomega, used with `gomega.NewWithT(t)`.
+--- Failures are reported below a location line without a message, with an
+--- optional description first:
+---
+---   file_test.go:15:
+---       the answer
+---       Expected
+---           <int>: 41
+---       to equal
+---           <int>: 42
+
+local diagnostic_parsers = require("neotest-golang.lib.diagnostic_parsers")
+
+local M = {}
+
+--- Split the lines of a report into the description, the actual value, the
+--- matcher and the expected value. Values are indented below their label.
+--- @param lines string[] Lines of the report, without Go's indentation
+--- @return {description: string[], actual: string[], matcher?: string, expected: string[]}|nil
+function M.split_report(lines)
+  local report = { description = {}, actual = {}, expected = {} }
+  local section = "description"
+  for _, line in ipairs(lines) do
+    if line:match("^%s") then
+      if section == "description" then
+        table.insert(report.description, line)
+      else
+        table.insert(report[section], (line:gsub("^    ", "")))
+      end
+    elseif section == "description" and line == "Expected" then
+      section = "actual"
+    elseif section == "description" then
+      table.insert(report.description, line)
+    elseif section == "actual" and not report.matcher then
+      report.matcher = line
+      section = "expected"
+    end
+  end
+  if section == "description" then
+    return nil
+  end
+  return report
+end
+
+--- Build the diagnostic of a complete report.
+--- @param pending table Pending report with {location, lines}
+--- @return table|nil
+local function emit(pending)
+  local lines = pending.lines
+  while #lines > 0 and vim.trim(lines[#lines]) == "" do
+    table.remove(lines)
+  end
+  local report = M.split_report(lines)
+  if not report then
+    return nil
+  end
+
+  local comparison = nil
+  if report.matcher and #report.expected > 0 then
+    comparison = {
+      expected = table.concat(report.expected, "\n"),
+      actual = table.concat(report.actual, "\n"),
+    }
+  end
+
+  return {
+    filename = pending.location.filename,
+    line_number = pending.location.line_number,
+    message = table.concat(lines, "\n"),
+    severity = vim.diagnostic.severity.ERROR,
+    comparison = comparison,
+  }
+end
+
+--- Parse a line of a gomega report.
+--- @param line string
+--- @param context table
+--- @return table|nil
+function M.parse(line, context)
+  local pending = context.pending
+
+  if pending then
+    local content =
+      diagnostic_parsers.report_line(line, pending.location.indent)
+    if content then
+      table.insert(pending.lines, content)
+      return nil
+    end
+    context.pending = nil
+  end
+
+  local location = diagnostic_parsers.parse_empty_location(line)
+  if location then
+    context.pending = { location = location, lines = {} }
+  end
+  return pending and emit(pending)
+end
+
+--- Emit the report which is still pending when the output ends.
+--- @param context table
+--- @return table|nil
+function M.flush(context)
+  local pending = context.pending
+  context.pending = nil
+  return pending and emit(pending)
+end
+
+---@type DiagnosticParser
+M.parser = {
+  name = "gomega",
+  parse = M.parse,
+  flush = M.flush,
+}
+
+return M
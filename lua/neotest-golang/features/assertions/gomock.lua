@@ -0,0 +1,106 @@
+--- Diagnostics of go.uber.org/mock (and github.com/golang/mock). The mock
+--- controller reports failures from its own source file, with the location
+--- of the expectation or of the unexpected call in the message:
+---
+---   controller.go:269: missing call(s) to *mocks.MockStore.Get(is equal to 1 (int)) /home/user/pkg/service_test.go:25
+---   controller.go:137: Unexpected call to *mocks.MockStore.Get([2]) at /home/user/pkg/service.go:30 because:
+---       expected call at /home/user/pkg/service_test.go:25 doesn't match the argument at index 0.
+---       Got: 2 (int)
+---       Want: is equal to 1 (int)
+---
+--- The diagnostics are placed at the location in the message instead.
+
+local diagnostic_parsers = require("neotest-golang.lib.diagnostic_parsers")
+local diagnostics = require("neotest-golang.lib.diagnostics")
+
+local M = {}
+
+---Captures a missing call and the location of its expectation: "missing call(s) to *mocks.MockStore.Get(...) /path/file_test.go:25"
+---Pattern breakdown: (missing call%(s%) to .-) (message) %s+ (separator) (%S+%.go):(%d+) (location) %s*$ (end)
+M.missing_call_pattern = "^(missing call%(s%) to .-)%s+(%S+%.go):(%d+)%s*$"
+
+---Captures an unexpected call and where it was made: "Unexpected call to *mocks.MockStore.Get([2]) at /path/file.go:30 because: ..."
+---Pattern breakdown: (Unexpected call to .-) (message) at (literal) (%S+%.go):(%d+) (location) (.*) (reason)
+M.unexpected_call_pattern = "^(Unexpected call to .-) at (%S+%.go):(%d+)(.*)$"
+
+--- Build a diagnostic from a pending report.
+--- @param pending table Pending report with {diagnostic, indent, lines}
+--- @return table
+local function emit(pending)
+  local diagnostic = pending.diagnostic
+  local lines = pending.lines
+  while #lines > 0 and vim.trim(lines[#lines]) == "" do
+    table.remove(lines)
+  end
+  if #lines > 0 then
+    diagnostic.message = diagnostic.message .. "\n" .. table.concat(lines, "\n")
+  end
+  return diagnostic
+end
+
+--- Parse a line of a gomock report.
+--- @param line string
+--- @param context table
+--- @return table|nil
+function M.parse(line, context)
+  local pending = context.pending
+
+  if pending then
+    local content = diagnostic_parsers.report_line(line, pending.indent)
+    if content and not diagnostics.parse_go_output_line(line) then
+      table.insert(pending.lines, content)
+      return nil
+    end
+    context.pending = nil
+  end
+
+  local parsed = diagnostics.parse_go_output_line(line)
+  local message = parsed and parsed.message
+  local missing, missing_file, missing_line =
+    (message or ""):match(M.missing_call_pattern)
+  local unexpected, call_file, call_line, reason =
+    (message or ""):match(M.unexpected_call_pattern)
+
+  if missing then
+    context.pending = {
+      diagnostic = {
+        filename = missing_file,
+        line_number = tonumber(missing_line),
+        message = missing,
+        severity = vim.diagnostic.severity.ERROR,
+      },
+      indent = #line:match("^%s*"),
+      lines = {},
+    }
+  elseif unexpected then
+    context.pending = {
+      diagnostic = {
+        filename = call_file,
+        line_number = tonumber(call_line),
+        message = unexpected .. reason,
+        severity = vim.diagnostic.severity.ERROR,
+      },
+      indent = #line:match("^%s*"),
+      lines = {},
+    }
+  end
+  return pending and emit(pending)
+end
+
+--- Emit the report which is still pending when the output ends.
+--- @param context table
+--- @return table|nil
+function M.flush(context)
+  local pending = context.pending
+  context.pending = nil
+  return pending and emit(pending)
+end
+
+---@type DiagnosticParser
+M.parser = {
+  name = "gomock",
+  parse = M.parse,
+  flush = M.flush,
+}
+
+return M
@@ -0,0 +1,62 @@
+--- Diagnostics of gotest.tools/v3/assert. Its failures are printed on Go's
+--- own location lines, e.g.
+---
+---   file_test.go:12: assertion failed: 1 (got int) != 2 (want int)
+---
+--- or, for `assert.DeepEqual`, with a go-cmp diff below:
+---
+---   file_test.go:12: assertion failed:
+---       --- got
+---       +++ want
+---       ...
+---
+--- so the diagnostics of `lib.diagnostics` are refined with the compared
+--- values.
+
+local diff = require("neotest-golang.lib.diff")
+
+local M = {}
+
+---Captures the values of `assert.Equal`: "assertion failed: 1 (got int) != 2 (want int)"
+---Pattern breakdown: (.-) (actual value) %(([^()]*)%) (argument and type) != (.-) (expected value) %(([^()]*)%) (argument and type)
+M.equal_pattern = "^assertion failed: (.-) %(([^()]*)%) != (.-) %(([^()]*)%)$"
+
+--- Refine a diagnostic of a failed assertion.
+--- @param diagnostic table Diagnostic data with {filename, line_number, message, severity}
+--- @return table
+function M.refine(diagnostic)
+  local message = diagnostic.message
+  if not vim.startswith(message, "assertion failed:") then
+    return diagnostic
+  end
+  diagnostic.severity = vim.diagnostic.severity.ERROR
+
+  if diagnostic.comparison then
+    return diagnostic
+  end
+
+  local actual, _, expected = message:match(M.equal_pattern)
+  if actual then
+    diagnostic.comparison = { expected = expected, actual = actual }
+    return diagnostic
+  end
+
+  -- The diff of `assert.DeepEqual(t, actual, expected)`, where the removed
+  -- lines are the actual value
+  local lines = vim.split(message, "\n", { plain = true })
+  if #lines > 2 and vim.startswith(lines[2], "--- ") then
+    diagnostic.comparison = diff.complete({
+      diff = vim.list_slice(lines, 2),
+      removed = "actual",
+    })
+  end
+  return diagnostic
+end
+
+---@type DiagnosticParser
+M.parser = {
+  name = "gotest_tools",
+  refine = M.refine,
+}
+
+return M
@@ -0,0 +1,57 @@
+--- Diagnostics of github.com/matryer/is. Its failures are logged on Go's own
+--- location lines, optionally with the comment of the assertion, e.g.
+---
+---   file_test.go:12: 1 != 2
+---   file_test.go:13: // the answer: 41 != 42
+---   file_test.go:14: not true: ok
+---   file_test.go:15: err: connection refused
+---
+--- As they are logged rather than reported as errors, `lib.diagnostics`
+--- classifies them as hints, and they are refined into errors here.
+
+local M = {}
+
+---Captures an optional comment: "// the answer: 41 != 42"
+---Pattern breakdown: ^// (literal) .-: (comment) (.*) (the failure)
+local comment_pattern = "^// .-: (.*)$"
+
+---Prefixes of the failures of is.True and is.NoErr
+local failure_prefixes = { "not true: ", "err: " }
+
+--- Refine a diagnostic of a failed assertion.
+--- @param diagnostic table Diagnostic data with {filename, line_number, message, severity}
+--- @return table
+function M.refine(diagnostic)
+  local failure = diagnostic.message:match(comment_pattern)
+    or diagnostic.message
+
+  local actual, expected = failure:match("^([^\n]-) != ([^\n]+)$")
+  if actual then
+    diagnostic.severity = vim.diagnostic.severity.ERROR
+    diagnostic.comparison = diagnostic.comparison
+      or { expected = expected, actual = actual }
+    return diagnostic
+  end
+
+  -- The failure of is.Fail
+  if failure == "failed" then
+    diagnostic.severity = vim.diagnostic.severity.ERROR
+    return diagnostic
+  end
+
+  for _, prefix in ipairs(failure_prefixes) do
+    if vim.startswith(failure, prefix) then
+      diagnostic.severity = vim.diagnostic.severity.ERROR
+      return diagnostic
+    end
+  end
+  return diagnostic
+end
+
+---@type DiagnosticParser
+M.parser = {
+  name = "is",
+  refine = M.refine,
+}
+
+return M
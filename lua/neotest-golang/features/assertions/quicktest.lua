@@ -0,0 +1,99 @@
+--- Diagnostics of github.com/frankban/quicktest (and its generic successor
+--- github.com/go-quicktest/qt). Failures are reported below a location line
+--- without a message, in labelled sections:
+---
+---   file_test.go:12:
+---       error:
+---         values are not equal
+---       got:
+---         int(1)
+---       want:
+---         int(2)
+---       stack:
+---         /home/user/pkg/file_test.go:12
+---           qt.Assert(t, got, qt.Equals, want)
+
+local diagnostic_parsers = require("neotest-golang.lib.diagnostic_parsers")
+
+local M = {}
+
+--- Build the diagnostic of a complete report.
+--- @param pending table Pending report with {location, sections}
+--- @return table|nil
+local function emit(pending)
+  local sections = pending.sections
+  if not sections.error then
+    return nil
+  end
+
+  local message = table.concat(sections.error, "\n")
+  if sections.comment then
+    message = message .. ": " .. table.concat(sections.comment, "\n")
+  end
+
+  local comparison = nil
+  if sections.got or sections.want then
+    comparison = {
+      expected = sections.want and table.concat(sections.want, "\n"),
+      actual = sections.got and table.concat(sections.got, "\n"),
+    }
+  end
+
+  return {
+    filename = pending.location.filename,
+    line_number = pending.location.line_number,
+    message = message,
+    severity = vim.diagnostic.severity.ERROR,
+    comparison = comparison,
+  }
+end
+
+--- Parse a line of a quicktest report.
+--- @param line string
+--- @param context table
+--- @return table|nil
+function M.parse(line, context)
+  local pending = context.pending
+
+  if pending then
+    local content =
+      diagnostic_parsers.report_line(line, pending.location.indent)
+    if content then
+      local label = content:match("^(%a[%w ]*):$")
+      if label then
+        pending.section = label
+        pending.sections[label] = {}
+        return nil
+      end
+      if pending.section and content:sub(1, 2) == "  " then
+        table.insert(pending.sections[pending.section], content:sub(3))
+        return nil
+      end
+    end
+    context.pending = nil
+  end
+
+  local location = diagnostic_parsers.parse_empty_location(line)
+  if location then
+    context.pending = { location = location, sections = {} }
+  end
+  return pending and emit(pending)
+end
+
+--- Emit the report which is still pending when the output ends.
+--- @param context table
+--- @return table|nil
+function M.flush(context)
+  local pending = context.pending
+  context.pending = nil
+  return pending and emit(pending)
+end
+
+---@type DiagnosticParser
+M.parser = {
+  name = "quicktest",
+  parse = M.parse,
+  flush = M.flush,
+}
+
+return M
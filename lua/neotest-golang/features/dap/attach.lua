@@ -0,0 +1,103 @@
+--- Run tests as a prebuilt test binary which the debugger can attach to while
+--- it is running, e.g. to inspect a test which hangs only occasionally. The
+--- results keep streaming into the tree while the debugger is attached.
+
+local dap = require("neotest-golang.features.dap")
+local logger = require("neotest-golang.lib.logging")
+
+local M = {}
+
+--- @class AttachableRun
+--- @field binary string Path to the test binary
+--- @field cwd string Directory the test binary runs in
+
+--- The last run which can be attached to.
+--- @type AttachableRun|nil
+local current = nil
+
+--- Run the nearest test, or the given position, so that the debugger can be
+--- attached to it.
+--- @param pos_id string|nil Position to run, defaults to the nearest test
+function M.run(pos_id)
+  require("neotest").run.run({
+    pos_id,
+    extra_args = { attachable = true },
+  })
+end
+
+--- Find the process of a test binary among the descendants of Neovim.
+--- @param binary string Path to the test binary
+--- @return integer|nil
+function M.find_pid(binary)
+  local name = vim.fn.fnamemodify(binary, ":t")
+  local queue = { vim.fn.getpid() }
+  while #queue > 0 do
+    local pid = table.remove(queue, 1)
+    for _, child in ipairs(vim.api.nvim_get_proc_children(pid)) do
+      local proc = vim.api.nvim_get_proc(child)
+      -- Linux truncates process names to 15 characters
+      if
+        proc
+        and (
+          proc.name == name
+          or (#proc.name >= 15 and vim.startswith(name, proc.name))
+        )
+      then
+        return child
+      end
+      table.insert(queue, child)
+    end
+  end
+  return nil
+end
+
+--- Remember a run which is about to start, and show the PID of its test
+--- binary once it runs.
+--- @param binary string Path to the test binary
+--- @param cwd string Directory the test binary runs in
+function M.register(binary, cwd)
+  current = { binary = binary, cwd = cwd }
+  vim.defer_fn(function()
+    if current == nil or current.binary ~= binary then
+      return
+    end
+    local pid = M.find_pid(binary)
+    if pid then
+      logger.info(
+        "Test binary is running with PID "
+          .. pid
+          .. ", attach the debugger with :NeotestGolang attach",
+        true
+      )
+    end
+  end, 1000)
+end
+
+--- Forget a run when it has finished.
+--- @param binary string Path to the test binary
+function M.unregister(binary)
+  if current and current.binary == binary then
+    current = nil
+  end
+end
+
+--- Attach the debugger to the test binary of the running attachable run.
+function M.attach()
+  local pid = current and M.find_pid(current.binary)
+  if not current or not pid then
+    logger.warn(
+      "No test binary is running to attach to, start one with "
+        .. ":NeotestGolang attachable",
+      true
+    )
+    return
+  end
+
+  dap.assert_dap_prerequisites()
+  dap.setup_debugging(current.cwd)
+  local dap_config = dap.get_attach_config(current.cwd, pid)
+  logger.info("Attaching the debugger to PID " .. pid, true)
+  require("dap").run(dap_config)
+end
+
+return M
@@ -0,0 +1,143 @@
+--- DAP (built-in delve adapter) setup related functions.
+---
+--- Registers an nvim-dap adapter which launches `dlv dap` on a free port, so
+--- that only nvim-dap and delve are needed.
+
+local logger = require("neotest-golang.lib.logging")
+local options = require("neotest-golang.options")
+
+local M = {}
+
+--- Name of the nvim-dap adapter registered by this mode.
+M.adapter_name = "neotest-golang-dlv"
+
+--- Get the configured options of the built-in delve adapter.
+--- @return {path?: string, args?: string[], build_flags?: string|string[], substitute_path?: {from: string, to: string}[], initialize_timeout_sec?: integer, detached?: boolean}
+local function get_opts()
+  local dap_dlv_opts = options.get().dap_dlv_opts or {}
+  if type(dap_dlv_opts) == "function" then
+    dap_dlv_opts = dap_dlv_opts()
+  end
+  return dap_dlv_opts
+end
+
+--- Build the nvim-dap server adapter which starts `dlv dap` in the directory.
+--- nvim-dap replaces `${port}` with a free port.
+--- @param cwd string
+--- @return table
+function M.adapter(cwd)
+  local dap_dlv_opts = get_opts()
+  local args = { "dap", "-l", "127.0.0.1:${port}" }
+  vim.list_extend(args, dap_dlv_opts.args or {})
+
+  local detached = dap_dlv_opts.detached
+  if detached == nil then
+    detached = vim.fn.has("win32") == 0
+  end
+
+  return {
+    type = "server",
+    port = "${port}",
+    executable = {
+      command = dap_dlv_opts.path or "dlv",
+      args = args,
+      cwd = cwd,
+      detached = detached,
+    },
+    options = {
+      initialize_timeout_sec = dap_dlv_opts.initialize_timeout_sec or 20,
+    },
+  }
+end
+
+--- The nvim-dap adapter, which starts delve in the `cwd` of the debugged
+--- configuration. As the directory comes from the configuration, one adapter
+--- serves all sessions, also concurrent ones.
+--- @param callback fun(adapter: table)
+--- @param config table
+local function resolve_adapter(callback, config)
+  callback(M.adapter(config.cwd))
+end
+
+---Register the delve adapter with nvim-dap, once.
+---@param _ string Working directory, taken from the DAP configuration instead
+function M.setup_debugging(_)
+  local dap = require("dap")
+  if dap.adapters[M.adapter_name] ~= resolve_adapter then
+    dap.adapters[M.adapter_name] = resolve_adapter
+  end
+end
+
+--- @param test_path string
+--- @param test_name_regex string?
+--- @return table | nil
+function M.get_dap_config(test_path, test_name_regex)
+  local dap_dlv_opts = get_opts()
+
+  -- :help dap-configuration
+  local dap_config = {
+    type = M.adapter_name,
+    name = "Neotest-golang",
+    request = "launch",
+    mode = "test",
+    program = test_path,
+    cwd = test_path,
+    outputMode = "remote",
+  }
+
+  if test_name_regex ~= nil then
+    dap_config.args = { "-test.run", test_name_regex }
+  end
+
+  if dap_dlv_opts.build_flags ~= nil then
+    dap_config.buildFlags = dap_dlv_opts.build_flags
+  end
+
+  if dap_dlv_opts.substitute_path ~= nil then
+    dap_config.substitutePath = dap_dlv_opts.substitute_path
+  end
+
+  return dap_config
+end
+
+--- @param cwd string
+--- @param pid integer
+--- @return table
+function M.get_attach_config(cwd, pid)
+  local dap_config = {
+    type = M.adapter_name,
+    name = "Neotest-golang attach",
+    request = "attach",
+    mode = "local",
+    processId = pid,
+    cwd = cwd,
+  }
+
+  local substitute_path = get_opts().substitute_path
+  if substitute_path ~= nil then
+    dap_config.substitutePath = substitute_path
+  end
+
+  return dap_config
+end
+
+function M.assert_dap_prerequisites()
+  local dap_found = pcall(require, "dap")
+  if not dap_found then
+    local msg = "You must have mfussenegger/nvim-dap installed to use DAP "
+      .. "strategy. See the neotest-golang README for more information."
+    logger.error(msg)
+    error(msg)
+  end
+
+  local dlv = get_opts().path or "dlv"
+  if vim.fn.executable(dlv) == 0 then
+    local msg = "Delve executable not found: "
+      .. dlv
+      .. ". See the neotest-golang README for more information."
+    logger.error(msg)
+    error(msg)
+  end
+end
+
+return M
@@ -5,6 +5,10 @@ local options = require("neotest-golang.options")
 
 local M = {}
 
+--- Number of debug sessions, started by runs which may overlap, which have
+--- not terminated yet.
+local active = 0
+
 ---This will prepare and setup nvim-dap-go for debugging.
 ---@param cwd string
 function M.setup_debugging(cwd)
@@ -20,8 +24,16 @@ function M.setup_debugging(cwd)
   logger.debug({ "Provided dap_go_opts for DAP: ", dap_go_opts })
   require("dap-go").setup(dap_go_opts)
 
-  -- reset nvim-dap-go (and cwd) after debugging with nvim-dap
-  require("dap").listeners.after.event_terminated["neotest-golang-debug"] = function()
+  -- reset nvim-dap-go (and cwd) after debugging with nvim-dap, once no other
+  -- debug session is still using it
+  active = active + 1
+  local listeners = require("dap").listeners.after.event_terminated
+  listeners["neotest-golang-debug"] = function()
+    active = math.max(active - 1, 0)
+    if active > 0 then
+      return
+    end
+    listeners["neotest-golang-debug"] = nil
     logger.debug({
       "Resetting provided dap_go_opts for DAP: ",
       dap_go_opts_original,
@@ -56,6 +68,20 @@ function M.get_dap_config(test_path, test_name_regex)
   return dap_config
 end
 
+--- @param cwd string
+--- @param pid integer
+--- @return table
+function M.get_attach_config(cwd, pid)
+  return {
+    type = "go",
+    name = "Neotest-golang attach",
+    request = "attach",
+    mode = "local",
+    processId = pid,
+    cwd = cwd,
+  }
+end
+
 function M.assert_dap_prerequisites()
   local dap_go_found = pcall(require, "dap-go")
   if not dap_go_found then
@@ -24,7 +24,10 @@ function M.get_dap_config(test_path, test_name_regex)
     dap_manual_config = dap_manual_config()
   end
 
+  -- Copy, so that arguments do not pile up in the configured table
+  dap_manual_config = vim.deepcopy(dap_manual_config)
   dap_manual_config.program = test_path
+  dap_manual_config.cwd = dap_manual_config.cwd or test_path
 
   if test_name_regex ~= nil then
     dap_manual_config.args = dap_manual_config.args or {}
@@ -34,6 +37,26 @@ function M.get_dap_config(test_path, test_name_regex)
   return dap_manual_config
 end
 
+---This will setup a dap configuration to attach to a running test binary
+---@param cwd string
+---@param pid integer
+---@return table
+function M.get_attach_config(cwd, pid)
+  local dap_manual_config = options.get().dap_manual_config or {}
+  if type(dap_manual_config) == "function" then
+    dap_manual_config = dap_manual_config()
+  end
+
+  return {
+    type = dap_manual_config.type or "go",
+    name = "Neotest-golang attach",
+    request = "attach",
+    mode = "local",
+    processId = pid,
+    cwd = cwd,
+  }
+end
+
 ---Dummy function is needed to be corresponding to dap-go setup (just like trait implementation)
 function M.assert_dap_prerequisites() end
 
@@ -0,0 +1,133 @@
+--- Debug a failed test with a temporary breakpoint where it failed: on the
+--- innermost frame of its panic, or else on its last failed assertion.
+
+local lib = require("neotest-golang.lib")
+local logger = require("neotest-golang.lib.logging")
+
+local M = {}
+
+--- Debug the nearest test, or the given position, with a breakpoint where it
+--- failed in the last results.
+--- @param pos_id string|nil Position to debug, defaults to the nearest test
+function M.debug(pos_id)
+  require("neotest").run.run({
+    pos_id,
+    strategy = "dap",
+    extra_args = { debug_at_failure = true },
+  })
+end
+
+--- Determine if a line of a buffer already has a breakpoint.
+--- @param bufnr integer
+--- @param line_number integer 1-based line number
+--- @return boolean
+local function has_breakpoint(bufnr, line_number)
+  local breakpoints = require("dap.breakpoints").get(bufnr)[bufnr] or {}
+  for _, breakpoint in ipairs(breakpoints) do
+    if breakpoint.line == line_number then
+      return true
+    end
+  end
+  return false
+end
+
+--- @class FailureBreakpoint
+--- @field key string Key of the DAP listeners of the breakpoint
+--- @field location FailureLocation Where the breakpoint goes
+--- @field session integer|nil Id of the debug session it was set for
+--- @field bufnr integer|nil Buffer of the breakpoint, once it was set
+
+--- Events after which a debug session is over.
+local end_events = { "event_terminated", "event_exited", "disconnect" }
+
+--- Number of breakpoints set so far, to key their listeners.
+local count = 0
+
+--- Remove a breakpoint set by `set`, along with its listeners. Removing it
+--- again, or one which was never set, does nothing.
+--- @param handle FailureBreakpoint|nil
+function M.remove(handle)
+  if not handle then
+    return
+  end
+  local listeners = require("dap").listeners
+  listeners.before.event_initialized[handle.key] = nil
+  listeners.after.launch[handle.key] = nil
+  for _, event in ipairs(end_events) do
+    listeners.after[event][handle.key] = nil
+  end
+
+  local bufnr = handle.bufnr
+  handle.bufnr = nil
+  if bufnr then
+    vim.schedule(function()
+      if vim.api.nvim_buf_is_valid(bufnr) then
+        require("dap.breakpoints").remove(bufnr, handle.location.line_number)
+      end
+    end)
+  end
+end
+
+--- Set a breakpoint where the test failed, once the debug session of the run
+--- starts, and remove it again when that session ends or fails to launch. A
+--- breakpoint which was already set is left alone.
+--- @param pos_id string Position of the test which is about to be debugged
+--- @return FailureBreakpoint|nil handle To remove the breakpoint with `remove`
+---   when the run ends, also if its session never started
+function M.set(pos_id)
+  local location = lib.failures.get(pos_id)
+  if not location then
+    logger.warn(
+      "No failure recorded for " .. pos_id .. ", debugging without breakpoint",
+      true
+    )
+    return nil
+  end
+
+  count = count + 1
+  --- @type FailureBreakpoint
+  local handle = {
+    key = "neotest-golang-failure-breakpoint-" .. count,
+    location = location,
+  }
+  local listeners = require("dap").listeners
+
+  -- The breakpoint is set before the session handles its initialized event,
+  -- which is when the breakpoints are sent to the debugger
+  listeners.before.event_initialized[handle.key] = function(session)
+    listeners.before.event_initialized[handle.key] = nil
+    handle.session = session.id
+
+    local bufnr = vim.fn.bufadd(location.filename)
+    vim.fn.bufload(bufnr)
+    if has_breakpoint(bufnr, location.line_number) then
+      return
+    end
+    require("dap.breakpoints").set({}, bufnr, location.line_number)
+    handle.bufnr = bufnr
+    logger.info(
+      "Breakpoint set at failure: "
+        .. location.filename
+        .. ":"
+        .. location.line_number
+    )
+  end
+
+  local function remove_for(session)
+    if handle.session and session.id == handle.session then
+      M.remove(handle)
+    end
+  end
+  listeners.after.launch[handle.key] = function(session, err)
+    if err then
+      remove_for(session)
+    end
+  end
+  for _, event in ipairs(end_events) do
+    listeners.after[event][handle.key] = remove_for
+  end
+
+  return handle
+end
+
+return M
@@ -0,0 +1,243 @@
+--- Translate the arguments of the `go test` command into a delve launch
+--- configuration: build flags go into `buildFlags`, test flags are passed to
+--- the test binary with their `-test.` prefix, and flags which make no sense
+--- under delve are dropped.
+
+local cmd = require("neotest-golang.lib.cmd")
+local extra_args = require("neotest-golang.lib.extra_args")
+local golden = require("neotest-golang.lib.golden")
+local options = require("neotest-golang.options")
+
+local M = {}
+
+--- Flags of `go build` (and so `go test`) which delve needs to build the test
+--- binary, and whether they take a value.
+M.build_flags = {
+  asmflags = true,
+  buildvcs = false,
+  cover = false,
+  covermode = true,
+  coverpkg = true,
+  gcflags = true,
+  ldflags = true,
+  mod = true,
+  modfile = true,
+  overlay = true,
+  pgo = true,
+  tags = true,
+  trimpath = false,
+}
+
+--- Flags of the test binary, which `go test` passes on with a `-test.` prefix,
+--- and whether they take a value. The flags `-run` and `-skip` are left out,
+--- as the debugged test is selected by the adapter.
+M.test_flags = {
+  bench = true,
+  benchmem = false,
+  benchtime = true,
+  blockprofile = true,
+  count = true,
+  coverprofile = true,
+  cpu = true,
+  cpuprofile = true,
+  failfast = false,
+  fullpath = false,
+  memprofile = true,
+  mutexprofile = true,
+  outputdir = true,
+  parallel = true,
+  short = false,
+  shuffle = true,
+  timeout = true,
+  trace = true,
+  v = false,
+}
+
+--- Build flags which instrument the test binary. Delve cannot debug such a
+--- binary well, so they are dropped unless asked for.
+M.sanitizer_flags = {
+  asan = false,
+  msan = false,
+  race = false,
+}
+
+--- Flags which make no sense under delve, and whether they take a value.
+M.dropped_flags = {
+  json = false,
+  list = true,
+  p = true,
+  run = true,
+  skip = true,
+  vet = true,
+}
+
+--- Split a `go test` flag like "-tags=integration" or "--count" into its
+--- name and inline value.
+--- @param arg string
+--- @return string|nil name, string|nil value
+local function parse_flag(arg)
+  local name, value = arg:match("^%-%-?([%w%.]+)=(.*)$")
+  if name then
+    return name, value
+  end
+  return arg:match("^%-%-?([%w%.]+)$"), nil
+end
+
+--- Quote a build flag for delve, which splits `buildFlags` like a shell.
+--- @param arg string
+--- @return string
+local function quote(arg)
+  if arg:match("%s") then
+    return '"' .. arg:gsub('"', '\\"') .. '"'
+  end
+  return arg
+end
+
+--- Flags of the test binary which need a binary built with `-cover`. For
+--- `go test` they imply `-cover`, which delve has to be told.
+M.cover_test_flags = {
+  coverprofile = true,
+}
+
+--- Translate `go test` arguments into delve build flags and test binary args.
+--- Arguments after "-args", and flags which are unknown to `go test`, are
+--- passed to the test binary unchanged.
+--- @param args string[] Arguments of `go test`, without package
+--- @param keep_sanitizers? boolean Keep e.g. `-race` as a build flag
+--- @return {build_flags: string[], args: string[]}
+function M.translate(args, keep_sanitizers)
+  local translated = { build_flags = {}, args = {} }
+  local cover = false
+  local needs_cover = false
+  local i = 1
+  while i <= #args do
+    local arg = args[i]
+    local name, value = parse_flag(arg)
+    if arg == "-args" or arg == "--args" then
+      for j = i + 1, #args do
+        table.insert(translated.args, args[j])
+      end
+      break
+    elseif name == nil then
+      table.insert(translated.args, arg)
+    else
+      -- "-test.v" is the same flag as "-v" for `go test`
+      name = name:gsub("^test%.", "")
+      cover = cover or name == "cover"
+      needs_cover = needs_cover or M.cover_test_flags[name] == true
+      local takes_value = M.build_flags[name]
+      if takes_value == nil and keep_sanitizers then
+        takes_value = M.sanitizer_flags[name]
+      end
+      local destination = translated.build_flags
+      if takes_value == nil then
+        takes_value = M.test_flags[name]
+        destination = translated.args
+      end
+      if takes_value == nil then
+        takes_value = M.dropped_flags[name]
+        if takes_value == nil then
+          takes_value = M.sanitizer_flags[name]
+        end
+        destination = nil
+      end
+      if takes_value == nil then
+        -- Custom flag of the test binary, e.g. "-update"
+        takes_value = false
+        destination = translated.args
+        name = arg:match("^%-%-?([^=]+)")
+      elseif destination == translated.args then
+        name = "test." .. name
+      end
+
+      if value == nil and takes_value and args[i + 1] then
+        value = args[i + 1]
+        i = i + 1
+      end
+      if destination then
+        local flag = "-" .. name
+        if value ~= nil then
+          flag = flag .. "=" .. value
+        end
+        table.insert(destination, flag)
+      end
+    end
+    i = i + 1
+  end
+  if needs_cover and not cover then
+    table.insert(translated.build_flags, "-cover")
+  end
+  return translated
+end
+
+--- Resolve the `go test` arguments of the current run, the same way as for
+--- the test command, and translate them for delve.
+--- @param keep_sanitizers? boolean Keep e.g. `-race` as a build flag
+--- @return {build_flags: string[], args: string[]}
+function M.resolve(keep_sanitizers)
+  local args = extra_args.get().go_test_args or options.get().go_test_args
+  if type(args) == "function" then
+    args = args()
+  end
+  args = vim.list_extend(vim.deepcopy(args or {}), cmd.fullpath_args())
+  args = vim.list_extend(args, cmd.shuffle_args())
+  args = vim.list_extend(args, golden.update_args())
+  return M.translate(args, keep_sanitizers)
+end
+
+--- Resolve the environment variables of the current run, the same way as for
+--- the test command.
+--- @return table<string, string>|nil
+function M.resolve_env()
+  local env = extra_args.get().env or options.get().env
+  if type(env) == "function" then
+    env = env()
+  end
+  if env == nil or vim.tbl_isempty(env) then
+    return nil
+  end
+  return env
+end
+
+--- Join build flags into the string which delve expects in `buildFlags`.
+--- @param build_flags string[]
+--- @return string
+function M.join_build_flags(build_flags)
+  return table.concat(vim.tbl_map(quote, build_flags), " ")
+end
+
+--- Add the arguments, build flags and environment of the current run to a
+--- DAP launch configuration. What the configuration already sets, e.g. in
+--- `dap_manual_config` or `dap_go_opts`, comes first and takes precedence.
+--- @param dap_config table
+--- @return table
+function M.apply(dap_config)
+  local resolved = M.resolve()
+
+  dap_config.args = vim.list_extend(dap_config.args or {}, resolved.args)
+
+  if #resolved.build_flags > 0 then
+    local build_flags = dap_config.buildFlags
+    if type(build_flags) == "function" then
+      build_flags = build_flags()
+    end
+    local joined = M.join_build_flags(resolved.build_flags)
+    if type(build_flags) == "table" then
+      dap_config.buildFlags =
+        vim.list_extend(vim.deepcopy(build_flags), resolved.build_flags)
+    elseif build_flags ~= nil and build_flags ~= "" then
+      dap_config.buildFlags = build_flags .. " " .. joined
+    else
+      dap_config.buildFlags = joined
+    end
+  end
+
+  local env = M.resolve_env()
+  if env then
+    dap_config.env = vim.tbl_extend("force", env, dap_config.env or {})
+  end
+
+  return dap_config
+end
+
+return M
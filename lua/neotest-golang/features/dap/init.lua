@@ -1,7 +1,11 @@
 --- DAP setup related functions.
 
+local go_test_args = require("neotest-golang.features.dap.go_test_args")
+local cmd = require("neotest-golang.lib.cmd")
+local convert = require("neotest-golang.lib.convert")
 local logger = require("neotest-golang.lib.logging")
 local options = require("neotest-golang.options")
+local test2json = require("neotest-golang.lib.test2json")
 
 ---@class DapConfig
 ---@field type string DAP adapter type (e.g., "go")
@@ -9,6 +13,7 @@ local options = require("neotest-golang.options")
 ---@field mode string DAP mode (e.g., "test", "debug")
 ---@field program string Path to program to debug
 ---@field args? string[] Optional arguments to pass to the program
+---@field buildFlags? string|string[] Optional flags to build the program with
 ---@field env? table<string, string> Optional environment variables
 
 local M = {}
@@ -26,6 +31,8 @@ local function get_dap_implementation()
     dap_impl = require("neotest-golang.features.dap.dap_go")
   elseif selected_dap_mode == "manual" then
     dap_impl = require("neotest-golang.features.dap.dap_manual")
+  elseif selected_dap_mode == "dlv" then
+    dap_impl = require("neotest-golang.features.dap.dap_dlv")
   else
     local msg = "Got dap-mode: `"
       .. selected_dap_mode
@@ -51,7 +58,82 @@ end
 --- @return DapConfig|nil DAP configuration table or nil if unavailable
 function M.get_dap_config(test_path, test_name_regex)
   local dap_impl = get_dap_implementation()
-  return dap_impl.get_dap_config(test_path, test_name_regex)
+  local dap_config = dap_impl.get_dap_config(test_path, test_name_regex)
+  if dap_config == nil then
+    return nil
+  end
+  dap_config = go_test_args.apply(dap_config)
+  -- Frame the output for test2json, to collect results after the session
+  dap_config.args = test2json.with_verbose_arg(dap_config.args)
+  return dap_config
+end
+
+--- List the top-level tests of the package in the directory which match the
+--- pattern, with `go test -list` in the environment of the run.
+--- @param test_path string Directory path containing tests
+--- @param pattern string Regex to match top-level test names
+--- @return string[]|nil tests The test names, or nil if they cannot be listed
+--- @return string|nil err
+function M.list_tests(test_path, pattern)
+  local command = { "go", "test", "-list", pattern }
+  vim.list_extend(command, go_test_args.resolve().build_flags)
+  table.insert(command, ".")
+  logger.debug("Listing tests: " .. table.concat(command, " "))
+  local result = cmd.system(
+    command,
+    { cwd = test_path, env = go_test_args.resolve_env(), text = true }
+  )
+  if result.code ~= 0 then
+    return nil, (result.stderr or "") .. (result.stdout or "")
+  end
+
+  local tests = {}
+  for _, line in ipairs(vim.split(result.stdout or "", "\n")) do
+    -- The last line is the "ok <package>" summary
+    line = vim.trim(line)
+    if line ~= "" and not line:match("^ok%s") then
+      table.insert(tests, line)
+    end
+  end
+  return tests, nil
+end
+
+--- Build the `-test.run` pattern which makes delve run exactly the given test,
+--- after checking with `go test -list` that its top-level test exists. Subtests
+--- are only known at runtime, so only the top-level test can be checked.
+--- @param test_path string Directory path containing tests
+--- @param test_name string Go test name like "TestName/SubTest/Nested"
+--- @return string|nil pattern The pattern, or nil if no test matches
+function M.test_run_pattern(test_path, test_name)
+  local top_level = vim.split(test_name, "/", { plain = true })[1]
+  local top_level_pattern = convert.to_exact_test_run_pattern(top_level)
+
+  local tests, err = M.list_tests(test_path, top_level_pattern)
+  if tests == nil then
+    -- Let delve report the build failure
+    logger.debug({ "Could not list tests: ", err })
+  elseif not vim.tbl_contains(tests, top_level) then
+    logger.warn(
+      "No test matches '"
+        .. test_name
+        .. "' in "
+        .. test_path
+        .. ", not starting the debugger",
+      true
+    )
+    return nil
+  end
+
+  return convert.to_exact_test_run_pattern(test_name)
+end
+
+--- Get DAP configuration for attaching to a running test binary
+--- @param cwd string Directory the test binary runs in
+--- @param pid integer Process ID of the test binary
+--- @return table DAP configuration table
+function M.get_attach_config(cwd, pid)
+  local dap_impl = get_dap_implementation()
+  return dap_impl.get_attach_config(cwd, pid)
 end
 
 --- Assert that DAP prerequisites are met for debugging
@@ -0,0 +1,47 @@
+--- Open the expected and actual values of a failed assertion side by side.
+
+local lib = require("neotest-golang.lib")
+local logger = require("neotest-golang.lib.logging")
+
+local M = {}
+
+--- Show lines in the current window, in a scratch buffer which is part of
+--- the diff.
+--- @param name string Name of the buffer
+--- @param lines string[]
+local function show(name, lines)
+  local bufnr = vim.api.nvim_get_current_buf()
+  vim.bo[bufnr].buftype = "nofile"
+  vim.bo[bufnr].bufhidden = "wipe"
+  vim.bo[bufnr].swapfile = false
+  vim.api.nvim_buf_set_lines(bufnr, 0, -1, false, lines)
+  vim.bo[bufnr].modifiable = false
+  pcall(vim.api.nvim_buf_set_name, bufnr, name)
+  vim.cmd("diffthis")
+end
+
+--- Open the comparison of the failed assertion under the cursor (or the
+--- closest one above it) in a new tab, expected on the left and actual on the
+--- right.
+function M.open()
+  local file_path = vim.fn.expand("%:p")
+  local line = vim.api.nvim_win_get_cursor(0)[1] - 1
+  local comparison = lib.diff.find(file_path, line)
+  if not comparison then
+    logger.warn(
+      "No expected/actual values recorded for failed assertions in "
+        .. file_path,
+      true
+    )
+    return
+  end
+
+  local expected, actual = lib.diff.sides(comparison)
+  vim.cmd("tabnew")
+  show("expected", expected)
+  vim.cmd("vnew")
+  show("actual", actual)
+  vim.cmd("wincmd L")
+end
+
+return M
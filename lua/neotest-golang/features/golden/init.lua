@@ -0,0 +1,40 @@
+--- Run golden file tests with their update flag, to regenerate the golden
+--- files they compare against.
+
+local lib = require("neotest-golang.lib")
+local logger = require("neotest-golang.lib.logging")
+
+local M = {}
+
+--- Run tests with the update flag declared by their package, e.g.
+--- `go test -run TestFoo -args -update`.
+--- @param pos_id string|nil Position to run, defaults to the nearest test
+function M.update(pos_id)
+  local file_path = vim.fn.expand("%:p")
+  if pos_id then
+    file_path = lib.path.extract_file_path_from_pos_id(pos_id) or pos_id
+  end
+  local dir = file_path
+  if vim.fn.isdirectory(dir) == 0 then
+    dir = lib.path.get_directory(file_path)
+  end
+
+  local flag = lib.golden.find_update_flag(dir)
+  if not flag then
+    logger.warn(
+      "No update flag declared by the tests in "
+        .. dir
+        .. ', e.g. flag.Bool("update", false, "update golden files")',
+      true
+    )
+    return
+  end
+
+  logger.info("Updating golden files with -" .. flag)
+  require("neotest").run.run({
+    pos_id,
+    extra_args = { golden_update = flag },
+  })
+end
+
+return M
@@ -0,0 +1,44 @@
+--- Replay a saved `go test -json` log, e.g. a CI artifact, into the test tree.
+
+local lib = require("neotest-golang.lib")
+local logger = require("neotest-golang.lib.logging")
+
+local M = {}
+
+--- Replay a `go test -json` log into the tree of the current working
+--- directory. Statuses, output and diagnostics are populated the same way as
+--- when running the tests, but nothing is executed.
+--- @param filepath string|nil Path to the log
+--- @param mapping_args? string[] Path mappings like "/home/runner/work/repo/repo=~/code/repo"
+function M.replay(filepath, mapping_args)
+  if not filepath or filepath == "" then
+    logger.warn("Usage: :NeotestGolang replay {file} [{from}={to} ...]", true)
+    return
+  end
+
+  filepath = vim.fn.fnamemodify(vim.fn.expand(filepath), ":p")
+  if vim.fn.filereadable(filepath) ~= 1 then
+    logger.warn("Cannot replay unreadable file: " .. filepath, true)
+    return
+  end
+
+  local path_mappings, invalid =
+    lib.replay.parse_path_mappings(mapping_args or {})
+  if #invalid > 0 then
+    logger.warn(
+      "Invalid path mappings, expected {from}={to}: "
+        .. table.concat(invalid, " "),
+      true
+    )
+    return
+  end
+
+  require("neotest").run.run({
+    vim.fn.getcwd(),
+    extra_args = {
+      replay = { filepath = filepath, path_mappings = path_mappings },
+    },
+  })
+end
+
+return M
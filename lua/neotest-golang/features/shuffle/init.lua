@@ -0,0 +1,60 @@
+--- Run tests in random order with `go test -shuffle`, and re-run them with the
+--- seed of the last run to reproduce an order-dependent failure.
+
+local lib = require("neotest-golang.lib")
+local logger = require("neotest-golang.lib.logging")
+
+local M = {}
+
+--- Run the tests of a position in random order.
+--- @param pos_id string|nil Position to run, defaults to the current file
+function M.run(pos_id)
+  require("neotest").run.run({
+    pos_id or vim.fn.expand("%:p"),
+    extra_args = { shuffle = "on" },
+  })
+end
+
+--- Re-run the position of the last run, in the same order as before.
+--- @param pos_id string The position to run
+--- @param seed string The seed of the run
+local function rerun_with_seed(pos_id, seed)
+  logger.info("Re-running " .. pos_id .. " with -shuffle=" .. seed)
+  require("neotest").run.run({
+    pos_id,
+    extra_args = { shuffle = seed },
+  })
+end
+
+--- Re-run the last shuffled run with the same seed. When packages were
+--- shuffled with different seeds, the seed of the failed package is used; if
+--- several packages failed, the seed is picked interactively.
+function M.rerun()
+  local last_run = lib.shuffle.get()
+  if not last_run.pos_id or #last_run.seeds == 0 then
+    logger.warn(
+      "No shuffle seed recorded yet, run tests with -shuffle=on first",
+      true
+    )
+    return
+  end
+
+  local candidates = lib.shuffle.candidates(last_run.seeds)
+  if #candidates == 1 then
+    rerun_with_seed(last_run.pos_id, candidates[1].seed)
+    return
+  end
+
+  vim.ui.select(candidates, {
+    prompt = "Re-run with the shuffle seed of:",
+    format_item = function(entry)
+      return entry.package .. " (" .. entry.status .. "): " .. entry.seed
+    end,
+  }, function(choice)
+    if choice then
+      rerun_with_seed(last_run.pos_id, choice.seed)
+    end
+  end)
+end
+
+return M
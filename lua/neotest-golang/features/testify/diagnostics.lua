@@ -1,3 +1,4 @@
+local diff = require("neotest-golang.lib.diff")
 local options = require("neotest-golang.options")
 
 require("neotest-golang.lib.types")
@@ -20,6 +21,10 @@ local testify_messages_pattern = "^%s*Messages:%s+(.*)"
 ---Pattern breakdown: ^%s* (optional whitespace) Test:%s+ (literal)
 local testify_test_pattern = "^%s*Test:%s+"
 
+---Captures the content of a continuation line of the error details: "        	            	expected: 1"
+---Pattern breakdown: ^%s*\t%s*\t (indentation and empty label column) (.*) (content)
+local testify_details_pattern = "^%s*\t%s*\t(.*)"
+
 ---Parse a testify assertion output line for Error Trace
 ---@param line string The line to parse
 ---@return table|nil Parsed data with {filename, line_number, message} or nil if no match
@@ -38,11 +43,10 @@ local function parse_testify_trace_line(line)
     return nil
   end
 
-  -- Extract just the filename from the full path
-  local filename = filepath:match("([^/]+%.go)$") or filepath
-
+  -- Keep the full path, so that the diagnostic is matched with the test file
+  -- by absolute path
   return {
-    filename = filename,
+    filename = filepath,
     line_number = line_number,
     message = "assertion failed", -- Generic message, actual error comes from subsequent lines
   }
@@ -57,9 +61,41 @@ local function emit_diagnostic(pending)
     line_number = pending.line_number,
     message = pending.error_message,
     severity = vim.diagnostic.severity.ERROR,
+    comparison = pending.comparison and diff.complete(pending.comparison),
   }
 end
 
+---Collect the expected and actual values, and the diff, of a failed equality
+---assertion from the lines below "Error:"
+---@param pending table Pending diagnostic state
+---@param line string A line of the error details
+local function parse_comparison_line(pending, line)
+  local content = line:match(testify_details_pattern)
+  if not content then
+    return
+  end
+
+  local expected = content:match("^expected:%s?(.*)")
+  local actual = content:match("^actual%s*:%s?(.*)")
+  if expected then
+    pending.comparison = pending.comparison or {}
+    pending.comparison.expected = expected
+    pending.comparison_section = "expected"
+  elseif actual and pending.comparison then
+    pending.comparison.actual = actual
+    pending.comparison_section = "actual"
+  elseif content:match("^Diff:") and pending.comparison then
+    pending.comparison.diff = {}
+    pending.comparison_section = "diff"
+  elseif pending.comparison_section == "diff" then
+    table.insert(pending.comparison.diff, content)
+  elseif pending.comparison_section then
+    -- Values which span multiple lines
+    local section = pending.comparison_section
+    pending.comparison[section] = pending.comparison[section] .. "\n" .. content
+  end
+end
+
 ---Parse testify-specific diagnostic patterns
 ---
 ---Testify assertions produce multiline error output that must be parsed incrementally.
@@ -71,13 +107,21 @@ end
 ---    Error:      	Not equal:
 ---                	expected: 1
 ---                	actual  : 2
+---                	
+---                	Diff:
+---                	--- Expected
+---                	+++ Actual
+---                	@@ -1 +1 @@
+---                	-1
+---                	+2
 ---    Test:       	TestExample
 ---    Messages:   	custom error message
 ---```
 ---
 ---The parser transitions through these states:
 ---  1. Wait for "Error Trace:" to capture file location
----  2. Wait for "Error:" to capture the assertion message (may span multiple lines)
+---  2. Wait for "Error:" to capture the assertion message (may span multiple lines),
+---     collecting the expected and actual values and the diff of equality assertions
 ---  3. Wait for "Test:" line (signals end of multiline error details)
 ---  4. Optionally capture "Messages:" line (custom user message appended to error)
 ---  5. Emit the complete diagnostic
@@ -148,7 +192,29 @@ function M.parse_testify_diagnostic(line, context)
   end
 
   -- Still in multiline error details, keep accumulating
+  parse_comparison_line(pending, line)
   return nil, context
 end
 
+---Emit the testify diagnostic which is still pending when the output ends
+---@param context table Context of the multi-line parsing
+---@return table|nil Diagnostic data with {filename, line_number, message, severity} or nil if nothing is pending
+function M.flush_testify_diagnostic(context)
+  local pending = context.testify_pending
+  context.testify_pending = nil
+  if pending and pending.error_message then
+    return emit_diagnostic(pending)
+  end
+  return nil
+end
+
+---The testify parser of the diagnostic parser registry, see
+---`lib.diagnostic_parsers`
+---@type DiagnosticParser
+M.parser = {
+  name = "testify",
+  parse = M.parse_testify_diagnostic,
+  flush = M.flush_testify_diagnostic,
+}
+
 return M
@@ -0,0 +1,29 @@
+--- Show the parallel execution timeline of the last test run.
+
+local lib = require("neotest-golang.lib")
+local logger = require("neotest-golang.lib.logging")
+
+local M = {}
+
+--- Open a scratch buffer showing, per package, when each test of the last run
+--- was running and when it was paused.
+function M.open()
+  local timelines = lib.timeline.get()
+  if vim.tbl_isempty(timelines) then
+    logger.warn("No test timeline recorded yet, run some tests first", true)
+    return
+  end
+
+  local buf = vim.api.nvim_create_buf(false, true)
+  vim.api.nvim_buf_set_lines(buf, 0, -1, false, lib.timeline.render(timelines))
+  vim.bo[buf].modifiable = false
+  vim.bo[buf].bufhidden = "wipe"
+  vim.bo[buf].filetype = "neotest-golang-timeline"
+
+  vim.cmd("botright split")
+  vim.api.nvim_win_set_buf(0, buf)
+  vim.wo.wrap = false
+  vim.keymap.set("n", "q", "<cmd>close<cr>", { buffer = buf, nowait = true })
+end
+
+return M
@@ -109,29 +109,17 @@ end
 --- @param file_path string Absolute file path
 --- @return neotest.Tree | nil
 function M.Adapter.discover_positions(file_path)
+  -- Warm the 'go list' cache, so that running tests starts right away
+  lib.cmd.golist_prefetch(lib.path.get_directory(file_path))
   return query.detect_tests(file_path)
 end
 
---- Build the runspec, which describes what command(s) are to be executed.
+--- Build the runspec of a position, with the state of its run bound.
 --- @param args neotest.RunArgs
+--- @param tree neotest.Tree
+--- @param pos neotest.Position
 --- @return neotest.RunSpec | neotest.RunSpec[] | nil
-function M.Adapter.build_spec(args)
-  -- Set extra args so that they can be past to the "go test" command later down the line.
-  lib.extra_args.set(args.extra_args)
-
-  --- The tree object, describing the AST-detected tests and their positions.
-  --- @type neotest.Tree
-  local tree = args.tree
-
-  if not tree then
-    logger.error("Unexpectedly did not receive a neotest.Tree.")
-    return
-  end
-
-  --- The position object, describing the current directory, file or test.
-  --- @type neotest.Position
-  local pos = tree:data() -- NOTE: causes <file> is not accessible by the current user!
-
+local function build_runspec(args, tree, pos)
   -- Below is the main logic of figuring out how to execute tests. In short,
   -- a "runspec" is defined for each command to execute.
   -- Neotest also distinguishes between different "position types":
@@ -153,6 +141,18 @@ function M.Adapter.build_spec(args)
   -- while this adapter is being developed, it can be useful to have such
   -- functionality.
 
+  if lib.extra_args.get().replay then
+    -- A runspec is to be created, based on replaying a saved `go test -json`
+    -- log, without running any tests.
+    return runspec.replay.build(pos, tree, lib.extra_args.get().replay)
+  end
+
+  if lib.extra_args.get().attachable then
+    -- A runspec is to be created, based on running a prebuilt test binary
+    -- which the debugger can attach to.
+    return runspec.attachable.build(pos, tree)
+  end
+
   if pos.type == "dir" and pos.path == vim.fn.getcwd() then
     -- A runspec is to be created, based on running all tests in the given
     -- directory. In this case, the directory is also the current working
@@ -182,6 +182,34 @@ function M.Adapter.build_spec(args)
   )
 end
 
+--- Build the runspec, which describes what command(s) are to be executed.
+--- @param args neotest.RunArgs
+--- @return neotest.RunSpec | neotest.RunSpec[] | nil
+function M.Adapter.build_spec(args)
+  --- The tree object, describing the AST-detected tests and their positions.
+  --- @type neotest.Tree
+  local tree = args.tree
+
+  if not tree then
+    logger.error("Unexpectedly did not receive a neotest.Tree.")
+    return
+  end
+
+  --- The position object, describing the current directory, file or test.
+  --- @type neotest.Position
+  local pos = tree:data() -- NOTE: causes <file> is not accessible by the current user!
+
+  -- Keep the state of this run, including its extra args which are passed to
+  -- the "go test" command later down the line, apart from other runs. The
+  -- position is remembered, so that it can be re-run with the same
+  -- `go test -shuffle` seed. The state is only bound while the runspec is
+  -- built, the runspec carries it in its context afterwards.
+  local state = lib.run_state.new(pos.id, args.extra_args)
+  return lib.run_state.with(state, function()
+    return build_runspec(args, tree, pos)
+  end)
+end
+
 --- Process the test command output and result. Populate test outcome into the
 --- Neotest internal tree structure.
 --- @async
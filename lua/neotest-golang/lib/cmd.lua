@@ -4,7 +4,9 @@ local async = require("neotest.async")
 
 local cgo = require("neotest-golang.lib.cgo")
 local extra_args = require("neotest-golang.lib.extra_args")
-local json = require("neotest-golang.lib.json")
+local goenv = require("neotest-golang.lib.goenv")
+local golden = require("neotest-golang.lib.golden")
+local golist = require("neotest-golang.lib.golist")
 local logger = require("neotest-golang.lib.logging")
 local options = require("neotest-golang.options")
 local path = require("neotest-golang.lib.path")
@@ -14,33 +16,19 @@ require("neotest-golang.lib.types")
 
 local M = {}
 
---- Call 'go list -json {go_list_args...} ./...' to get test file data
+--- Get the 'go list' data of the packages in, and below, a directory. The
+--- output of 'go list -json {go_list_args...} ./...' is cached per module root.
 --- @param cwd string Working directory to run 'go list' from
---- @return GoListItem[], string|nil
+--- @return GoListItem[], string[]|nil
 function M.golist_data(cwd)
-  local cmd = M.golist_command()
-  local go_list_command_concat = table.concat(cmd, " ")
-  logger.info("Running Go list: " .. go_list_command_concat .. " in " .. cwd)
-  local result = vim.system(cmd, { cwd = cwd, text = true }):wait()
-
-  local err = nil
-  if result.code == 1 then
-    err = "go list:"
-    if result.stdout ~= nil and result.stdout ~= "" then
-      err = err .. " " .. result.stdout
-    end
-    if result.stdout ~= nil and result.stderr ~= "" then
-      err = err .. " " .. result.stderr
-    end
-    logger.warn({ "Go list error: ", err }, true)
-  end
-
-  local output = result.stdout or ""
+  return golist.get(cwd, M.golist_command())
+end
 
-  ---@type GoListItem[]
-  local golist_output = json.decode_from_string(output)
-  logger.debug({ "JSON-decoded 'go list' output: ", golist_output })
-  return golist_output, err
+--- Start fetching the 'go list' data of the module of a directory in the
+--- background, so that it is ready by the time tests are run.
+--- @param cwd string Directory in the module
+function M.golist_prefetch(cwd)
+  golist.prefetch(cwd, M.golist_command())
 end
 
 --- Build the 'go list' command with optimized output format
@@ -51,14 +39,17 @@ function M.golist_command()
 
   -- NOTE: optimized command only outputs fields needed.
   -- NOTE: Dir and GoMod needs %q to escape backslashes on Windows.
+  -- NOTE: -e reports errors per package, in Errors, instead of failing.
   local cmd = {
     "go",
     "list",
+    "-e",
     "-f",
     [[{
     "Dir": {{printf "%q" .Dir}},
     "ImportPath": "{{.ImportPath}}",
     "Name": "{{.Name}}",
+    {{if or .Error .DepsErrors}}"Errors": [{{if .Error}}{{printf "%q" .Error}}{{end}}{{range $i, $e := .DepsErrors}}{{if or $i $.Error}},{{end}}{{printf "%q" $e}}{{end}}],{{end}}
     "TestGoFiles": [{{range $i, $f := .TestGoFiles}}{{if ne $i 0}},{{end}}"{{$f}}"{{end}}],
     "XTestGoFiles": [{{range $i, $f := .XTestGoFiles}}{{if ne $i 0}},{{end}}"{{$f}}"{{end}}],
     "Module": { "GoMod": {{printf "%q" .Module.GoMod}} }
@@ -79,6 +70,7 @@ end
 --- @return string[], string|nil
 function M.test_command_in_package(package_or_path)
   local go_test_required_args = { package_or_path }
+  vim.list_extend(go_test_required_args, M.fullpath_args())
   local cmd, json_filepath = M.test_command(go_test_required_args, true)
   return cmd, json_filepath
 end
@@ -89,6 +81,7 @@ end
 --- @return string[], string|nil
 function M.test_command_in_package_with_regexp(package_or_path, regexp)
   local go_test_required_args = { package_or_path, "-run", regexp }
+  vim.list_extend(go_test_required_args, M.fullpath_args())
   local cmd, json_filepath = M.test_command(go_test_required_args, true)
   return cmd, json_filepath
 end
@@ -144,6 +137,9 @@ function M.go_test(go_test_required_args)
 
   cmd = vim.list_extend(vim.deepcopy(cmd), go_test_required_args)
   cmd = vim.list_extend(vim.deepcopy(cmd), args)
+  cmd = vim.list_extend(vim.deepcopy(cmd), M.shuffle_args())
+  -- NOTE: arguments after -args are passed to the test binary, so they go last
+  cmd = vim.list_extend(vim.deepcopy(cmd), golden.update_args())
   return cmd
 end
 
@@ -174,9 +170,37 @@ function M.gotestsum(go_test_required_args, json_filepath)
   cmd = vim.list_extend(vim.deepcopy(cmd), { "--" })
   cmd = vim.list_extend(vim.deepcopy(cmd), go_test_required_args)
   cmd = vim.list_extend(vim.deepcopy(cmd), go_test_args)
+  cmd = vim.list_extend(vim.deepcopy(cmd), M.shuffle_args())
+  -- NOTE: arguments after -args are passed to the test binary, so they go last
+  cmd = vim.list_extend(vim.deepcopy(cmd), golden.update_args())
   return cmd
 end
 
+--- Build the '-fullpath' argument, which makes Go print the full path of files
+--- in test output (e.g. "/home/user/pkg/foo_test.go:12: message"), so that
+--- diagnostics are matched with files by absolute path. Requires Go 1.21+.
+--- @return string[]
+function M.fullpath_args()
+  if goenv.is_go_version_at_least(1, 21) then
+    return { "-fullpath" }
+  end
+  return {}
+end
+
+--- Build the '-shuffle' argument, when tests are to be run in random order.
+--- The 'shuffle' extra arg is either "on" or the seed of a previous run.
+--- @return string[]
+function M.shuffle_args()
+  local shuffle = extra_args.get().shuffle
+  if shuffle == nil or shuffle == false then
+    return {}
+  end
+  if shuffle == true then
+    shuffle = "on"
+  end
+  return { "-shuffle=" .. tostring(shuffle) }
+end
+
 --- Handle runner fallback when executable is not available
 --- @param executable string Name of the executable to check
 --- @return RunnerType The actual runner to use after fallback
@@ -190,6 +214,24 @@ function M.runner_fallback(executable)
   return options.get().runner
 end
 
+--- Run a command to completion. Within an async task, the task waits for the
+--- command without blocking the editor.
+--- @param command string[]
+--- @param opts vim.SystemOpts|nil Options of `vim.system`
+--- @return vim.SystemCompleted
+function M.system(command, opts)
+  if not async.current_task() then
+    return vim.system(command, opts):wait()
+  end
+  local future = async.control.future()
+  vim.system(command, opts, function(result)
+    vim.schedule(function()
+      future.set(result)
+    end)
+  end)
+  return future.wait()
+end
+
 --- Check if an executable is available in the system PATH
 --- @param executable string Name of the executable to check
 --- @return boolean True if executable is found and executable
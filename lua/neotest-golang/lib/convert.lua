@@ -57,6 +57,49 @@ function M.to_gotest_regex_pattern(test_name)
   return table.concat(segments, "/")
 end
 
+--- Unicode space characters (besides ASCII whitespace) which Go replaces with
+--- an underscore in subtest names.
+local unicode_spaces = {
+  "\194\133", -- U+0085
+  "\194\160", -- U+00A0
+  "\226\128\128", -- U+2000
+  "\226\128\129", -- U+2001
+  "\226\128\130", -- U+2002
+  "\226\128\131", -- U+2003
+  "\226\128\132", -- U+2004
+  "\226\128\133", -- U+2005
+  "\226\128\134", -- U+2006
+  "\226\128\135", -- U+2007
+  "\226\128\136", -- U+2008
+  "\226\128\137", -- U+2009
+  "\226\128\138", -- U+200A
+  "\226\128\168", -- U+2028
+  "\226\128\169", -- U+2029
+  "\226\128\175", -- U+202F
+  "\226\129\159", -- U+205F
+  "\227\128\128", -- U+3000
+}
+
+--- Escapes of the ASCII control characters which Go quotes with a letter.
+local control_escapes = { ["\a"] = "\\a", ["\b"] = "\\b" }
+
+---Rewrite a subtest name the way `t.Run` does (see `rewrite` in Go's
+---testing/match.go): each space character becomes an underscore, and
+---non-printable characters are replaced by their escape sequence.
+---@param name string Subtest name as written in the test source
+---@return string Subtest name as reported by `go test`
+function M.rewrite_subtest_name(name)
+  for _, space in ipairs(unicode_spaces) do
+    name = name:gsub(space, "_")
+  end
+  name = name:gsub("%s", "_")
+  name = name:gsub("[%z\1-\31\127]", function(character)
+    return control_escapes[character]
+      or string.format("\\x%02x", character:byte())
+  end)
+  return name
+end
+
 ---Convert AST-detected Neotest position ID to `go test` test name format
 ---@param pos_id string Neotest position ID like /path/file.go::TestName::"SubTest"::"Nested"
 ---@return string|nil Go test name like "TestName/SubTest/Nested" or nil if invalid
@@ -85,14 +128,27 @@ function M.pos_id_to_go_test_name(pos_id)
       -- Sub-test name: strip surrounding quotes, unescape inner quotes, normalize whitespace to underscore
       local sub = part:gsub('^"(.*)"$', "%1")
       sub = sub:gsub('\\"', '"')
-      sub = sub:gsub("%s+", "_")
-      table.insert(go_test_parts, sub)
+      table.insert(go_test_parts, M.rewrite_subtest_name(sub))
     end
   end
 
   return table.concat(go_test_parts, "/")
 end
 
+---Convert a `go test` test name into a `-test.run` pattern which only matches
+---that exact test: every character which has a meaning in a Go regexp is
+---escaped (like `regexp.QuoteMeta`) and each level is anchored.
+---@param test_name string Go test name like "TestName/SubTest/Nested"
+---@return string Pattern like "^TestName$/^SubTest$/^Nested$"
+function M.to_exact_test_run_pattern(test_name)
+  local segments = {}
+  for _, segment in ipairs(vim.split(test_name, "/", { plain = true })) do
+    local escaped = segment:gsub("[\\%.%+%*%?%(%)|%[%]{}%^%$]", "\\%0")
+    table.insert(segments, "^" .. escaped .. "$")
+  end
+  return table.concat(segments, "/")
+end
+
 ---Convert `go test` test name to Neotest position ID format
 ---@param go_test_name string Go test name like "TestName/SubTest/Nested"
 ---@return string Neotest format like TestName::"SubTest"::"Nested"
@@ -0,0 +1,203 @@
+--- Registry of diagnostic parsers for assertion libraries.
+---
+--- Go's own `file.go:12: message` lines are parsed by `lib.diagnostics`. On
+--- top of that, every line of a test's output is handed to each parser of the
+--- `diagnostic_parsers` option, so that the output of assertion libraries
+--- which print their own format (e.g. testify's "Error Trace") becomes
+--- diagnostics too. Each parser keeps its own multi-line context.
+---
+--- A parser is a table like this, where all functions are optional:
+---
+---   {
+---     name = "mylib",
+---     -- Parse a line of output, and return a diagnostic once complete
+---     parse = function(line, context) end,
+---     -- Return the diagnostic still pending when the output ends
+---     flush = function(context) end,
+---     -- Adjust a diagnostic parsed from Go's `file.go:12: message` lines
+---     refine = function(diagnostic) end,
+---   }
+---
+--- Diagnostics are tables with {filename, line_number, message, severity,
+--- comparison?}.
+
+local logger = require("neotest-golang.lib.logging")
+local options = require("neotest-golang.options")
+
+local M = {}
+
+--- @class DiagnosticParser
+--- @field name string Unique name of the parser
+--- @field parse? fun(line: string, context: table): table|nil
+--- @field flush? fun(context: table): table|nil
+--- @field refine? fun(diagnostic: table): table|nil
+
+--- Modules of the built-in parsers, by name.
+M.builtin = {
+  testify = "neotest-golang.features.testify.diagnostics",
+  gotest_tools = "neotest-golang.features.assertions.gotest_tools",
+  quicktest = "neotest-golang.features.assertions.quicktest",
+  is = "neotest-golang.features.assertions.is",
+  gomega = "neotest-golang.features.assertions.gomega",
+  gomock = "neotest-golang.features.assertions.gomock",
+}
+
+--- Parsers registered at runtime, in addition to the configured ones.
+--- @type DiagnosticParser[]
+local registered = {}
+
+--- Register a parser, e.g. from another plugin.
+--- @param parser DiagnosticParser
+function M.register(parser)
+  for i, existing in ipairs(registered) do
+    if existing.name == parser.name then
+      registered[i] = parser
+      return
+    end
+  end
+  table.insert(registered, parser)
+end
+
+--- Remove all parsers registered at runtime.
+function M.clear_registered()
+  registered = {}
+end
+
+--- Get the parsers to run: the configured ones, followed by the ones
+--- registered at runtime.
+--- @return DiagnosticParser[]
+function M.get()
+  local parsers = {}
+  for _, entry in ipairs(options.get().diagnostic_parsers or {}) do
+    if type(entry) == "string" then
+      local module = M.builtin[entry]
+      if module then
+        table.insert(parsers, require(module).parser)
+      else
+        logger.warn("Unknown diagnostic parser: " .. entry)
+      end
+    elseif type(entry) == "table" and entry.name then
+      table.insert(parsers, entry)
+    else
+      logger.warn({ "Invalid diagnostic parser: ", entry })
+    end
+  end
+  vim.list_extend(parsers, registered)
+  return parsers
+end
+
+--- Call a function of a parser, logging errors instead of raising them, so
+--- that a broken parser does not break the results of a run.
+--- @param parser DiagnosticParser
+--- @param fn_name string
+--- @return table|nil
+local function call(parser, fn_name, ...)
+  local ok, result = pcall(parser[fn_name], ...)
+  if not ok then
+    logger.warn(
+      "Diagnostic parser "
+        .. parser.name
+        .. " failed in "
+        .. fn_name
+        .. ": "
+        .. tostring(result)
+    )
+    return nil
+  end
+  return result
+end
+
+--- Get the context of a parser within the context of all parsers.
+--- @param context table
+--- @param parser DiagnosticParser
+--- @return table
+local function parser_context(context, parser)
+  context.parsers = context.parsers or {}
+  context.parsers[parser.name] = context.parsers[parser.name] or {}
+  return context.parsers[parser.name]
+end
+
+--- Hand a line of output to all parsers.
+--- @param line string
+--- @param context table Context of all parsers, kept across the lines of a test
+--- @param parsers? DiagnosticParser[] The parsers to run, defaults to `M.get()`
+--- @return table[] Diagnostics completed by this line
+function M.parse_line(line, context, parsers)
+  local diagnostics = {}
+  for _, parser in ipairs(parsers or M.get()) do
+    if parser.parse then
+      local diagnostic =
+        call(parser, "parse", line, parser_context(context, parser))
+      if diagnostic then
+        table.insert(diagnostics, diagnostic)
+      end
+    end
+  end
+  return diagnostics
+end
+
+--- Collect the diagnostics still pending when the output ends.
+--- @param context table Context of all parsers
+--- @param parsers? DiagnosticParser[] The parsers to run, defaults to `M.get()`
+--- @return table[]
+function M.flush(context, parsers)
+  local diagnostics = {}
+  for _, parser in ipairs(parsers or M.get()) do
+    if parser.flush then
+      local diagnostic = call(parser, "flush", parser_context(context, parser))
+      if diagnostic then
+        table.insert(diagnostics, diagnostic)
+      end
+    end
+  end
+  return diagnostics
+end
+
+--- Let all parsers adjust a diagnostic parsed from Go's output lines, e.g. to
+--- classify an assertion library's message as an error.
+--- @param diagnostic table
+--- @param parsers? DiagnosticParser[] The parsers to run, defaults to `M.get()`
+--- @return table
+function M.refine(diagnostic, parsers)
+  for _, parser in ipairs(parsers or M.get()) do
+    if parser.refine then
+      diagnostic = call(parser, "refine", diagnostic) or diagnostic
+    end
+  end
+  return diagnostic
+end
+
+---Captures a location line without a message, which assertion libraries print
+---their multi-line report below: "    file_test.go:12: "
+---Pattern breakdown: ^(%s*) (indentation) (.*go) (file) :(%d+): (number) %s*$ (no message)
+M.empty_location_pattern = "^(%s*)(.*go):(%d+):%s*$"
+
+--- Parse a location line without a message.
+--- @param line string
+--- @return {filename: string, line_number: integer, indent: integer}|nil
+function M.parse_empty_location(line)
+  local indent, filename, line_number = line:match(M.empty_location_pattern)
+  if not filename then
+    return nil
+  end
+  return {
+    filename = filename,
+    line_number = tonumber(line_number),
+    indent = #indent,
+  }
+end
+
+--- Get the content of a line of a report below a location line, without the
+--- 4 spaces which Go indents it by.
+--- @param line string
+--- @param indent integer Indentation of the location line
+--- @return string|nil The content, or nil if the line is not part of the report
+function M.report_line(line, indent)
+  local prefix = string.rep(" ", indent + 4)
+  if line:sub(1, #prefix) ~= prefix then
+    return nil
+  end
+  return line:sub(#prefix + 1)
+end
+
+return M
@@ -1,4 +1,11 @@
 local convert = require("neotest-golang.lib.convert")
+local diagnostic_parsers = require("neotest-golang.lib.diagnostic_parsers")
+local diff = require("neotest-golang.lib.diff")
+local file_diagnostics = require("neotest-golang.lib.file_diagnostics")
+local helper_diagnostics = require("neotest-golang.lib.helper_diagnostics")
+local logger = require("neotest-golang.lib.logging")
+local options = require("neotest-golang.options")
+local path = require("neotest-golang.lib.path")
 
 require("neotest-golang.lib.types")
 
@@ -26,12 +33,48 @@ M.error_patterns = {
 M.go_output_pattern = "^%s*(.*go):(%d+): (.*)"
 
 ---Parse Go test output line and classify as hint or error
+---
+---When a context is given, a diagnostic is only emitted once the lines which
+---Go indents below its location line have been parsed, e.g. for
+---`t.Errorf("got %d\nwant %d", got, want)`:
+---```
+---    file_test.go:12: got 1
+---        want 2
+---```
+---These continuation lines are merged into the message, or collected as the
+---diff of a message like "mismatch (-want +got):". The line which ends the
+---diagnostic is not consumed: `context.reparse` is set, and the line must be
+---parsed again.
+---
+---Output in other formats, such as testify's, is parsed by the parsers of
+---`lib.diagnostic_parsers`.
+---
 ---@param line string The line to parse
----@param context table|nil Optional context to maintain state across multiple lines for testify parsing
+---@param context table|nil Optional context to maintain state across multiple lines for multi-line messages
 ---@return table|nil Diagnostic data with {filename, line_number, message, severity} or nil if no match
 ---@return table|nil Updated context for multi-line parsing
 function M.parse_diagnostic_line(line, context)
+  local multiline = context ~= nil
   context = context or {}
+  context.reparse = false
+
+  -- Collect the lines indented below the location line
+  local pending = context.go_pending
+  if pending then
+    local continuation = M.parse_continuation_line(line, pending.indent)
+    if continuation then
+      if pending.diagnostic.comparison then
+        table.insert(pending.diagnostic.comparison.diff, continuation)
+      else
+        table.insert(pending.continuation_lines, continuation)
+      end
+      return nil, context
+    end
+    -- The diagnostic ended, this line still needs to be parsed
+    context.go_pending = nil
+    context.reparse = true
+    return M.finish_diagnostic(pending, context.diagnostic_parsers), context
+  end
 
   -- Try standard Go output parsing first
   local parsed = M.parse_go_output_line(line)
@@ -40,19 +83,29 @@ function M.parse_diagnostic_line(line, context)
     local severity = is_hint and vim.diagnostic.severity.HINT
       or vim.diagnostic.severity.ERROR
 
-    return {
+    local diagnostic = {
       filename = parsed.filename,
       line_number = parsed.line_number,
       message = parsed.message,
       severity = severity,
-    },
-      context
+    }
+
+    -- Collect the diff printed below a message like "mismatch (-want +got):"
+    diagnostic.comparison = diff.parse_cmp_header(parsed.message)
+
+    pending = {
+      diagnostic = diagnostic,
+      indent = #line:match("^%s*"),
+      continuation_lines = {},
+    }
+    if multiline then
+      context.go_pending = pending
+      return nil, context
+    end
+    return M.finish_diagnostic(pending, context.diagnostic_parsers), context
   end
 
-  -- If standard parsing failed, try testify-specific patterns
-  local testify_diagnostics =
-    require("neotest-golang.features.testify.diagnostics")
-  return testify_diagnostics.parse_testify_diagnostic(line, context)
+  return nil, context
 end
 
 ---Parse a single line of Go test output
@@ -86,6 +139,59 @@ function M.parse_go_output_line(line)
   }
 end
 
+---Parse a line which continues the message of a location line. Go indents
+---such lines 4 spaces deeper than the location line.
+---@param line string The line to parse
+---@param indent integer Indentation of the location line
+---@return string|nil The line without the indentation, or nil if the line is not a continuation
+function M.parse_continuation_line(line, indent)
+  local prefix = string.rep(" ", indent + 4)
+  if line:sub(1, #prefix) ~= prefix then
+    return nil
+  end
+  if M.parse_go_output_line(line) then
+    return nil
+  end
+  return line:sub(#prefix + 1)
+end
+
+---Complete a diagnostic once all of its lines were parsed: merge the
+---continuation lines into its message, complete its comparison, and let the
+---diagnostic parsers refine it.
+---@param pending table Pending diagnostic state with {diagnostic, indent, continuation_lines}
+---@param parsers? DiagnosticParser[] Defaults to the configured parsers
+---@return table Diagnostic data with {filename, line_number, message, severity, comparison?}
+function M.finish_diagnostic(pending, parsers)
+  local diagnostic = pending.diagnostic
+  local lines = pending.continuation_lines
+  while #lines > 0 and vim.trim(lines[#lines]) == "" do
+    table.remove(lines)
+  end
+  if #lines > 0 then
+    diagnostic.message = diagnostic.message .. "\n" .. table.concat(lines, "\n")
+    local is_hint = M.is_hint_message(diagnostic.message)
+    diagnostic.severity = is_hint and vim.diagnostic.severity.HINT
+      or vim.diagnostic.severity.ERROR
+  end
+
+  if diagnostic.comparison then
+    diagnostic.comparison = diff.complete(diagnostic.comparison)
+  end
+  return diagnostic_parsers.refine(diagnostic, parsers)
+end
+
+---Emit the diagnostic which is still pending when the output ends.
+---@param context table Context of the multi-line parsing
+---@return table|nil Diagnostic data with {filename, line_number, message, severity} or nil if nothing is pending
+function M.flush_diagnostic_context(context)
+  local pending = context.go_pending
+  context.go_pending = nil
+  if pending then
+    return M.finish_diagnostic(pending, context.diagnostic_parsers)
+  end
+  return nil
+end
+
 ---Determine if a message should be classified as a hint vs error
 ---@param message string The message content to classify
 ---@return boolean True if message should be treated as hint, false for error
@@ -106,10 +212,132 @@ function M.is_hint_message(message)
   return true
 end
 
+---Get a list from an option value which may be a single string.
+---@param value string|string[]|nil
+---@return string[]|nil
+local function as_list(value)
+  if type(value) == "string" then
+    return { value }
+  end
+  return value
+end
+
+---Determine if a value matches any of the glob patterns of a rule's scope.
+---@param value string|nil
+---@param patterns string|string[]|nil Glob patterns, or nil if not scoped
+---@return boolean
+local function in_scope(value, patterns)
+  patterns = as_list(patterns)
+  if not patterns then
+    return true
+  end
+  for _, pattern in ipairs(patterns) do
+    if value and path.matches_glob_pattern(value, pattern) then
+      return true
+    end
+  end
+  return false
+end
+
+---Compiled regexes of the rule patterns, false for invalid patterns.
+---@type table<string, any|false>
+local rule_regexes = {}
+
+---Compile the pattern of a diagnostic rule. Patterns are Vim regexes in very
+---magic mode, e.g. "error|fail", and match case-sensitively regardless of
+---'ignorecase'.
+---@param pattern string
+---@return any|nil regex The compiled regex, or nil if the pattern is invalid
+---@return string|nil err Why the pattern is invalid
+function M.rule_regex(pattern)
+  local ok, regex = pcall(vim.regex, "\\v\\C" .. pattern)
+  if not ok then
+    return nil, tostring(regex)
+  end
+  return regex
+end
+
+---Get the compiled regex of a rule pattern, compiling it once.
+---@param pattern string
+---@return any|nil
+local function cached_rule_regex(pattern)
+  if rule_regexes[pattern] == nil then
+    local regex, err = M.rule_regex(pattern)
+    if not regex then
+      logger.warn(
+        "Invalid pattern of diagnostic rule: " .. pattern .. " (" .. err .. ")"
+      )
+    end
+    rule_regexes[pattern] = regex or false
+  end
+  return rule_regexes[pattern] or nil
+end
+
+---Classify a diagnostic by the `diagnostic_rules` option: the first rule in
+---scope whose regex matches the message decides the severity. Diagnostics
+---which no rule matches keep their severity.
+---@param diagnostic table Diagnostic data with {filename, line_number, message, severity}
+---@param test_file string|nil Absolute path to the test file
+---@param import_path string|nil Import path of the test's package
+---@return table
+function M.apply_rules(diagnostic, test_file, import_path)
+  for _, rule in ipairs(options.get().diagnostic_rules or {}) do
+    if
+      in_scope(test_file, rule.files) and in_scope(import_path, rule.packages)
+    then
+      local regex = cached_rule_regex(rule.pattern)
+      if regex and regex:match_str(diagnostic.message) then
+        local severity = rule.severity
+        if type(severity) == "string" then
+          severity = vim.diagnostic.severity[severity:upper()]
+        end
+        diagnostic.severity = severity or diagnostic.severity
+        return diagnostic
+      end
+    end
+  end
+  return diagnostic
+end
+
+---Find the import path of the package of a test file.
+---@param test_file string|nil Absolute path to the test file
+---@param golist_data GoListItem[]|nil The 'go list -json' output
+---@return string|nil
+local function package_import_path(test_file, golist_data)
+  if not test_file then
+    return nil
+  end
+  local dir = path.get_directory(test_file)
+  for _, item in ipairs(golist_data or {}) do
+    if item.Dir == dir then
+      return item.ImportPath
+    end
+  end
+  return nil
+end
+
+---Determine if the file of a diagnostic is the test file. Full paths, as
+---printed by `go test -fullpath` and testify's "Error Trace", are compared as
+---a whole; bare file names, as printed by older Go versions, by name only.
+---@param filename string File of the diagnostic
+---@param test_file string Absolute path to the test file
+---@return boolean
+function M.is_test_file(filename, test_file)
+  if filename:find("[/\\]") then
+    return file_diagnostics.same_file(filename, test_file)
+  end
+  return filename == convert.pos_id_to_filename(test_file)
+end
+
 ---Process diagnostics.
+---
+---Diagnostics of other files than the test file, e.g. of assertion helpers,
+---are published on the buffers of their files, and the test file gets a hint
+---where the test calls the helper.
 ---@param test_entry TestEntry Test entry with metadata containing output_parts
+---@param golist_data? GoListItem[] The 'go list -json' output, to find the files of helpers in other packages
 ---@return neotest.Error[] Array of diagnostic errors
-function M.process_diagnostics(test_entry)
+function M.process_diagnostics(test_entry, golist_data)
   if
     not test_entry.metadata.output_parts
     or #test_entry.metadata.output_parts == 0
@@ -122,8 +350,54 @@ function M.process_diagnostics(test_entry)
 
   local test_filename =
     convert.pos_id_to_filename(test_entry.metadata.position_id)
+  local test_file =
+    convert.extract_file_path_from_pos_id(test_entry.metadata.position_id)
+  local import_path = package_import_path(test_file, golist_data)
+  local show_log_hints = options.get().show_log_hints ~= false
   local error_set = {}
-  local context = {} -- Context for multi-line parsing (e.g., testify assertions)
+  -- Context for multi-line parsing, also of the parsers. The parsers are
+  -- resolved once, instead of for every line of output.
+  local parsers = diagnostic_parsers.get()
+  local context = { diagnostic_parsers = parsers }
+  local other_file_diagnostics = {}
+
+  local function add_error(err)
+    -- Create a unique key for duplicate detection
+    local error_key = err.line .. ":" .. err.message
+    if not error_set[error_key] then
+      error_set[error_key] = true
+      table.insert(errors, err)
+    end
+  end
+
+  local function add(diagnostic)
+    diagnostic = M.apply_rules(diagnostic, test_file, import_path)
+    if
+      not show_log_hints
+      and diagnostic.severity == vim.diagnostic.severity.HINT
+    then
+      return
+    end
+
+    -- Filter diagnostics by filename if we have both filenames
+    local should_include_diagnostic = true
+    if test_filename and diagnostic.filename then
+      -- Only include diagnostic if it belongs to the test file
+      should_include_diagnostic =
+        M.is_test_file(diagnostic.filename, test_file)
+    end
+
+    if should_include_diagnostic then
+      add_error({
+        line = diagnostic.line_number - 1,
+        message = diagnostic.message,
+        severity = diagnostic.severity,
+        comparison = diagnostic.comparison,
+      })
+    else
+      table.insert(other_file_diagnostics, diagnostic)
+    end
+  end
 
   -- Process each output part directly
   for _, part in ipairs(test_entry.metadata.output_parts) do
@@ -131,39 +405,48 @@ function M.process_diagnostics(test_entry)
       -- Handle multi-line parts by splitting if needed
       local lines = vim.split(part, "\n", { trimempty = true })
       for _, line in ipairs(lines) do
-        -- Use context-aware parsing for multi-line patterns
-        local diagnostic, updated_context =
-          M.parse_diagnostic_line(line, context)
-        context = updated_context or context
-
-        if diagnostic then
-          -- Filter diagnostics by filename if we have both filenames
-          local should_include_diagnostic = true
-          if test_filename and diagnostic.filename then
-            -- Only include diagnostic if it belongs to the test file
-            should_include_diagnostic = (diagnostic.filename == test_filename)
-          end
+        -- Use context-aware parsing for multi-line patterns. A line which
+        -- ends a multi-line diagnostic is parsed again on its own.
+        repeat
+          local diagnostic, updated_context =
+            M.parse_diagnostic_line(line, context)
+          context = updated_context or context
 
-          if should_include_diagnostic then
-            -- Create a unique key for duplicate detection
-            local error_key = (diagnostic.line_number - 1)
-              .. ":"
-              .. diagnostic.message
-
-            if not error_set[error_key] then
-              error_set[error_key] = true
-              table.insert(errors, {
-                line = diagnostic.line_number - 1,
-                message = diagnostic.message,
-                severity = diagnostic.severity,
-              })
-            end
+          if diagnostic then
+            add(diagnostic)
           end
+        until not context.reparse
+
+        -- Output of assertion libraries, e.g. testify's "Error Trace"
+        local parsed = diagnostic_parsers.parse_line(line, context, parsers)
+        for _, diagnostic in ipairs(parsed) do
+          add(diagnostic)
         end
       end
     end
   end
 
+  local pending = M.flush_diagnostic_context(context)
+  if pending then
+    add(pending)
+  end
+  for _, diagnostic in ipairs(diagnostic_parsers.flush(context, parsers)) do
+    add(diagnostic)
+  end
+
+  -- Diagnostics reported from helpers in other files
+  if #other_file_diagnostics > 0 then
+    local hints = helper_diagnostics.process(
+      other_file_diagnostics,
+      test_file,
+      convert.pos_id_to_go_test_name(test_entry.metadata.position_id),
+      golist_data
+    )
+    for _, hint in ipairs(hints) do
+      add_error(hint)
+    end
+  end
+
   return errors
 end
 
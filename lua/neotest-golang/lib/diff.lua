@@ -0,0 +1,162 @@
+--- Expected and actual values of failed comparisons, as printed by testify's
+--- `assert.Equal` or by a go-cmp diff, e.g.
+--- `t.Errorf("Parse() mismatch (-want +got):\n%s", cmp.Diff(want, got))`.
+---
+--- The comparison is attached to the diagnostic of the failed assertion, so
+--- that the expected and actual values can be diffed side by side.
+
+local path = require("neotest-golang.lib.path")
+
+local M = {}
+
+--- @class Comparison
+--- @field expected? string The expected value
+--- @field actual? string The actual value
+--- @field diff? string[] Lines of the diff between expected and actual
+--- @field removed? "expected"|"actual" Which value the `-` lines of the diff belong to
+
+---Captures the legend of a go-cmp diff: "mismatch (-want +got):"
+---Pattern breakdown: %(%- (literal "(-") (%w+) (removed side) %+ (literal " +") (%w+) (added side) %)
+M.cmp_header_pattern = "%(%-(%w+) %+(%w+)%)"
+
+--- Names which go-cmp diffs commonly use for the actual value.
+local actual_names = { got = true, actual = true, have = true }
+
+--- Start a comparison for a message which announces a go-cmp diff.
+--- @param message string The message of the diagnostic
+--- @return Comparison|nil
+function M.parse_cmp_header(message)
+  local removed, added = message:match(M.cmp_header_pattern)
+  if not removed then
+    return nil
+  end
+  if actual_names[removed:lower()] and not actual_names[added:lower()] then
+    return { diff = {}, removed = "actual" }
+  end
+  return { diff = {}, removed = "expected" }
+end
+
+--- Split the lines of a diff into the lines of its removed and added sides.
+--- Both testify's unified diff and go-cmp's report use a one character prefix
+--- of "-", "+" or " " on each line.
+--- @param diff string[]
+--- @return string[] removed, string[] added
+function M.split(diff)
+  local removed = {}
+  local added = {}
+  for _, line in ipairs(diff) do
+    local marker = line:sub(1, 1)
+    local is_header = line:match("^%-%-%- ")
+      or line:match("^%+%+%+ ")
+      or line:match("^@@ ")
+    if not is_header then
+      if marker == "-" then
+        table.insert(removed, line:sub(2))
+      elseif marker == "+" then
+        table.insert(added, line:sub(2))
+      else
+        table.insert(removed, line:sub(2))
+        table.insert(added, line:sub(2))
+      end
+    end
+  end
+  return removed, added
+end
+
+--- Fill in the expected and actual values of a comparison from its diff, when
+--- they were not printed separately, and drop trailing blank lines.
+--- @param comparison Comparison
+--- @return Comparison|nil The comparison, or nil if there is nothing to compare
+function M.complete(comparison)
+  local diff = comparison.diff
+  while diff and #diff > 0 and vim.trim(diff[#diff]) == "" do
+    table.remove(diff)
+  end
+  if diff and #diff == 0 then
+    comparison.diff = nil
+  end
+
+  if comparison.diff and not comparison.expected and not comparison.actual then
+    local removed, added = M.split(comparison.diff)
+    if comparison.removed == "actual" then
+      removed, added = added, removed
+    end
+    comparison.expected = table.concat(removed, "\n")
+    comparison.actual = table.concat(added, "\n")
+  end
+
+  if not comparison.expected and not comparison.actual then
+    return nil
+  end
+  comparison.expected = comparison.expected
+    and comparison.expected:gsub("%s+$", "")
+  comparison.actual = comparison.actual and comparison.actual:gsub("%s+$", "")
+  return comparison
+end
+
+--- Get the lines to show side by side for a comparison. The diff is
+--- preferred, as testify prints full (multi-line) values in its diff.
+--- @param comparison Comparison
+--- @return string[] expected, string[] actual
+function M.sides(comparison)
+  if comparison.diff then
+    local removed, added = M.split(comparison.diff)
+    if comparison.removed == "actual" then
+      return added, removed
+    end
+    return removed, added
+  end
+  return vim.split(comparison.expected or "", "\n"),
+    vim.split(comparison.actual or "", "\n")
+end
+
+--- Failed comparisons of the last results: position ID -> errors with a
+--- comparison.
+--- @type table<string, neotest.Error[]>
+local comparisons_by_position = {}
+
+--- Keep the errors of a result which carry a comparison.
+--- @param position_id string
+--- @param errors neotest.Error[]
+function M.record(position_id, errors)
+  local with_comparison = {}
+  for _, err in ipairs(errors or {}) do
+    if err.comparison then
+      table.insert(with_comparison, err)
+    end
+  end
+  if #with_comparison > 0 then
+    comparisons_by_position[position_id] = with_comparison
+  else
+    comparisons_by_position[position_id] = nil
+  end
+end
+
+--- Find the comparison of a failed assertion in a file: the one on the given
+--- line, or else the closest one above it, or else the first one.
+--- @param file_path string Absolute path to the test file
+--- @param line integer 0-based line number
+--- @return Comparison|nil
+function M.find(file_path, line)
+  local candidates = {}
+  for position_id, errors in pairs(comparisons_by_position) do
+    local pos_file = path.extract_file_path_from_pos_id(position_id)
+    if pos_file == file_path then
+      vim.list_extend(candidates, errors)
+    end
+  end
+  table.sort(candidates, function(a, b)
+    return a.line < b.line
+  end)
+
+  local found = nil
+  for _, err in ipairs(candidates) do
+    if err.line <= line then
+      found = err
+    end
+  end
+  found = found or candidates[1]
+  return found and found.comparison
+end
+
+return M
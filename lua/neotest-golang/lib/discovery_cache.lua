@@ -1,54 +1,278 @@
 --- Discovery cache to prevent redundant test discovery when buffers are opened rapidly.
 --- This addresses issues like DAP-UI triggering multiple discoveries.
+---
+--- Trees are keyed by a hash of the file content, the content of the other
+--- test files of its package, the queries the file is parsed with and the
+--- options which change the tree. With the
+--- `persistent_discovery_cache` option, trees are also kept on disk, so that
+--- a new Neovim session does not parse every test file again.
+---
+--- In memory, a tree is also stamped with the size and modification time of
+--- the file. While the stamp matches, the tree is returned without reading the
+--- file, and the content is only hashed when the stamp changed.
+
+local file = require("neotest-golang.lib.file")
+local logger = require("neotest-golang.lib.logging")
+local options = require("neotest-golang.options")
+local path = require("neotest-golang.lib.path")
 
 local M = {}
 
---- Cache structure: { [file_path] = { tree = neotest.Tree, mtime = number } }
---- @type table<string, {tree: neotest.Tree|nil, mtime: number}>
+--- Version of the on-disk format. Bump it when the format, or the way trees
+--- are built, changes, so that trees written by older versions are ignored.
+local version = 2
+
+--- Cache structure: file path -> tree, with its key and stamp.
+--- @type table<string, {tree: neotest.Tree|nil, key: string, stamp: string|nil}>
 local cache = {}
 
---- Get the modification time of a file.
+--- Directory holding the trees on disk.
+--- @return string
+function M.cache_dir()
+  return path.normalize_path(
+    vim.fn.stdpath("cache") .. "/neotest-golang/discovery"
+  )
+end
+
+--- Get the path of the tree of a file on disk.
+--- @param file_path string Absolute file path
+--- @return string
+function M.disk_path(file_path)
+  return path.normalize_path(
+    M.cache_dir() .. "/" .. vim.fn.sha256(file_path) .. ".json"
+  )
+end
+
+--- Hash the content of a file.
+--- @param file_path string Absolute file path
+--- @return string|nil Hash of the content, or nil if the file can't be read
+local function content_hash(file_path)
+  local ok, lines = pcall(file.read_lines, file_path)
+  if not ok then
+    return nil
+  end
+  return vim.fn.sha256(table.concat(lines, "\n"))
+end
+
+--- Hash source which was read from a file, the same way as `content_hash`
+--- hashes the lines of the file.
+--- @param source string Content of a file
+--- @return string
+local function source_hash(source)
+  if source:sub(-1) == "\n" then
+    source = source:sub(1, -2)
+  end
+  source = source:gsub("\r\n", "\n"):gsub("\r$", "")
+  return vim.fn.sha256(source)
+end
+
+--- Describe a file by its size and modification time, without reading it.
 --- @param file_path string Absolute file path
---- @return number|nil Modification time in seconds, or nil if file doesn't exist
-local function get_mtime(file_path)
+--- @return string|nil Stamp of the file, or nil if it does not exist
+local function stat_stamp(file_path)
   local stat = vim.uv.fs_stat(file_path)
-  if stat then
-    return stat.mtime.sec
+  if not stat then
+    return nil
+  end
+  return stat.size .. ":" .. stat.mtime.sec .. "." .. stat.mtime.nsec
+end
+
+--- Describe the other test files of the package of a file. The tree of a file
+--- depends on those files, e.g. on the update flag of golden files or, with
+--- testify, on the suites declared in them.
+--- @param file_path string Absolute file path
+--- @param describe fun(file_path: string): string|nil Describes a file
+--- @return string
+local function describe_package(file_path, describe)
+  local dir = path.get_directory(file_path)
+  local names = {}
+  for name, type in vim.fs.dir(dir) do
+    if type == "file" and vim.endswith(name, "_test.go") then
+      table.insert(names, name)
+    end
+  end
+  table.sort(names)
+
+  local parts = {}
+  for _, name in ipairs(names) do
+    local filepath = dir .. path.os_path_sep .. name
+    if filepath ~= file_path then
+      table.insert(parts, name .. ":" .. (describe(filepath) or ""))
+    end
+  end
+  return vim.fn.sha256(table.concat(parts, "\n"))
+end
+
+--- Describe the tree of a file: the file and the other test files of its
+--- package, along with the queries and the options.
+--- @param file_path string Absolute file path
+--- @param query string|nil The queries the file is parsed with
+--- @param describe fun(file_path: string): string|nil Describes a file
+--- @return string|nil Description, or nil if the file can't be described
+local function describe_tree(file_path, query, describe)
+  local described = describe(file_path)
+  if not described then
+    return nil
+  end
+
+  local opts = options.get()
+  local parts = {
+    tostring(version),
+    described,
+    vim.fn.sha256(query or ""),
+    "testify_enabled=" .. tostring(opts.testify_enabled == true),
+    describe_package(file_path, describe),
+  }
+  if opts.testify_enabled == true then
+    table.insert(
+      parts,
+      "testify_import_identifier=" .. opts.testify_import_identifier
+    )
+  end
+  return vim.fn.sha256(table.concat(parts, "\n"))
+end
+
+--- Compute the cache key of the tree of a file, from the content of the files.
+--- @param file_path string Absolute file path
+--- @param query string|nil The queries the file is parsed with
+--- @param source string|nil Content the file was parsed from, else it is read
+--- @return string|nil Cache key, or nil if the file can't be read
+function M.key(file_path, query, source)
+  if source == nil then
+    return describe_tree(file_path, query, content_hash)
+  end
+  return describe_tree(file_path, query, function(filepath)
+    if filepath == file_path then
+      return source_hash(source)
+    end
+    return content_hash(filepath)
+  end)
+end
+
+--- Compute the stamp of the tree of a file, from the size and modification
+--- time of the files.
+--- @param file_path string Absolute file path
+--- @param query string|nil The queries the file is parsed with
+--- @return string|nil Stamp, or nil if the file does not exist
+function M.stamp(file_path, query)
+  return describe_tree(file_path, query, stat_stamp)
+end
+
+--- Read the tree of a file from disk.
+--- @param file_path string Absolute file path
+--- @param key string The current cache key of the file
+--- @return neotest.Tree|nil Tree if it is on disk with the same key
+local function read_disk(file_path, key)
+  local disk_path = M.disk_path(file_path)
+  if not vim.uv.fs_stat(disk_path) then
+    return nil
+  end
+
+  local ok, entry = pcall(function()
+    return vim.json.decode(
+      table.concat(file.read_lines(disk_path), "\n"),
+      { luanil = { object = true, array = true } }
+    )
+  end)
+  if not ok or type(entry) ~= "table" or type(entry.tree) ~= "table" then
+    logger.debug("Removing unreadable discovery cache entry: " .. disk_path)
+    os.remove(disk_path)
+    return nil
+  end
+  if entry.key ~= key then
+    return nil
+  end
+
+  local Tree = require("neotest.types").Tree
+  return Tree.from_list(entry.tree, function(pos)
+    return pos.id
+  end)
+end
+
+--- Write the tree of a file to disk.
+--- @param file_path string Absolute file path
+--- @param key string The cache key of the file
+--- @param tree neotest.Tree
+local function write_disk(file_path, key, tree)
+  local disk_path = M.disk_path(file_path)
+  local ok, err = pcall(function()
+    vim.fn.mkdir(M.cache_dir(), "p")
+    local data = vim.json.encode({
+      file_path = file_path,
+      key = key,
+      tree = tree:to_list(),
+    })
+    -- Write to a temporary file first, so that another Neovim session never
+    -- reads a partially written entry
+    local tmp_path = disk_path .. ".tmp"
+    file.write_lines(tmp_path, { data })
+    assert(vim.uv.fs_rename(tmp_path, disk_path))
+  end)
+  if not ok then
+    logger.debug({ "Could not write discovery cache entry: ", err })
   end
-  return nil
 end
 
 --- Get cached discovery result if valid.
---- Returns cached tree if file hasn't been modified since last discovery.
+--- Returns cached tree if the file, the queries and the options are unchanged
+--- since last discovery, also across sessions with the on-disk cache.
 --- @param file_path string Absolute file path
+--- @param query string|nil The queries the file is parsed with
 --- @return neotest.Tree|nil Cached tree if valid, nil if cache miss or stale
-function M.get(file_path)
+function M.get(file_path, query)
+  local stamp = M.stamp(file_path, query)
   local entry = cache[file_path]
-  if not entry then
-    return nil
+  if entry and stamp and entry.stamp == stamp then
+    return entry.tree
   end
 
-  local current_mtime = get_mtime(file_path)
-  if not current_mtime then
+  -- The file was touched, or it is not in memory: compare its content
+  local key = M.key(file_path, query)
+  if not key then
     cache[file_path] = nil
     return nil
   end
-
-  if current_mtime == entry.mtime then
+  if entry and entry.key == key then
+    entry.stamp = stamp
     return entry.tree
   end
-
   cache[file_path] = nil
+
+  if options.get().persistent_discovery_cache == true then
+    local tree = read_disk(file_path, key)
+    if tree then
+      logger.debug("Restored discovered tests from disk: " .. file_path)
+      cache[file_path] = { tree = tree, key = key, stamp = stamp }
+      return tree
+    end
+  end
+
   return nil
 end
 
 --- Store discovery result in cache.
+--- The stamp is to be taken before the file is read, and the tree is keyed by
+--- the source it was parsed from, so that a change to the file while it is
+--- parsed is noticed by the next `get`, rather than the tree of the old
+--- content being stored under the key of the new content.
 --- @param file_path string Absolute file path
 --- @param tree neotest.Tree|nil Discovered test tree
-function M.set(file_path, tree)
-  local mtime = get_mtime(file_path)
-  if mtime then
-    cache[file_path] = { tree = tree, mtime = mtime }
+--- @param query string|nil The queries the file was parsed with
+--- @param stamp string|nil Stamp of the file before it was read, see `stamp`
+--- @param source string|nil Content the file was parsed from
+function M.set(file_path, tree, query, stamp, source)
+  -- Without the source, the file is stamped and read now
+  if source == nil then
+    stamp = M.stamp(file_path, query)
+  end
+  local key = M.key(file_path, query, source)
+  if not key then
+    return
+  end
+
+  cache[file_path] = { tree = tree, key = key, stamp = stamp }
+  if tree and options.get().persistent_discovery_cache == true then
+    write_disk(file_path, key, tree)
   end
 end
 
@@ -56,11 +280,16 @@ end
 --- @param file_path string Absolute file path
 function M.invalidate(file_path)
   cache[file_path] = nil
+  os.remove(M.disk_path(file_path))
 end
 
 --- Clear the entire cache.
-function M.clear()
+--- @param disk boolean|nil Also remove the trees on disk
+function M.clear(disk)
   cache = {}
+  if disk then
+    vim.fn.delete(M.cache_dir(), "rf")
+  end
 end
 
 --- Get cache statistics (for debugging).
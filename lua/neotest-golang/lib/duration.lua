@@ -0,0 +1,126 @@
+--- Helpers for the elapsed time reported by `go test -json` on pass, fail and
+--- skip events.
+
+local options = require("neotest-golang.options")
+require("neotest-golang.lib.types")
+
+local M = {}
+
+---Format elapsed seconds the same way `go test -v` does, e.g. "0.52s".
+---@param elapsed number Elapsed time in seconds
+---@return string
+function M.format(elapsed)
+  return string.format("%.2fs", elapsed)
+end
+
+---Get the configured slow test threshold in seconds.
+---@return number Threshold in seconds, 0 when disabled
+function M.slow_threshold()
+  local threshold = options.get().slow_test_threshold
+  if type(threshold) ~= "number" or threshold <= 0 then
+    return 0
+  end
+  return threshold
+end
+
+---Determine if the elapsed time is above the configured slow test threshold.
+---@param elapsed number|nil Elapsed time in seconds
+---@return boolean
+function M.is_slow(elapsed)
+  local threshold = M.slow_threshold()
+  if threshold == 0 or not elapsed then
+    return false
+  end
+  return elapsed >= threshold
+end
+
+---Build the short result text, e.g. "passed in 0.52s".
+---@param status string The neotest result status
+---@param elapsed number Elapsed time in seconds
+---@return string
+function M.short(status, elapsed)
+  local short = status .. " in " .. M.format(elapsed)
+  if M.is_slow(elapsed) then
+    short = short .. " [slow]"
+  end
+  return short
+end
+
+---Build the header line which is written on top of a test's output.
+---@param elapsed number Elapsed time in seconds
+---@return string
+function M.header(elapsed)
+  local header = "=== Elapsed: " .. M.format(elapsed)
+  if M.is_slow(elapsed) then
+    header = header
+      .. " (slow, threshold "
+      .. M.format(M.slow_threshold())
+      .. ")"
+  end
+  return header .. " ==="
+end
+
+---Collect all tests which ran for longer than the slow test threshold,
+---slowest first.
+---@param gotest_output GoTestEvent[] Array of go test JSON events
+---@return { package: string, test: string, elapsed: number }[]
+function M.slow_tests(gotest_output)
+  local slow = {}
+  if M.slow_threshold() == 0 then
+    return slow
+  end
+
+  for _, e in ipairs(gotest_output) do
+    if
+      e.Test
+      and e.Elapsed
+      and (e.Action == "pass" or e.Action == "fail" or e.Action == "skip")
+      and M.is_slow(e.Elapsed)
+    then
+      table.insert(slow, {
+        package = e.Package or "",
+        test = e.Test,
+        elapsed = e.Elapsed,
+      })
+    end
+  end
+
+  table.sort(slow, function(a, b)
+    if a.elapsed == b.elapsed then
+      return a.test < b.test
+    end
+    return a.elapsed > b.elapsed
+  end)
+  return slow
+end
+
+---Build a summary of slow tests, suitable for output and logging.
+---@param slow { package: string, test: string, elapsed: number }[] Slow tests, as returned by `slow_tests`
+---@return string[] Summary lines, empty if there are no slow tests
+function M.slow_summary_lines(slow)
+  if #slow == 0 then
+    return {}
+  end
+
+  local lines = {
+    "=== Slow tests (threshold "
+      .. M.format(M.slow_threshold())
+      .. "): "
+      .. #slow
+      .. " ===",
+  }
+  for _, entry in ipairs(slow) do
+    table.insert(
+      lines,
+      string.format(
+        "%10s  %s %s",
+        M.format(entry.elapsed),
+        entry.package,
+        entry.test
+      )
+    )
+  end
+  return lines
+end
+
+return M
@@ -0,0 +1,288 @@
+--- Support for Example functions. `go test` runs an Example function only when
+--- it has an `// Output:` or `// Unordered output:` comment, and compares what
+--- it prints with the comment. On a mismatch, it prints the output without a
+--- file and line:
+---
+---   --- FAIL: ExampleAdd (0.00s)
+---   got:
+---   6
+---   want:
+---   5
+---
+--- The mismatch is placed on the output comment, found with tree-sitter.
+
+local convert = require("neotest-golang.lib.convert")
+local file = require("neotest-golang.lib.file")
+local logger = require("neotest-golang.lib.logging")
+local path = require("neotest-golang.lib.path")
+local query_loader = require("neotest-golang.lib.query_loader")
+
+local M = {}
+
+M.output_query = query_loader.load_query("queries/go/example_output.scm")
+
+--- The parsed output query. It is parsed on first use rather than when the
+--- module is loaded, as the Go parser may not be installed.
+--- @type vim.treesitter.Query|nil
+local parsed_output_query = nil
+
+--- Get the parsed output query.
+--- @return vim.treesitter.Query
+local function output_query()
+  if not parsed_output_query then
+    parsed_output_query = vim.treesitter.query.parse("go", M.output_query)
+  end
+  return parsed_output_query
+end
+
+--- Short text of the result of an Example function which is never run.
+M.not_run_short = "not run: Example function has no output comment"
+
+--- Determine if a test is an Example function.
+--- @param test_name string|nil The `go test` name of the test
+--- @return boolean
+function M.is_example(test_name)
+  return test_name ~= nil and test_name:match("^Example") ~= nil
+end
+
+--- Determine if a comment is an output comment, like `go test` does: its
+--- text starts with "output:" or "unordered output:", in any case.
+--- @param text string Text of the comment, including the comment markers
+--- @return boolean
+function M.is_output_comment(text)
+  local content = text:gsub("^//", ""):gsub("^/%*", ""):lower()
+  return content:match("^%s*output:") ~= nil
+    or content:match("^%s*unordered output:") ~= nil
+end
+
+--- Find the output comments of the Example functions of a file.
+--- @param file_path string Path to the test file
+--- @param source string|nil Content of the file, read from disk if not given
+--- @return table<string, integer|false> Example name -> 0-based line of its output comment, or false if it has none
+function M.find_output_comments(file_path, source)
+  if not source then
+    local ok, lines = pcall(file.read_lines, file_path)
+    if not ok then
+      return {}
+    end
+    source = table.concat(lines, "\n")
+  end
+
+  local parser_ok, parser =
+    pcall(vim.treesitter.get_string_parser, source, "go")
+  if not parser_ok or not parser then
+    logger.debug("Could not parse Example functions of " .. file_path)
+    return {}
+  end
+  local root = parser:parse()[1]:root()
+  local query = output_query()
+
+  local examples = {}
+  local comments = {}
+  for _, match in query:iter_matches(root, source, 0, -1, { all = true }) do
+    local captured = {}
+    for id, nodes in pairs(match) do
+      captured[query.captures[id]] = nodes[1]
+    end
+    if captured["example.definition"] then
+      local start_row, _, end_row = captured["example.definition"]:range()
+      table.insert(examples, {
+        name = vim.treesitter.get_node_text(captured["example.name"], source),
+        start_row = start_row,
+        end_row = end_row,
+      })
+    elseif captured["example.comment"] then
+      local node = captured["example.comment"]
+      local row, _, end_row = node:range()
+      table.insert(comments, {
+        row = row,
+        end_row = end_row,
+        text = vim.treesitter.get_node_text(node, source),
+      })
+    end
+  end
+
+  table.sort(comments, function(a, b)
+    return a.row < b.row
+  end)
+
+  local rows = {}
+  for _, example in ipairs(examples) do
+    -- Like `go test`, only the last group of adjacent comments in the body
+    -- is the output comment, when it starts with "Output:"
+    local group = nil
+    for _, comment in ipairs(comments) do
+      if comment.row > example.start_row and comment.row <= example.end_row then
+        if not group or comment.row > group.end_row + 1 then
+          group = { first = comment, end_row = comment.end_row }
+        else
+          group.end_row = comment.end_row
+        end
+      end
+    end
+    rows[example.name] = false
+    if group and M.is_output_comment(group.first.text) then
+      rows[example.name] = group.first.row
+    end
+  end
+  return rows
+end
+
+--- Mark the Example functions of a discovered tree which have no output
+--- comment with `example_not_run = true`, as `go test` never runs them.
+--- @param file_path string Path to the test file
+--- @param tree neotest.Tree Tree of the test file
+--- @param source string|nil Content of the file, read from disk if not given
+--- @return neotest.Tree
+function M.mark_tree(file_path, tree, source)
+  local examples = {}
+  for _, node in tree:iter_nodes() do
+    local pos = node:data()
+    if pos.type == "test" and M.is_example(pos.name) then
+      table.insert(examples, pos)
+    end
+  end
+  if #examples == 0 then
+    return tree
+  end
+
+  local rows = M.find_output_comments(file_path, source)
+  for _, pos in ipairs(examples) do
+    if rows[pos.name] == false then
+      pos.example_not_run = true
+    end
+  end
+  return tree
+end
+
+--- Parse the got and want blocks which `go test` prints for an Example
+--- function whose output does not match its output comment.
+--- @param output_parts string[] Output of the Example function
+--- @return {got: string[], want: string[]}|nil
+function M.parse_mismatch(output_parts)
+  local lines = {}
+  for _, part in ipairs(output_parts) do
+    local split = vim.split(part, "\n", { plain = true })
+    if split[#split] == "" then
+      table.remove(split)
+    end
+    vim.list_extend(lines, split)
+  end
+
+  local failed = false
+  local mismatch = nil
+  local section = nil
+  for _, line in ipairs(lines) do
+    if line:match("^%-%-%- FAIL: ") then
+      failed = true
+      section = nil
+    elseif failed and line == "got:" then
+      mismatch = { got = {}, want = {} }
+      section = "got"
+    elseif mismatch and line == "want:" then
+      section = "want"
+    elseif line:match("^=== ") or line:match("^%-%-%- ") then
+      section = nil
+    elseif section then
+      table.insert(mismatch[section], line)
+    end
+  end
+  return mismatch
+end
+
+--- Diff two lists of lines, with a one character prefix of "-", "+" or " "
+--- on each line.
+--- @param want string[]
+--- @param got string[]
+--- @return string[]
+function M.diff_lines(want, got)
+  -- Lengths of the longest common subsequences of the remaining lines
+  local lcs = {}
+  for i = #want + 1, 1, -1 do
+    lcs[i] = {}
+    for j = #got + 1, 1, -1 do
+      if i > #want or j > #got then
+        lcs[i][j] = 0
+      elseif want[i] == got[j] then
+        lcs[i][j] = lcs[i + 1][j + 1] + 1
+      else
+        lcs[i][j] = math.max(lcs[i + 1][j], lcs[i][j + 1])
+      end
+    end
+  end
+
+  local lines = {}
+  local i, j = 1, 1
+  while i <= #want or j <= #got do
+    if i <= #want and j <= #got and want[i] == got[j] then
+      table.insert(lines, " " .. want[i])
+      i = i + 1
+      j = j + 1
+    elseif i <= #want and (j > #got or lcs[i + 1][j] >= lcs[i][j + 1]) then
+      table.insert(lines, "-" .. want[i])
+      i = i + 1
+    else
+      table.insert(lines, "+" .. got[j])
+      j = j + 1
+    end
+  end
+  return lines
+end
+
+--- Turn the output mismatch of a failed Example function into an error on
+--- its output comment.
+--- @param test_entry TestEntry Test entry with metadata containing output_parts
+--- @return neotest.Error[]
+function M.process(test_entry)
+  local position_id = test_entry.metadata.position_id
+  local test_name = convert.pos_id_to_go_test_name(position_id)
+  if not M.is_example(test_name) then
+    return {}
+  end
+  local mismatch = M.parse_mismatch(test_entry.metadata.output_parts or {})
+  if not mismatch then
+    return {}
+  end
+
+  local file_path = path.extract_file_path_from_pos_id(position_id)
+  local row = file_path and M.find_output_comments(file_path)[test_name]
+  if not row then
+    logger.debug("Could not find the output comment of " .. test_name)
+    return {}
+  end
+
+  local diff = M.diff_lines(mismatch.want, mismatch.got)
+  return {
+    {
+      line = row,
+      message = "output mismatch (-want +got):\n" .. table.concat(diff, "\n"),
+      severity = vim.diagnostic.severity.ERROR,
+      comparison = {
+        expected = table.concat(mismatch.want, "\n"),
+        actual = table.concat(mismatch.got, "\n"),
+        diff = diff,
+        removed = "expected",
+      },
+    },
+  }
+end
+
+--- Show the Example functions of a tree which have no output comment as not
+--- run, instead of leaving them without result.
+--- @param tree neotest.Tree
+--- @param results table<string, neotest.Result>
+--- @return table<string, neotest.Result>
+function M.mark_not_run(tree, results)
+  for _, node in tree:iter_nodes() do
+    local pos = node:data()
+    if pos.example_not_run then
+      results[pos.id] = vim.tbl_extend("force", results[pos.id] or {}, {
+        status = "skipped",
+        short = M.not_run_short,
+      })
+    end
+  end
+  return results
+end
+
+return M
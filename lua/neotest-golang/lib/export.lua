@@ -0,0 +1,347 @@
+--- Export test results as JUnit XML and TAP reports, so that they can be
+--- shared or fed into other tools, regardless of the configured runner.
+
+local logger = require("neotest-golang.lib.logging")
+local options = require("neotest-golang.options")
+local path = require("neotest-golang.lib.path")
+require("neotest-golang.lib.types")
+
+local M = {}
+
+--- @class ExportTest
+--- @field name string Go test name, e.g. "TestFoo/bar"
+--- @field status "passed"|"failed"|"skipped"
+--- @field elapsed number Elapsed time in seconds
+--- @field output string[] Output lines
+--- @field messages string[] Failure messages, from the test's diagnostics
+
+--- @class ExportPackage
+--- @field name string Go package import path
+--- @field status "passed"|"failed"|"skipped"
+--- @field elapsed number Elapsed time in seconds
+--- @field output string[] Package-level output lines
+--- @field tests ExportTest[] Tests, in the order they were started
+
+--- Map a terminal `go test -json` action to a neotest status.
+local statuses = {
+  pass = "passed",
+  fail = "failed",
+  skip = "skipped",
+}
+
+--- Split output into lines, dropping the trailing newline.
+--- @param output string
+--- @return string[]
+local function output_lines(output)
+  return vim.split(output:gsub("\n$", ""), "\n", { plain = true })
+end
+
+--- Build a report of packages and their tests from the raw event stream,
+--- enriched with the failure messages of the finalized results.
+--- @param results table<string, neotest.Result> Finalized results
+--- @param gotest_output GoTestEvent[] Array of go test JSON events
+--- @param lookup table<string, string> Position lookup: "pkg::TestName" -> pos_id
+--- @return ExportPackage[]
+function M.build_report(results, gotest_output, lookup)
+  ---@type ExportPackage[]
+  local packages = {}
+  ---@type table<string, ExportPackage>
+  local package_by_name = {}
+  ---@type table<string, ExportTest>
+  local test_by_key = {}
+
+  local function get_package(name)
+    if not package_by_name[name] then
+      package_by_name[name] = {
+        name = name,
+        status = "skipped",
+        elapsed = 0,
+        output = {},
+        tests = {},
+      }
+      table.insert(packages, package_by_name[name])
+    end
+    return package_by_name[name]
+  end
+
+  for _, e in ipairs(gotest_output) do
+    if e.Package then
+      local pkg = get_package(e.Package)
+      if e.Test then
+        local key = e.Package .. "::" .. e.Test
+        local test = test_by_key[key]
+        if not test then
+          test = {
+            name = e.Test,
+            status = "skipped",
+            elapsed = 0,
+            output = {},
+            messages = {},
+          }
+          test_by_key[key] = test
+          table.insert(pkg.tests, test)
+        end
+        if e.Action == "output" and e.Output then
+          vim.list_extend(test.output, output_lines(e.Output))
+        elseif statuses[e.Action] then
+          test.status = statuses[e.Action]
+          test.elapsed = e.Elapsed or 0
+        end
+      else
+        if e.Action == "output" and e.Output then
+          vim.list_extend(pkg.output, output_lines(e.Output))
+        elseif statuses[e.Action] then
+          pkg.status = statuses[e.Action]
+          pkg.elapsed = e.Elapsed or 0
+        end
+      end
+    end
+  end
+
+  -- Attach the diagnostics of failed tests as failure messages.
+  for key, test in pairs(test_by_key) do
+    local pos_id = lookup[key]
+    local result = pos_id and results[pos_id]
+    if test.status == "failed" and result and result.errors then
+      local file_path = path.extract_file_path_from_pos_id(pos_id)
+      for _, err in ipairs(result.errors) do
+        if err.severity ~= vim.diagnostic.severity.HINT then
+          table.insert(
+            test.messages,
+            string.format("%s:%d: %s", file_path, err.line + 1, err.message)
+          )
+        end
+      end
+    end
+  end
+
+  return packages
+end
+
+--- Escape text for use in XML attributes and character data. Characters
+--- which are not allowed in XML 1.0 are dropped.
+--- @param text string
+--- @return string
+function M.xml_escape(text)
+  local escaped = text
+    :gsub("&", "&amp;")
+    :gsub("<", "&lt;")
+    :gsub(">", "&gt;")
+    :gsub('"', "&quot;")
+    :gsub("'", "&apos;")
+    :gsub("[%z\1-\8\11\12\14-\31]", "")
+  return escaped
+end
+
+--- Count tests per status.
+--- @param tests ExportTest[]
+--- @return { tests: integer, failures: integer, skipped: integer }
+local function count(tests)
+  local counts = { tests = #tests, failures = 0, skipped = 0 }
+  for _, test in ipairs(tests) do
+    if test.status == "failed" then
+      counts.failures = counts.failures + 1
+    elseif test.status == "skipped" then
+      counts.skipped = counts.skipped + 1
+    end
+  end
+  return counts
+end
+
+--- A failed package without failed tests, e.g. due to a build failure or a
+--- failing TestMain, is reported as a test case of its own.
+--- @param pkg ExportPackage
+--- @return ExportTest[]
+local function tests_with_package_failure(pkg)
+  local tests = vim.list_slice(pkg.tests, 1, #pkg.tests)
+  if pkg.status == "failed" and count(pkg.tests).failures == 0 then
+    table.insert(tests, {
+      name = "TestMain",
+      status = "failed",
+      elapsed = pkg.elapsed,
+      output = pkg.output,
+      messages = { "package " .. pkg.name .. " failed" },
+    })
+  end
+  return tests
+end
+
+--- Render the report as JUnit XML.
+--- @param packages ExportPackage[]
+--- @return string[] Lines of the XML document
+function M.to_junit(packages)
+  local body = {}
+  local totals = { tests = 0, failures = 0, skipped = 0, time = 0 }
+
+  for _, pkg in ipairs(packages) do
+    local tests = tests_with_package_failure(pkg)
+    local counts = count(tests)
+    totals.tests = totals.tests + counts.tests
+    totals.failures = totals.failures + counts.failures
+    totals.skipped = totals.skipped + counts.skipped
+    totals.time = totals.time + pkg.elapsed
+
+    table.insert(
+      body,
+      string.format(
+        '  <testsuite name="%s" tests="%d" failures="%d" errors="0" skipped="%d" time="%.3f">',
+        M.xml_escape(pkg.name),
+        counts.tests,
+        counts.failures,
+        counts.skipped,
+        pkg.elapsed
+      )
+    )
+    for _, test in ipairs(tests) do
+      table.insert(
+        body,
+        string.format(
+          '    <testcase classname="%s" name="%s" time="%.3f">',
+          M.xml_escape(pkg.name),
+          M.xml_escape(test.name),
+          test.elapsed
+        )
+      )
+      local output = M.xml_escape(table.concat(test.output, "\n"))
+      if test.status == "failed" then
+        local message = test.messages[1] or "test failed"
+        table.insert(
+          body,
+          string.format(
+            '      <failure message="%s" type="failure">%s</failure>',
+            M.xml_escape(message),
+            M.xml_escape(table.concat(test.messages, "\n"))
+          )
+        )
+      elseif test.status == "skipped" then
+        table.insert(body, '      <skipped message="skipped"></skipped>')
+      end
+      if output ~= "" then
+        table.insert(body, "      <system-out>" .. output .. "</system-out>")
+      end
+      table.insert(body, "    </testcase>")
+    end
+    table.insert(body, "  </testsuite>")
+  end
+
+  local lines = {
+    '<?xml version="1.0" encoding="UTF-8"?>',
+    string.format(
+      '<testsuites tests="%d" failures="%d" errors="0" skipped="%d" time="%.3f">',
+      totals.tests,
+      totals.failures,
+      totals.skipped,
+      totals.time
+    ),
+  }
+  vim.list_extend(lines, body)
+  table.insert(lines, "</testsuites>")
+  return lines
+end
+
+--- Render the report as TAP version 13, one test point per test.
+--- @param packages ExportPackage[]
+--- @return string[] Lines of the TAP document
+function M.to_tap(packages)
+  local points = {}
+  local number = 0
+
+  for _, pkg in ipairs(packages) do
+    table.insert(points, "# " .. pkg.name)
+    for _, test in ipairs(tests_with_package_failure(pkg)) do
+      number = number + 1
+      local description = pkg.name .. " " .. test.name
+      if test.status == "failed" then
+        table.insert(points, "not ok " .. number .. " - " .. description)
+      elseif test.status == "skipped" then
+        table.insert(
+          points,
+          "ok " .. number .. " - " .. description .. " # SKIP"
+        )
+      else
+        table.insert(points, "ok " .. number .. " - " .. description)
+      end
+
+      -- YAML diagnostic block
+      table.insert(points, "  ---")
+      table.insert(
+        points,
+        string.format("  duration_ms: %d", math.floor(test.elapsed * 1000))
+      )
+      if #test.messages > 0 then
+        table.insert(points, "  message: |")
+        for _, message in ipairs(test.messages) do
+          for _, line in ipairs(vim.split(message, "\n", { plain = true })) do
+            table.insert(points, "    " .. line)
+          end
+        end
+      end
+      if test.status ~= "passed" and #test.output > 0 then
+        table.insert(points, "  output: |")
+        for _, line in ipairs(test.output) do
+          table.insert(points, "    " .. line)
+        end
+      end
+      table.insert(points, "  ...")
+    end
+  end
+
+  local lines = { "TAP version 13", "1.." .. number }
+  vim.list_extend(lines, points)
+  return lines
+end
+
+--- Get the configured export paths.
+--- @return { junit?: string, tap?: string }
+function M.get_paths()
+  local paths = options.get().export_results or {}
+  if type(paths) == "function" then
+    paths = paths()
+  end
+  return paths
+end
+
+--- Write the configured JUnit XML and/or TAP reports.
+--- @async
+--- @param results table<string, neotest.Result> Finalized results
+--- @param gotest_output GoTestEvent[] Array of go test JSON events
+--- @param lookup table<string, string> Position lookup: "pkg::TestName" -> pos_id
+--- @param root string|nil Directory which relative export paths are resolved from
+--- @param paths { junit?: string, tap?: string } Export paths, see get_paths
+function M.write(results, gotest_output, lookup, root, paths)
+  if not paths.junit and not paths.tap then
+    return
+  end
+
+  local file = require("neotest-golang.lib.file")
+  local packages = M.build_report(results, gotest_output, lookup)
+  local renderers = { junit = M.to_junit, tap = M.to_tap }
+
+  for format, render in pairs(renderers) do
+    local export_path = paths[format]
+    if export_path then
+      export_path = vim.fn.expand(export_path)
+      if
+        root
+        and not vim.startswith(export_path, "/")
+        and not path.has_drive_letter(export_path)
+      then
+        export_path = root .. path.os_path_sep .. export_path
+      end
+      export_path = path.normalize_path(export_path)
+      vim.fn.mkdir(path.get_directory(export_path), "p")
+
+      local ok, err =
+        pcall(file.write_lines_async, export_path, render(packages))
+      if ok then
+        logger.debug("Exported " .. format .. " report to " .. export_path)
+      else
+        logger.warn(
+          "Failed to export " .. format .. " report: " .. tostring(err)
+        )
+      end
+    end
+  end
+end
+
+return M
@@ -1,18 +1,20 @@
 --- extra_args can provided directly when invoking Neotest.
 --- require('neotest').run.run( { vim.fn.expand('%'), extra_args = { go_test_args = { go_test_args = { "-p=1", "-parallel=10" }, }, }, }, )
+---
+--- The extra args belong to the run which is being built, see run_state.lua.
 
-local M = {}
+local run_state = require("neotest-golang.lib.run_state")
 
-local extra_args = {}
+local M = {}
 
 function M.set(args)
   -- NOTE: we want to ensure that extra_args is not nil, because code in cmd.lua will call
   -- extra_args.go_test_args and we can't be indexing a nil value.
-  extra_args = args or {}
+  run_state.current().extra_args = args or {}
 end
 
 function M.get()
-  return extra_args
+  return run_state.current().extra_args
 end
 
 return M
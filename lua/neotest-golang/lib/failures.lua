@@ -0,0 +1,96 @@
+--- Remember where the tests of the last results failed, so that a test can be
+--- debugged with a breakpoint on its failure: the innermost frame of a panic
+--- outside the standard library, or else the last failed assertion.
+---
+---   panic: runtime error: index out of range [3] with length 3 [recovered]
+---   ...
+---   goroutine 7 [running]:
+---   testing.tRunner.func1.2({0x5a1f20, 0xc000018030})
+---       /usr/local/go/src/testing/testing.go:1632 +0x230
+---   panic({0x5a1f20?, 0xc000018030?})
+---       /usr/local/go/src/runtime/panic.go:770 +0x132
+---   example.com/pkg.Get(...)
+---       /home/user/pkg/list.go:8
+
+local path = require("neotest-golang.lib.path")
+local stack = require("neotest-golang.lib.stack")
+
+local M = {}
+
+--- @class FailureLocation
+--- @field filename string Absolute path to the source file
+--- @field line_number integer 1-based line number
+
+--- Failure locations of the last results: position ID -> location.
+--- @type table<string, FailureLocation>
+local locations_by_position = {}
+
+--- Find the innermost frame outside the standard library of the goroutine
+--- which panicked.
+--- @param output_parts string[]
+--- @return FailureLocation|nil
+function M.panic_location(output_parts)
+  local lines = stack.to_lines(output_parts)
+  local panicked = false
+  for i, line in ipairs(lines) do
+    if line:match("^panic: ") then
+      panicked = true
+    elseif panicked and line:match("^goroutine %d+ %[.-%]:") then
+      local frames = stack.parse_frames(lines, i + 1)
+      -- The frames below the last call to panic() are the panicking code
+      local first = 1
+      for index, frame in ipairs(frames) do
+        if frame.func == "panic" then
+          first = index + 1
+        end
+      end
+      local frame = stack.first_user_frame(vim.list_slice(frames, first))
+      if frame then
+        return { filename = frame.filename, line_number = frame.line_number }
+      end
+      return nil
+    end
+  end
+  return nil
+end
+
+--- Find the line of the last failed assertion among the errors of a test.
+--- @param position_id string
+--- @param errors neotest.Error[]
+--- @return FailureLocation|nil
+function M.assertion_location(position_id, errors)
+  local filename = path.extract_file_path_from_pos_id(position_id)
+  local last = nil
+  for _, err in ipairs(errors or {}) do
+    if err.severity == vim.diagnostic.severity.ERROR and err.line then
+      last = err
+    end
+  end
+  if not filename or not last then
+    return nil
+  end
+  return { filename = filename, line_number = last.line + 1 }
+end
+
+--- Remember where a test failed, or forget it when it did not.
+--- @param position_id string
+--- @param status string Status of the test's result
+--- @param errors neotest.Error[]
+--- @param output_parts string[]
+function M.record(position_id, status, errors, output_parts)
+  if status ~= "failed" then
+    locations_by_position[position_id] = nil
+    return
+  end
+  locations_by_position[position_id] = M.panic_location(output_parts or {})
+    or M.assertion_location(position_id, errors)
+end
+
+--- Get where a test failed in the last results.
+--- @param position_id string
+--- @return FailureLocation|nil
+function M.get(position_id)
+  return locations_by_position[position_id]
+end
+
+return M
@@ -0,0 +1,138 @@
+--- Diagnostics for files other than the file of a test position, e.g. the
+--- source file where a data race happened. Neotest only shows the errors of a
+--- result in the file of its position, so these diagnostics are published on
+--- the buffers of their files under a namespace of their own. Each position
+--- which is run has its own namespace, so that runs which overlap don't
+--- replace each other's diagnostics.
+
+local path = require("neotest-golang.lib.path")
+local run_state = require("neotest-golang.lib.run_state")
+
+local M = {}
+
+--- @class FileDiagnostic
+--- @field line_number integer 1-based line number
+--- @field message string
+--- @field severity integer vim.diagnostic.severity
+
+--- The namespace which the diagnostics of a run are published under.
+--- @param pos_id string|nil The position which is run, defaults to the one of the current run
+--- @return integer
+function M.namespace(pos_id)
+  pos_id = pos_id or run_state.current().pos_id
+  local name = "neotest-golang-file-diagnostics"
+  if pos_id then
+    name = name .. "-" .. pos_id
+  end
+  return vim.api.nvim_create_namespace(name)
+end
+
+--- Forget the diagnostics of the previous run of the same position, before a
+--- new run starts.
+function M.reset()
+  run_state.current().file_diagnostics = { by_file = {}, seen = {} }
+  local namespace = M.namespace()
+  vim.schedule(function()
+    vim.diagnostic.reset(namespace)
+  end)
+end
+
+--- Add a diagnostic for a file.
+--- @param filename string Absolute path to the file
+--- @param diagnostic FileDiagnostic
+function M.add(filename, diagnostic)
+  -- Diagnostics of the run: absolute filename -> diagnostics, and the keys of
+  -- the collected diagnostics, for duplicate detection.
+  local collected = run_state.current().file_diagnostics
+  local diagnostics_by_file, seen = collected.by_file, collected.seen
+
+  local key = filename
+    .. ":"
+    .. diagnostic.line_number
+    .. ":"
+    .. diagnostic.message
+  if seen[key] then
+    return
+  end
+  seen[key] = true
+
+  if not diagnostics_by_file[filename] then
+    diagnostics_by_file[filename] = {}
+  end
+  table.insert(diagnostics_by_file[filename], {
+    line_number = diagnostic.line_number,
+    message = diagnostic.message,
+    severity = diagnostic.severity,
+  })
+end
+
+--- Compare file paths, regardless of path separators.
+--- @param a string
+--- @param b string
+--- @return boolean
+function M.same_file(a, b)
+  return a:gsub("\\", "/") == b:gsub("\\", "/")
+end
+
+--- Get the file of a position, or nil if the position is not a file or test,
+--- e.g. the directory of a package.
+--- @param position_id string|nil
+--- @return string|nil
+function M.position_file(position_id)
+  local pos_file = path.extract_file_path_from_pos_id(position_id or "")
+  if pos_file and pos_file:match("%.go$") then
+    return pos_file
+  end
+  return nil
+end
+
+--- Turn a diagnostic into an error of the position, when it is located in the
+--- position's file. Otherwise, it is added to the diagnostics of its file.
+--- @param diagnostic {filename: string, line_number: integer, message: string, severity: integer}
+--- @param pos_file string|nil The file of the position, see `position_file`
+--- @return neotest.Error|nil
+function M.to_position_error(diagnostic, pos_file)
+  if pos_file and M.same_file(diagnostic.filename, pos_file) then
+    return {
+      line = diagnostic.line_number - 1,
+      message = diagnostic.message,
+      severity = diagnostic.severity,
+    }
+  end
+  M.add(diagnostic.filename, diagnostic)
+  return nil
+end
+
+--- Get the diagnostics of the run.
+--- @return table<string, FileDiagnostic[]>
+function M.get()
+  return run_state.current().file_diagnostics.by_file
+end
+
+--- Publish the collected diagnostics on the buffers of their files. Buffers
+--- are created (but not loaded) for files which are not open yet, so that the
+--- diagnostics show up once the file is opened.
+function M.publish()
+  local collected = M.get()
+  local namespace = M.namespace()
+  vim.schedule(function()
+    for filename, diagnostics in pairs(collected) do
+      if vim.fn.filereadable(filename) == 1 then
+        local bufnr = vim.fn.bufadd(filename)
+        local items = {}
+        for _, diagnostic in ipairs(diagnostics) do
+          table.insert(items, {
+            lnum = diagnostic.line_number - 1,
+            col = 0,
+            message = diagnostic.message,
+            severity = diagnostic.severity,
+            source = "neotest-golang",
+          })
+        end
+        vim.diagnostic.set(namespace, bufnr, items)
+      end
+    end
+  end)
+end
+
+return M
@@ -1,5 +1,6 @@
---- Go environment utilities for detecting GOPATH/GOROOT paths.
---- Used to prevent discovering tests in Go's stdlib or installed packages.
+--- Go environment utilities for detecting GOPATH/GOROOT paths and the Go
+--- version. Used to prevent discovering tests in Go's stdlib or installed
+--- packages, and to only pass flags which the Go toolchain supports.
 
 local logger = require("neotest-golang.lib.logging")
 local path = require("neotest-golang.lib.path")
@@ -10,7 +11,11 @@ local M = {}
 -- @type {gopath: string, goroot: string} | nil
 local go_env_cache = nil
 
---- Populate the go env cache (async version).
+-- Cache for the Go version (populated on first call), false if unknown
+-- @type {major: integer, minor: integer} | false | nil
+local go_version_cache = nil
+
+--- Populate the go env cache (async version), along with the Go version.
 --- A failing 'go env' is cached as empty paths, which match nothing, so the
 --- command is executed at most once per session.
 --- @async
@@ -18,16 +23,20 @@ local go_env_cache = nil
 local function get_go_env_async()
   if go_env_cache == nil then
     local async = require("neotest.async")
-    local result = async.fn.system({ "go", "env", "GOPATH", "GOROOT" })
+    local result =
+      async.fn.system({ "go", "env", "GOPATH", "GOROOT", "GOVERSION" })
     if vim.v.shell_error ~= 0 then
       -- A failing 'go env' does not throw, it reports its error on stderr.
       logger.error(
-        "Command 'go env GOPATH GOROOT' exited with code "
+        "Command 'go env GOPATH GOROOT GOVERSION' exited with code "
           .. vim.v.shell_error
           .. ": "
           .. vim.trim(result or "")
       )
       go_env_cache = { gopath = "", goroot = "" }
+      if go_version_cache == nil then
+        go_version_cache = false
+      end
       return go_env_cache
     end
     local lines = vim.split(vim.trim(result or ""), "\n")
@@ -35,6 +44,12 @@ local function get_go_env_async()
       gopath = path.normalize_path(lines[1] or ""),
       goroot = path.normalize_path(lines[2] or ""),
     }
+    if go_version_cache == nil then
+      go_version_cache = M.parse_version(lines[3] or "") or false
+      if not go_version_cache then
+        logger.debug({ "Could not determine the Go version: ", lines[3] })
+      end
+    end
   end
   return go_env_cache
 end
@@ -42,6 +57,52 @@ end
 --- Clear the cached go env results (useful for testing).
 function M.clear_cache()
   go_env_cache = nil
+  go_version_cache = nil
+end
+
+--- Parse a Go version like "go1.21.0", "go1.22rc1" or "devel go1.23-abc123".
+--- @param version string
+--- @return {major: integer, minor: integer}|nil
+function M.parse_version(version)
+  local major, minor = (version or ""):match("go(%d+)%.(%d+)")
+  if not major then
+    return nil
+  end
+  return { major = tonumber(major), minor = tonumber(minor) }
+end
+
+--- Get the version of the Go toolchain, from 'go env GOVERSION'. It is read
+--- along with the rest of the go env, asynchronously and at most once per
+--- session, so outside of an async context it is unknown until then.
+--- @return {major: integer, minor: integer}|nil The version, or nil if unknown
+function M.go_version()
+  if go_version_cache == nil then
+    local async = require("neotest.async")
+    if not async.current_task() then
+      return nil
+    end
+    get_go_env_async()
+  end
+  return go_version_cache or nil
+end
+
+--- Set the Go version cache directly (useful for testing).
+--- @param version string|nil Version like "go1.21.0", or nil for an unknown version
+function M.set_version_for_testing(version)
+  go_version_cache = M.parse_version(version) or false
+end
+
+--- Check if the Go toolchain is at least of the given version.
+--- @param major integer
+--- @param minor integer
+--- @return boolean False if the version is unknown
+function M.is_go_version_at_least(major, minor)
+  local version = M.go_version()
+  if not version then
+    return false
+  end
+  return version.major > major
+    or (version.major == major and version.minor >= minor)
 end
 
 --- Set the go env cache directly (useful for testing).
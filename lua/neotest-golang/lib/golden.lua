@@ -0,0 +1,286 @@
+--- Support for golden file tests: tests which compare their output against
+--- `testdata/*.golden` files, and regenerate them when run with an update
+--- flag, e.g. `go test -run TestFoo -args -update`.
+
+local extra_args = require("neotest-golang.lib.extra_args")
+local file = require("neotest-golang.lib.file")
+local logger = require("neotest-golang.lib.logging")
+local path = require("neotest-golang.lib.path")
+
+local M = {}
+
+--- @class GoldenChange
+--- @field path string Absolute path to the golden file
+--- @field change "added"|"modified"|"removed"
+
+--- Patterns capturing the name and variable of boolean flags, e.g.
+--- `var update = flag.Bool("update", false, "update golden files")` or
+--- `flag.BoolVar(&update, "update", false, "update golden files")`.
+M.flag_patterns = {
+  {
+    pattern = '([%w_]+)%s*:?=%s*flag%.Bool%(%s*"([%w_%.%-]+)"',
+    deref = true,
+  },
+  {
+    pattern = 'flag%.BoolVar%(%s*&([%w_]+)%s*,%s*"([%w_%.%-]+)"',
+    deref = false,
+  },
+}
+
+--- Determine if a flag name looks like a flag for updating golden files.
+--- @param name string
+--- @return boolean
+function M.is_update_flag(name)
+  local lower = name:lower()
+  return lower:find("update", 1, true) ~= nil
+    or lower:find("golden", 1, true) ~= nil
+end
+
+--- Find the declaration of an update flag.
+--- @param lines string[] Lines of a Go file
+--- @return {name: string, variable: string, deref: boolean}|nil
+function M.find_update_flag_in_lines(lines)
+  for _, line in ipairs(lines) do
+    for _, flag in ipairs(M.flag_patterns) do
+      local variable, name = line:match(flag.pattern)
+      if name and M.is_update_flag(name) then
+        return { name = name, variable = variable, deref = flag.deref }
+      end
+    end
+  end
+  return nil
+end
+
+--- Find the declaration of the update flag in any test file of a package
+--- directory. The flag is often declared once, e.g. in main_test.go, and used
+--- by the tests of all files of the package.
+--- @param dir string Package directory
+--- @return {name: string, variable: string, deref: boolean}|nil
+function M.find_update_flag_declaration(dir)
+  local test_files =
+    vim.fn.glob(dir .. path.os_path_sep .. "*_test.go", false, true)
+  for _, test_file in ipairs(test_files) do
+    local ok, lines = pcall(file.read_lines, test_file)
+    if ok then
+      local flag = M.find_update_flag_in_lines(lines)
+      if flag then
+        return flag
+      end
+    end
+  end
+  return nil
+end
+
+--- Find the update flag declared by any test file in a package directory.
+--- @param dir string Package directory
+--- @return string|nil The flag name, e.g. "update"
+function M.find_update_flag(dir)
+  local flag = M.find_update_flag_declaration(dir)
+  return flag and flag.name
+end
+
+--- Determine if code references golden files or an update flag variable.
+--- @param lines string[] Lines of code
+--- @param flag {name: string, variable: string, deref: boolean}|nil Update flag of the file
+--- @return boolean
+function M.references_golden(lines, flag)
+  for _, line in ipairs(lines) do
+    if line:find(".golden", 1, true) or line:find("golden%.%u") then
+      return true
+    end
+    if flag then
+      -- Pad the line, so that the identifier may be at its start or end
+      local padded = " " .. line .. " "
+      local pattern = "[^%w_]" .. flag.variable .. "[^%w_]"
+      if flag.deref then
+        pattern = "%*" .. flag.variable .. "[^%w_]"
+      end
+      if padded:find(pattern) then
+        return true
+      end
+    end
+  end
+  return false
+end
+
+--- Mark the tests of a discovered tree which use golden files with
+--- `golden = true`, so that they can be run with the update flag.
+--- @param file_path string Path to the test file
+--- @param tree neotest.Tree Tree of the test file
+--- @param lines string[]|nil Lines of the test file, when read already
+--- @return neotest.Tree
+function M.mark_tree(file_path, tree, lines)
+  if lines == nil then
+    local ok, read_lines = pcall(file.read_lines, file_path)
+    if not ok then
+      return tree
+    end
+    lines = read_lines
+  end
+
+  -- The flag may be declared in another test file of the package
+  local flag = M.find_update_flag_in_lines(lines)
+    or M.find_update_flag_declaration(path.get_directory(file_path))
+  for _, node in tree:iter_nodes() do
+    local pos = node:data()
+    if pos.type == "test" and pos.range then
+      local body = vim.list_slice(lines, pos.range[1] + 1, pos.range[3] + 1)
+      if M.references_golden(body, flag) then
+        pos.golden = true
+      end
+    end
+  end
+  return tree
+end
+
+--- Get the tests which use golden files, along with their subtests, which
+--- share the golden file comparison of their parent.
+--- @param tree neotest.Tree
+--- @return table<string, boolean> Position ids of the tests
+function M.positions(tree)
+  local ids = {}
+  for _, node in tree:iter_nodes() do
+    local pos = node:data()
+    if pos.golden and not ids[pos.id] then
+      for _, child in node:iter_nodes() do
+        ids[child:data().id] = true
+      end
+    end
+  end
+  return ids
+end
+
+--- Build the arguments passing the update flag to the test binary. They must
+--- be the last arguments of the command.
+--- @return string[]
+function M.update_args()
+  local flag = extra_args.get().golden_update
+  if not flag then
+    return {}
+  end
+  return { "-args", "-" .. flag }
+end
+
+--- Find all golden files below a directory.
+--- @param dir string
+--- @return string[] Absolute paths, sorted
+function M.golden_files(dir)
+  local files = vim.fn.glob(dir .. "/**/*.golden", false, true)
+  table.sort(files)
+  return files
+end
+
+--- Read a file's content, or nil if it cannot be read.
+--- @param filepath string
+--- @return string|nil
+local function read(filepath)
+  local ok, lines = pcall(file.read_lines, filepath)
+  if ok then
+    return table.concat(lines, "\n")
+  end
+  return nil
+end
+
+--- Take a snapshot of the golden files below a directory, when golden files
+--- are about to be updated.
+--- @param dir string
+--- @return {dir: string, files: table<string, string>}|nil
+function M.snapshot_for_update(dir)
+  if not extra_args.get().golden_update then
+    return nil
+  end
+  local files = {}
+  for _, golden_file in ipairs(M.golden_files(dir)) do
+    files[golden_file] = read(golden_file)
+  end
+  return { dir = dir, files = files }
+end
+
+--- Compare the golden files below the directory of a snapshot with the
+--- snapshot.
+--- @param snapshot {dir: string, files: table<string, string>}
+--- @return GoldenChange[]
+function M.changes(snapshot)
+  local changes = {}
+  local current = {}
+  for _, golden_file in ipairs(M.golden_files(snapshot.dir)) do
+    current[golden_file] = true
+    local before = snapshot.files[golden_file]
+    if before == nil then
+      table.insert(changes, { path = golden_file, change = "added" })
+    elseif before ~= read(golden_file) then
+      table.insert(changes, { path = golden_file, change = "modified" })
+    end
+  end
+  for golden_file, _ in pairs(snapshot.files) do
+    if not current[golden_file] then
+      table.insert(changes, { path = golden_file, change = "removed" })
+    end
+  end
+  table.sort(changes, function(a, b)
+    return a.path < b.path
+  end)
+  return changes
+end
+
+--- Build a summary of the changed golden files.
+--- @param changes GoldenChange[]
+--- @param dir string Directory which paths are shown relative to
+--- @return string[]
+function M.summary_lines(changes, dir)
+  if #changes == 0 then
+    return { "=== Golden files: none changed ===" }
+  end
+  local lines = { "=== Golden files: " .. #changes .. " changed ===" }
+  for _, change in ipairs(changes) do
+    local relative = change.path
+    if vim.startswith(relative, dir .. path.os_path_sep) then
+      relative = relative:sub(#dir + 2)
+    end
+    table.insert(lines, string.format("%10s  %s", change.change, relative))
+  end
+  return lines
+end
+
+--- Find the golden files involved in a failed test: the ones mentioned in its
+--- output, or else the conventional `testdata/<TestName>.golden`.
+--- @param output_parts string[] Output of the test
+--- @param dir string Package directory, which relative paths are resolved from
+--- @param test_name string The `go test` name of the test
+--- @return string[] Absolute paths of existing golden files
+function M.involved_files(output_parts, dir, test_name)
+  local files = {}
+  local seen = {}
+
+  local function add(golden_file)
+    if
+      not vim.startswith(golden_file, "/")
+      and not path.has_drive_letter(golden_file)
+    then
+      golden_file = dir .. path.os_path_sep .. golden_file
+    end
+    golden_file = path.normalize_path(golden_file)
+    if not seen[golden_file] and vim.uv.fs_stat(golden_file) then
+      seen[golden_file] = true
+      table.insert(files, golden_file)
+    end
+  end
+
+  for _, part in ipairs(output_parts) do
+    for golden_file in part:gmatch("[%w_%.%-/\\:]*%.golden") do
+      add(golden_file)
+    end
+  end
+
+  if #files == 0 then
+    add("testdata/" .. test_name .. ".golden")
+    add("testdata/" .. test_name:gsub("^.*/", "") .. ".golden")
+  end
+
+  if #files > 0 then
+    logger.debug({ "Golden files involved in " .. test_name .. ":", files })
+  end
+  return files
+end
+
+return M
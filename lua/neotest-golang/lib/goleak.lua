@@ -0,0 +1,216 @@
+--- Parse the goroutine leak reports of go.uber.org/goleak into diagnostics,
+--- placed where the leaked goroutines were created.
+---
+--- With `goleak.VerifyTestMain`, the package fails after all of its tests
+--- passed, and a report like this is printed:
+---
+---   goleak: Errors on successful test run: found unexpected goroutines:
+---   [Goroutine 7 in state chan receive, with example.com/pkg.worker on top of the stack:
+---   goroutine 7 [chan receive]:
+---   example.com/pkg.worker()
+---   	/home/user/pkg/worker.go:10 +0x25
+---   created by example.com/pkg.Start in goroutine 6
+---   	/home/user/pkg/worker.go:5 +0x6f
+---   ]
+---
+--- With `goleak.VerifyNone(t)`, the same report is part of the test's output.
+
+local file_diagnostics = require("neotest-golang.lib.file_diagnostics")
+local path = require("neotest-golang.lib.path")
+local stack = require("neotest-golang.lib.stack")
+require("neotest-golang.lib.types")
+
+local M = {}
+
+--- The line which starts a goleak report.
+M.marker = "found unexpected goroutines:"
+
+--- @class LeakedGoroutine
+--- @field id string The goroutine ID
+--- @field state string The state of the goroutine, e.g. "chan receive"
+--- @field top string The function on top of the goroutine's stack
+--- @field frames StackFrame[] Stack of the goroutine, innermost first
+--- @field created_by? StackFrame Where the goroutine was created
+
+--- Parse all leaked goroutines found in the output.
+--- @param lines string[] Lines of output
+--- @return LeakedGoroutine[]
+function M.parse(lines)
+  local leaks = {}
+  local in_report = false
+  local leak = nil
+
+  local i = 1
+  while i <= #lines do
+    local line = vim.trim(lines[i])
+    local next_i = i + 1
+
+    if line:find(M.marker, 1, true) then
+      in_report = true
+      -- The first goroutine may be reported on the same line
+      line = vim.trim(line:sub(line:find(M.marker, 1, true) + #M.marker))
+    end
+
+    local id, state, top = line:match(
+      "^%[?Goroutine (%d+) in state ([^,]+), with (%S+) on top of the stack:"
+    )
+    if in_report and id then
+      leak = { id = id, state = state, top = top, frames = {} }
+      table.insert(leaks, leak)
+    elseif leak then
+      local frames
+      frames, next_i = stack.parse_frames(lines, i)
+      if #frames == 0 then
+        next_i = i + 1
+        if line == "]" or line:match("^%]") then
+          leak = nil
+        end
+      end
+      for _, frame in ipairs(frames) do
+        local creator = frame.func:match("^created by (%S+)")
+        if creator then
+          leak.created_by = vim.tbl_extend("force", frame, { func = creator })
+        else
+          table.insert(leak.frames, frame)
+        end
+      end
+    end
+
+    i = next_i
+  end
+
+  return leaks
+end
+
+--- Determine if output parts contain a goleak report, without splitting them
+--- into lines. This is called for the output of every test.
+--- @param output_parts string[]
+--- @return boolean
+function M.has_report(output_parts)
+  for _, part in ipairs(output_parts) do
+    if string.find(part, "found unexpected goroutines", 1, true) then
+      return true
+    end
+  end
+  return false
+end
+
+--- Parse all leaked goroutines found in output parts.
+--- @param output_parts string[]
+--- @return LeakedGoroutine[]
+function M.parse_parts(output_parts)
+  if not M.has_report(output_parts) then
+    return {}
+  end
+  return M.parse(stack.to_lines(output_parts))
+end
+
+--- Describe a leaked goroutine, e.g. "goroutine 7 [chan receive] in
+--- pkg.worker, created at worker.go:5".
+--- @param leak LeakedGoroutine
+--- @return string
+function M.describe(leak)
+  local description = "goroutine "
+    .. leak.id
+    .. " ["
+    .. leak.state
+    .. "] in "
+    .. stack.short_function(leak.top)
+  if leak.created_by then
+    description = description
+      .. ", created at "
+      .. path.get_filename_fast(leak.created_by.filename)
+      .. ":"
+      .. leak.created_by.line_number
+  end
+  return description
+end
+
+--- Summarize the leaks in a single line.
+--- @param leaks LeakedGoroutine[]
+--- @return string
+function M.summary(leaks)
+  local noun = #leaks == 1 and " goroutine" or " goroutines"
+  return "goroutine leak: " .. #leaks .. noun .. " still running"
+end
+
+--- Build the lines which head the output of a result with leaks.
+--- @param leaks LeakedGoroutine[]
+--- @return string[]
+function M.header_lines(leaks)
+  local lines = { "=== " .. M.summary(leaks) .. " ===" }
+  for _, leak in ipairs(leaks) do
+    table.insert(lines, "===   " .. M.describe(leak))
+  end
+  return lines
+end
+
+--- Turn leaked goroutines into diagnostics, at the frame which created them
+--- (or the innermost frame outside the standard library, if not known).
+--- Diagnostics in the file of the given position are returned, all others are
+--- published on the buffer of their file.
+--- @param leaks LeakedGoroutine[]
+--- @param position_id string|nil The position the output belongs to
+--- @return neotest.Error[]
+function M.process(leaks, position_id)
+  ---@type neotest.Error[]
+  local errors = {}
+  local pos_file = file_diagnostics.position_file(position_id)
+
+  for _, leak in ipairs(leaks) do
+    local frame = leak.created_by or stack.first_user_frame(leak.frames)
+    if frame then
+      local err = file_diagnostics.to_position_error({
+        filename = frame.filename,
+        line_number = frame.line_number,
+        message = "goroutine leak: goroutine "
+          .. leak.id
+          .. " ["
+          .. leak.state
+          .. "] in "
+          .. stack.short_function(leak.top)
+          .. " was started here and never finished",
+        severity = vim.diagnostic.severity.ERROR,
+      }, pos_file)
+      if err then
+        table.insert(errors, err)
+      end
+    end
+  end
+
+  return errors
+end
+
+--- Collect the leaks of all packages of a run, for the summary of the run.
+--- @param gotest_output GoTestEvent[] Array of go test JSON events
+--- @return string[] Summary lines, empty if there are no leaks
+function M.summary_lines(gotest_output)
+  local output_by_package = {}
+  local packages = {}
+  for _, e in ipairs(gotest_output) do
+    if e.Package and e.Output then
+      if not output_by_package[e.Package] then
+        output_by_package[e.Package] = {}
+        table.insert(packages, e.Package)
+      end
+      table.insert(output_by_package[e.Package], e.Output)
+    end
+  end
+
+  local lines = {}
+  for _, package_import in ipairs(packages) do
+    local leaks = M.parse_parts(output_by_package[package_import])
+    if #leaks > 0 then
+      table.insert(
+        lines,
+        "=== " .. package_import .. ": " .. M.summary(leaks) .. " ==="
+      )
+      for _, leak in ipairs(leaks) do
+        table.insert(lines, "===   " .. M.describe(leak))
+      end
+    end
+  end
+  return lines
+end
+
+return M
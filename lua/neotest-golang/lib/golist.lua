@@ -0,0 +1,373 @@
+--- Cache of 'go list' output per module root.
+---
+--- 'go list ./...' runs once per module root, asynchronously, and its output is
+--- reused by every run in the module. An entry is invalidated, and fetched
+--- again in the background, when go.mod, go.sum or go.work changes or when the
+--- set of Go files changes in a directory which output was used for. Only
+--- these directories are watched, as a watcher per package could exhaust the
+--- watches of the system. Output without a package in a directory which is
+--- used for the first time is fetched again too, as the directory may be new.
+--- If a directory can't be watched, the output is fetched again for every run
+--- instead.
+
+local async = require("neotest.async")
+
+local json = require("neotest-golang.lib.json")
+local logger = require("neotest-golang.lib.logging")
+local path = require("neotest-golang.lib.path")
+require("neotest-golang.lib.types")
+
+local M = {}
+
+--- Files which define the packages and dependencies of a module.
+local module_files = {
+  ["go.mod"] = true,
+  ["go.sum"] = true,
+  ["go.work"] = true,
+  ["go.work.sum"] = true,
+}
+
+--- How long to wait for 'go list' outside of an async context.
+local sync_timeout_ms = 10 * 60 * 1000
+
+--- @class GoListCacheEntry
+--- @field command string[] The 'go list' command the entry was fetched with
+--- @field items GoListItem[]|nil Packages of the module, nil while fetching
+--- @field errors string[] Errors of 'go list' itself, not of a package
+--- @field waiters fun()[] Callbacks to call once fetched
+--- @field reported boolean Whether the errors were reported to the user
+--- @field watchers table<string, uv.uv_fs_event_t> File watchers, by directory
+--- @field opened table<string, boolean> Directories the output was used for
+--- @field unwatched boolean Whether a directory could not be watched
+
+--- @type table<string, GoListCacheEntry>
+local cache = {}
+
+--- Whether the user was told that directories can't be watched.
+local unwatched_reported = false
+
+--- Get the module root of a directory, which is where 'go list' runs.
+--- @param cwd string
+--- @return string
+function M.module_root(cwd)
+  local root = vim.fs.root(cwd, "go.mod")
+  if root == nil then
+    return cwd
+  end
+  return path.normalize_path(root)
+end
+
+--- Determine if a directory holds, or is below, another directory.
+--- @param dir string
+--- @param parent string
+--- @return boolean
+local function is_within(dir, parent)
+  return dir == parent or vim.startswith(dir, parent .. path.os_path_sep)
+end
+
+--- Get the names of the Go files and subdirectories of a directory, which make
+--- up the package in it, or the packages below it.
+--- @param dir string
+--- @return string
+local function file_set(dir)
+  local names = {}
+  local handle = vim.uv.fs_scandir(dir)
+  while handle do
+    local name, type = vim.uv.fs_scandir_next(handle)
+    if name == nil then
+      break
+    end
+    if
+      (type == "file" and name:match("%.go$"))
+      or (type == "directory" and not name:match("^[._]"))
+    then
+      table.insert(names, name)
+    end
+  end
+  table.sort(names)
+  return table.concat(names, "\n")
+end
+
+--- Stop watching the files of a module.
+--- @param entry GoListCacheEntry
+local function unwatch(entry)
+  for _, watcher in pairs(entry.watchers) do
+    if not watcher:is_closing() then
+      watcher:stop()
+      watcher:close()
+    end
+  end
+  entry.watchers = {}
+end
+
+--- Watch a directory of a module for changes to its module files, or to its
+--- set of Go files and subdirectories.
+--- @param root string
+--- @param entry GoListCacheEntry
+--- @param dir string
+local function watch_dir(root, entry, dir)
+  if
+    vim.uv.new_fs_event == nil
+    or entry.unwatched
+    or entry.watchers[dir] ~= nil
+  then
+    return
+  end
+
+  --- Invalidate the entry, unless a previous change did already.
+  --- @param reason string
+  local function on_change(reason)
+    vim.schedule(function()
+      if cache[root] == entry then
+        logger.debug(reason .. ", invalidating 'go list' output of " .. root)
+        M.invalidate(root, true)
+      end
+    end)
+  end
+
+  local watcher = vim.uv.new_fs_event()
+  local ok, err = false, "could not create a file watcher"
+  if watcher then
+    local files = file_set(dir)
+    ok, err = watcher:start(dir, {}, function(watch_err, filename, events)
+      if watch_err then
+        return
+      end
+      if module_files[filename] then
+        on_change(filename .. " changed in " .. dir)
+      elseif events.rename and file_set(dir) ~= files then
+        on_change("Set of Go files changed in " .. dir)
+      end
+    end)
+  end
+  if ok then
+    entry.watchers[dir] = watcher
+    return
+  end
+
+  -- E.g. the inotify watches of the system are exhausted
+  if watcher and not watcher:is_closing() then
+    watcher:close()
+  end
+  unwatch(entry)
+  entry.unwatched = true
+  local msg = "Could not watch "
+    .. dir
+    .. " ("
+    .. tostring(err)
+    .. "), 'go list' runs for every run instead"
+  if unwatched_reported then
+    logger.debug(msg)
+  else
+    unwatched_reported = true
+    logger.warn(msg, true)
+  end
+end
+
+--- Watch the module files of a module and the directories its output was used
+--- for.
+--- @param root string
+--- @param entry GoListCacheEntry
+local function watch(root, entry)
+  watch_dir(root, entry, root)
+  local go_work = vim.fs.root(root, "go.work")
+  if go_work ~= nil then
+    watch_dir(root, entry, path.normalize_path(go_work))
+  end
+  for dir in pairs(entry.opened) do
+    watch_dir(root, entry, dir)
+  end
+end
+
+--- Report the errors of a 'go list' run, one per package, the first time its
+--- output is used.
+--- @param root string
+--- @param entry GoListCacheEntry
+local function report(root, entry)
+  if entry.reported then
+    return
+  end
+  entry.reported = true
+  for _, err in ipairs(entry.errors) do
+    logger.warn({ "Go list error in " .. root .. ": ", err }, true)
+  end
+  for _, item in ipairs(entry.items or {}) do
+    for _, err in ipairs(item.Errors or {}) do
+      logger.warn("Go list error in " .. item.ImportPath .. ": " .. err, true)
+    end
+  end
+end
+
+--- Run 'go list' in a module root in the background.
+--- @param root string
+--- @param command string[]
+--- @param opened table<string, boolean>|nil Directories to keep watching
+--- @return GoListCacheEntry
+local function fetch(root, command, opened)
+  --- @type GoListCacheEntry
+  local entry = {
+    command = command,
+    items = nil,
+    errors = {},
+    waiters = {},
+    reported = false,
+    watchers = {},
+    opened = opened or {},
+    unwatched = false,
+  }
+  cache[root] = entry
+
+  logger.info(
+    "Running Go list: " .. table.concat(command, " ") .. " in " .. root
+  )
+  vim.system(command, { cwd = root, text = true }, function(result)
+    vim.schedule(function()
+      if result.code ~= 0 then
+        local err = vim.trim((result.stderr or "") .. (result.stdout or ""))
+        table.insert(entry.errors, "go list: " .. err)
+      end
+      entry.items = json.decode_from_string(result.stdout or "")
+      logger.debug({ "JSON-decoded 'go list' output: ", entry.items })
+
+      if cache[root] == entry then
+        watch(root, entry)
+      end
+
+      local waiters = entry.waiters
+      entry.waiters = {}
+      for _, waiter in ipairs(waiters) do
+        waiter()
+      end
+    end)
+  end)
+
+  return entry
+end
+
+--- Get the cache entry of a module root, fetching it if needed.
+--- @param root string
+--- @param command string[]
+--- @return GoListCacheEntry
+local function get_entry(root, command)
+  local entry = cache[root]
+  if
+    entry ~= nil
+    and vim.deep_equal(entry.command, command)
+    -- Without watchers, output which was used already may be outdated
+    and not (entry.unwatched and entry.reported)
+  then
+    return entry
+  end
+  if entry ~= nil then
+    unwatch(entry)
+  end
+  return fetch(root, command, entry and entry.opened)
+end
+
+--- Wait until 'go list' of a cache entry finished.
+--- @param entry GoListCacheEntry
+local function wait(entry)
+  if entry.items ~= nil then
+    return
+  end
+  if async.current_task() then
+    local future = async.control.future()
+    table.insert(entry.waiters, function()
+      future.set()
+    end)
+    future.wait()
+  else
+    vim.wait(sync_timeout_ms, function()
+      return entry.items ~= nil
+    end)
+  end
+end
+
+--- Determine if the output of an entry has a package in, or below, a
+--- directory.
+--- @param entry GoListCacheEntry
+--- @param dir string
+--- @return boolean
+local function covers(entry, dir)
+  for _, item in ipairs(entry.items or {}) do
+    if item.Dir and is_within(item.Dir, dir) then
+      return true
+    end
+  end
+  return false
+end
+
+--- Start fetching the 'go list' output of the module of a directory, unless
+--- it is cached already.
+--- @param cwd string
+--- @param command string[] The 'go list' command
+function M.prefetch(cwd, command)
+  get_entry(M.module_root(cwd), command)
+end
+
+--- Get the 'go list' output of the packages in, and below, a directory.
+--- @param cwd string
+--- @param command string[] The 'go list' command
+--- @return GoListItem[] items
+--- @return string[]|nil errors Errors of 'go list' and of the packages
+function M.get(cwd, command)
+  local root = M.module_root(cwd)
+  local entry = get_entry(root, command)
+  local fetched = entry.items ~= nil
+  wait(entry)
+
+  -- A package created below a directory which isn't watched goes unnoticed,
+  -- so output without any package in a directory used for the first time is
+  -- fetched again
+  if fetched and not entry.opened[cwd] and not covers(entry, cwd) then
+    logger.debug("No package in " .. cwd .. ", running 'go list' again")
+    unwatch(entry)
+    entry = fetch(root, command, entry.opened)
+    wait(entry)
+  end
+  report(root, entry)
+  entry.opened[cwd] = true
+  if cache[root] == entry then
+    watch_dir(root, entry, cwd)
+  end
+
+  local items = {}
+  local errors = vim.deepcopy(entry.errors)
+  for _, item in ipairs(entry.items or {}) do
+    if item.Dir and is_within(item.Dir, cwd) then
+      table.insert(items, item)
+      for _, err in ipairs(item.Errors or {}) do
+        table.insert(errors, "go list: " .. item.ImportPath .. ": " .. err)
+      end
+    end
+  end
+
+  if #errors == 0 then
+    return items, nil
+  end
+  return items, errors
+end
+
+--- Invalidate the cached 'go list' output of a module root.
+--- @param root string
+--- @param refetch boolean|nil Fetch the output again in the background
+function M.invalidate(root, refetch)
+  local entry = cache[root]
+  if entry == nil then
+    return
+  end
+  unwatch(entry)
+  cache[root] = nil
+  if refetch then
+    fetch(root, entry.command, entry.opened)
+  end
+end
+
+--- Clear the entire cache.
+function M.clear()
+  for root in pairs(cache) do
+    M.invalidate(root)
+  end
+end
+
+return M
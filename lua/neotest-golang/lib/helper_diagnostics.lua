@@ -0,0 +1,223 @@
+--- Diagnostics which a test reports from another file, e.g. from a shared
+--- assertion helper in `helpers_test.go` or `internal/testutil` which does
+--- not call `t.Helper()`. Go prints the location of the helper, so these
+--- diagnostics are resolved to the helper's file and published there, and the
+--- test gets a hint where it calls the helper.
+
+local file = require("neotest-golang.lib.file")
+local file_diagnostics = require("neotest-golang.lib.file_diagnostics")
+local logger = require("neotest-golang.lib.logging")
+local path = require("neotest-golang.lib.path")
+local run_state = require("neotest-golang.lib.run_state")
+
+local M = {}
+
+--- Determine if a path is absolute.
+--- @param filename string
+--- @return boolean
+local function is_absolute(filename)
+  return vim.startswith(filename, "/")
+    or vim.startswith(filename, "\\")
+    or path.has_drive_letter(filename)
+end
+
+--- Determine if a file belongs to the code under test: it is in one of the
+--- directories of the packages listed by `go list`, or below the root of
+--- their module. Files of GOROOT or the module cache, e.g. printed with
+--- `-fullpath`, are not.
+--- @param filename string Absolute path
+--- @param golist_data GoListItem[]|nil The 'go list -json' output
+--- @return boolean
+local function in_run(filename, golist_data)
+  local dir = path.get_directory(filename)
+  for _, item in ipairs(golist_data or {}) do
+    if item.Dir == dir then
+      return true
+    end
+    local gomod = item.Module and item.Module.GoMod
+    if gomod and gomod ~= "" then
+      local root = path.get_directory(gomod) .. path.os_path_sep
+      if vim.startswith(filename, root) then
+        return true
+      end
+    end
+  end
+  return false
+end
+
+--- Find the file of a diagnostic: in the directory of the test, or else in
+--- exactly one of the directories of the packages listed by `go list`.
+--- @param filename string File name as printed by Go, e.g. "helpers_test.go"
+--- @param test_dir string Directory of the test file
+--- @param golist_data GoListItem[]|nil The 'go list -json' output
+--- @return string|nil Absolute path, or nil if the file was not found
+local function find(filename, test_dir, golist_data)
+  local own = test_dir .. path.os_path_sep .. filename
+  if vim.uv.fs_stat(own) then
+    return own
+  end
+
+  local found = {}
+  for _, item in ipairs(golist_data or {}) do
+    if item.Dir and item.Dir ~= test_dir then
+      local candidate = item.Dir .. path.os_path_sep .. filename
+      if vim.uv.fs_stat(candidate) then
+        table.insert(found, candidate)
+      end
+    end
+  end
+  if #found > 1 then
+    logger.debug({ "Ambiguous file of diagnostic, skipping it: ", found })
+    return nil
+  end
+  return found[1]
+end
+
+--- Resolve the file name of a diagnostic into an absolute path, by looking
+--- for it in the directory of the test first, and then in the directories of
+--- the packages listed by `go list`. A file name which several packages have
+--- is not resolved, nor is an absolute path outside of the packages and their
+--- module. Resolved paths are kept for the rest of the run.
+--- @param filename string File name as printed by Go, e.g. "helpers_test.go"
+--- @param test_dir string Directory of the test file
+--- @param golist_data GoListItem[]|nil The 'go list -json' output
+--- @return string|nil Absolute path, or nil if the file was not found
+function M.resolve(filename, test_dir, golist_data)
+  if is_absolute(filename) then
+    if not in_run(filename, golist_data) or not vim.uv.fs_stat(filename) then
+      return nil
+    end
+    return filename
+  end
+
+  local resolved = run_state.current().helper_files.resolved
+  local key = test_dir .. path.os_path_sep .. filename
+  if resolved[key] == nil then
+    resolved[key] = find(filename, test_dir, golist_data) or false
+  end
+  return resolved[key] or nil
+end
+
+--- Read the lines of a file, once per run.
+--- @param filename string Absolute path
+--- @return string[]|nil
+local function read_lines(filename)
+  local cache = run_state.current().helper_files.lines
+  if cache[filename] == nil then
+    local ok, lines = pcall(file.read_lines, filename)
+    cache[filename] = ok and lines or false
+  end
+  return cache[filename] or nil
+end
+
+--- Find the name of the function which encloses a line.
+--- @param lines string[] Lines of a Go file
+--- @param line_number integer 1-based line number
+--- @return string|nil
+function M.enclosing_function(lines, line_number)
+  for i = math.min(line_number, #lines), 1, -1 do
+    local line = lines[i]
+    local name = line:match("^func%s+([%w_]+)")
+      or line:match("^func%s+%b()%s*([%w_]+)")
+    if name then
+      return name
+    end
+  end
+  return nil
+end
+
+--- Find where a test calls a function: the first call in the test function,
+--- or else the declaration of the test function.
+--- @param lines string[] Lines of the test file
+--- @param test_name string The `go test` name of the test, e.g. "TestFoo/sub"
+--- @param func_name string|nil Name of the called function
+--- @return integer|nil 1-based line number, or nil if the test was not found
+function M.call_site(lines, test_name, func_name)
+  local top_level = vim.split(test_name, "/", { plain = true })[1]
+  local decl = nil
+  for i, line in ipairs(lines) do
+    if decl and line:match("^func%s") then
+      break
+    end
+    if not decl then
+      local name = line:match("^func%s+([%w_]+)%s*%(")
+        or line:match("^func%s+%b()%s*([%w_]+)%s*%(")
+      if name == top_level then
+        decl = i
+      end
+    elseif func_name then
+      local padded = " " .. line
+      if padded:find("[^%w_]" .. func_name .. "%s*%(") then
+        return i
+      end
+    end
+  end
+  return decl
+end
+
+--- Shorten the path of a file for messages: relative to the directory of the
+--- test when it is below it, otherwise absolute.
+--- @param filename string Absolute path
+--- @param test_dir string
+--- @return string
+local function display_path(filename, test_dir)
+  local prefix = test_dir .. path.os_path_sep
+  if vim.startswith(filename, prefix) then
+    return filename:sub(#prefix + 1)
+  end
+  return filename
+end
+
+--- Publish diagnostics reported from other files than the test file, and
+--- build hints for the test file where it calls the reporting functions.
+--- @param diagnostics table[] Diagnostic data with {filename, line_number, message, severity}
+--- @param test_file string Absolute path to the test file
+--- @param test_name string|nil The `go test` name of the test
+--- @param golist_data GoListItem[]|nil The 'go list -json' output
+--- @return neotest.Error[] Hints for the test file
+function M.process(diagnostics, test_file, test_name, golist_data)
+  ---@type neotest.Error[]
+  local hints = {}
+  if #diagnostics == 0 then
+    return hints
+  end
+
+  local test_dir = path.get_directory(test_file)
+  local test_lines = read_lines(test_file) or {}
+
+  for _, diagnostic in ipairs(diagnostics) do
+    local filename = M.resolve(diagnostic.filename, test_dir, golist_data)
+    if not filename then
+      logger.debug(
+        "Could not find the file of diagnostic: " .. diagnostic.filename
+      )
+    else
+      file_diagnostics.add(filename, diagnostic)
+
+      if diagnostic.severity == vim.diagnostic.severity.ERROR and test_name then
+        local func_name = nil
+        local lines = read_lines(filename)
+        if lines then
+          func_name = M.enclosing_function(lines, diagnostic.line_number)
+        end
+        local line_number = M.call_site(test_lines, test_name, func_name)
+        if line_number then
+          table.insert(hints, {
+            line = line_number - 1,
+            message = "reported at "
+              .. display_path(filename, test_dir)
+              .. ":"
+              .. diagnostic.line_number
+              .. ": "
+              .. diagnostic.message,
+            severity = vim.diagnostic.severity.HINT,
+          })
+        end
+      end
+    end
+  end
+
+  return hints
+end
+
+return M
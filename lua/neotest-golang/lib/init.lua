@@ -3,18 +3,37 @@ local M = {}
 M.colorize = require("neotest-golang.lib.colorize")
 M.convert = require("neotest-golang.lib.convert")
 M.cmd = require("neotest-golang.lib.cmd")
+M.diagnostic_parsers = require("neotest-golang.lib.diagnostic_parsers")
 M.diagnostics = require("neotest-golang.lib.diagnostics")
+M.diff = require("neotest-golang.lib.diff")
 M.discovery_cache = require("neotest-golang.lib.discovery_cache")
 M.dupe = require("neotest-golang.lib.dupe")
+M.duration = require("neotest-golang.lib.duration")
+M.examples = require("neotest-golang.lib.examples")
+M.export = require("neotest-golang.lib.export")
 M.extra_args = require("neotest-golang.lib.extra_args")
+M.failures = require("neotest-golang.lib.failures")
 M.file = require("neotest-golang.lib.file")
+M.file_diagnostics = require("neotest-golang.lib.file_diagnostics")
 M.find = require("neotest-golang.lib.find")
 M.goenv = require("neotest-golang.lib.goenv")
+M.golden = require("neotest-golang.lib.golden")
+M.goleak = require("neotest-golang.lib.goleak")
+M.golist = require("neotest-golang.lib.golist")
+M.helper_diagnostics = require("neotest-golang.lib.helper_diagnostics")
 M.json = require("neotest-golang.lib.json")
 M.logging = require("neotest-golang.lib.logging")
 M.mapping = require("neotest-golang.lib.mapping")
 M.path = require("neotest-golang.lib.path")
+M.prebuilt = require("neotest-golang.lib.prebuilt")
+M.race = require("neotest-golang.lib.race")
+M.replay = require("neotest-golang.lib.replay")
+M.run_state = require("neotest-golang.lib.run_state")
 M.sanitize = require("neotest-golang.lib.sanitize")
+M.shuffle = require("neotest-golang.lib.shuffle")
+M.stack = require("neotest-golang.lib.stack")
 M.stream = require("neotest-golang.lib.stream")
+M.test2json = require("neotest-golang.lib.test2json")
+M.timeline = require("neotest-golang.lib.timeline")
 
 return M
@@ -105,14 +105,21 @@ end
 
 ---Log the information.
 ---@param msg string|table
-function M.info(msg)
-  if M.get_level() > vim.log.levels.INFO then
+---@param notify boolean|nil Whether to also notify the user
+function M.info(msg, notify)
+  local logged = M.get_level() <= vim.log.levels.INFO
+  if not logged and not notify then
     return
   end
   if type(msg) ~= "string" then
     msg = handle_input(msg)
   end
-  get_logger().info(msg)
+  if notify then
+    vim.notify(msg, vim.log.levels.INFO)
+  end
+  if logged then
+    get_logger().info(msg)
+  end
 end
 
 ---Log the warning.
@@ -2,6 +2,7 @@ local convert = require("neotest-golang.lib.convert")
 local logger = require("neotest-golang.lib.logging")
 local metrics = require("neotest-golang.lib.metrics")
 local options = require("neotest-golang.options")
+local run_state = require("neotest-golang.lib.run_state")
 
 local M = {}
 
@@ -109,10 +110,6 @@ function M.build_position_lookup(tree, golist_data)
   return lookup
 end
 
----Collection of failed position mappings for bulk reporting
----@type table<string, boolean>
-M._failed_mappings = {}
-
 ---Get position ID from go test event using lookup
 ---@param lookup table<string, string> The position lookup table
 ---@param package_import string Go package import path
@@ -127,8 +124,8 @@ function M.get_pos_id(lookup, package_import, test_name)
   metrics.record_position_lookup(success)
 
   if not success then
-    -- Collect failed mappings for bulk reporting to avoid spam during streaming
-    M._failed_mappings[internal_key] = true
+    -- Collect failed mappings of the run for bulk reporting to avoid spam during streaming
+    run_state.current().failed_mappings[internal_key] = true
   end
 
   return pos_id
@@ -136,8 +133,9 @@ end
 
 ---Report all collected failed position mappings and clear the collection
 function M.report_failed_mappings()
-  if vim.tbl_count(M._failed_mappings) > 0 then
-    local failed_list = vim.tbl_keys(M._failed_mappings)
+  local state = run_state.current()
+  if vim.tbl_count(state.failed_mappings) > 0 then
+    local failed_list = vim.tbl_keys(state.failed_mappings)
     table.sort(failed_list)
 
     local message = "Tests executed but not detected by tree-sitter query ("
@@ -152,7 +150,7 @@ function M.report_failed_mappings()
     end
 
     -- Clear the collection after reporting
-    M._failed_mappings = {}
+    state.failed_mappings = {}
   end
 end
 
@@ -1,6 +1,7 @@
 ---Performance metrics collection for streaming operations
 local logger = require("neotest-golang.lib.logging")
 local options = require("neotest-golang.options")
+local run_state = require("neotest-golang.lib.run_state")
 
 local M = {}
 
@@ -15,9 +16,11 @@ local M = {}
 ---@field position_lookups number Total position lookups attempted
 ---@field position_failures number Failed position lookups
 
----Current streaming session metrics
----@type StreamingMetrics|nil
-M._current_session = nil
+---Get the streaming session metrics of the current run
+---@return StreamingMetrics|nil
+function M.current_session()
+  return run_state.current().metrics
+end
 
 ---Start a new metrics collection session
 function M.start_session()
@@ -25,7 +28,7 @@ function M.start_session()
     return
   end
 
-  M._current_session = {
+  run_state.current().metrics = {
     start_time = vim.uv.hrtime(),
     events_processed = 0,
     events_by_type = {},
@@ -43,63 +46,66 @@ end
 ---Record an event being processed
 ---@param event_action string The Go test event action (run, pass, fail, output, etc.)
 function M.record_event(event_action)
-  if not M._current_session then
+  local session = M.current_session()
+  if not session then
     return
   end
 
-  M._current_session.events_processed = M._current_session.events_processed + 1
+  session.events_processed = session.events_processed + 1
 
-  local count = M._current_session.events_by_type[event_action] or 0
-  M._current_session.events_by_type[event_action] = count + 1
+  local count = session.events_by_type[event_action] or 0
+  session.events_by_type[event_action] = count + 1
 end
 
 ---Record accumulator table size
 ---@param size number Current accumulator table size
 function M.record_accum_size(size)
-  if not M._current_session then
+  local session = M.current_session()
+  if not session then
     return
   end
 
-  if size > M._current_session.peak_accum_size then
-    M._current_session.peak_accum_size = size
+  if size > session.peak_accum_size then
+    session.peak_accum_size = size
   end
 end
 
 ---Record cache table size
 ---@param size number Current cache table size
 function M.record_cache_size(size)
-  if not M._current_session then
+  local session = M.current_session()
+  if not session then
     return
   end
 
-  if size > M._current_session.peak_cache_size then
-    M._current_session.peak_cache_size = size
+  if size > session.peak_cache_size then
+    session.peak_cache_size = size
   end
 end
 
 ---Record a file being written
 ---@param file_size number Size of the written file in bytes
 function M.record_file_write(file_size)
-  if not M._current_session then
+  local session = M.current_session()
+  if not session then
     return
   end
 
-  M._current_session.files_written = M._current_session.files_written + 1
-  M._current_session.total_output_size = M._current_session.total_output_size
-    + file_size
+  session.files_written = session.files_written + 1
+  session.total_output_size = session.total_output_size + file_size
 end
 
 ---Record a position lookup attempt
 ---@param success boolean Whether the lookup succeeded
 function M.record_position_lookup(success)
-  if not M._current_session then
+  local session = M.current_session()
+  if not session then
     return
   end
 
-  M._current_session.position_lookups = M._current_session.position_lookups + 1
+  session.position_lookups = session.position_lookups + 1
   if not success then
-    M._current_session.position_failures = M._current_session.position_failures
-      + 1
+    session.position_failures = session.position_failures + 1
   end
 end
 
@@ -129,11 +135,11 @@ end
 
 ---End the current session and log performance summary
 function M.end_session()
-  if not M._current_session then
+  local session = M.current_session()
+  if not session then
     return
   end
 
-  local session = M._current_session
   local duration_ns = vim.uv.hrtime() - session.start_time
   local duration_s = duration_ns / 1e9
   local events_per_sec = session.events_processed / duration_s
@@ -185,7 +191,7 @@ function M.end_session()
   logger.info(table.concat(summary_lines, "\n"))
 
   -- Clear the session
-  M._current_session = nil
+  run_state.current().metrics = nil
 end
 
 return M
@@ -0,0 +1,154 @@
+--- Cache of prebuilt test binaries, so that re-running tests of a package
+--- does not link its test binary again when nothing changed.
+---
+--- A binary is keyed by its build flags, its environment and the export data
+--- of the package, its tests and all of their dependencies, as reported by
+--- `go list -export -deps -test`. The export data lives in Go's build cache,
+--- and its paths change whenever a package compiles differently. So Go
+--- decides what is up to date, including embedded files, cgo and modules
+--- outside the module, and compiles what changed, while linking only happens
+--- when something did change.
+
+local cmd = require("neotest-golang.lib.cmd")
+local logger = require("neotest-golang.lib.logging")
+local path = require("neotest-golang.lib.path")
+
+local M = {}
+
+--- Directory holding the prebuilt test binaries.
+--- @return string
+function M.cache_dir()
+  return path.normalize_path(
+    vim.fn.stdpath("cache") .. "/neotest-golang/binaries"
+  )
+end
+
+--- Describe environment variables in a stable order.
+--- @param env table<string, string>|nil
+--- @return string
+local function describe_env(env)
+  local names = vim.tbl_keys(env or {})
+  table.sort(names)
+  local parts = {}
+  for _, name in ipairs(names) do
+    table.insert(parts, name .. "=" .. tostring(env[name]))
+  end
+  return table.concat(parts, "\0")
+end
+
+--- Compute the cache key of the test binary of a package. Within an async
+--- task, the task waits for `go list` without blocking the editor.
+--- @param dir string Directory of the package
+--- @param build_flags string[] Flags the binary is built with
+--- @param env table<string, string>|nil Environment the binary is built in
+--- @return string|nil key The key, or nil if the package does not compile
+--- @return string|nil err Output of `go list`, if it failed
+function M.key(dir, build_flags, env)
+  local command = {
+    "go",
+    "list",
+    "-export",
+    "-deps",
+    "-test",
+    "-f",
+    "{{.ImportPath}} {{.Export}}",
+  }
+  vim.list_extend(command, build_flags)
+  table.insert(command, ".")
+  local result = cmd.system(command, { cwd = dir, env = env, text = true })
+  if result.code ~= 0 then
+    return nil, (result.stderr or "") .. (result.stdout or "")
+  end
+
+  return vim.fn.sha256(table.concat({
+    dir,
+    table.concat(build_flags, "\0"),
+    describe_env(env),
+    result.stdout or "",
+  }, "\n"))
+end
+
+--- Get the path of the cached test binary of a package, for a cache key.
+--- Binaries built with other flags, e.g. the debuggable binaries of
+--- attachable runs, have other paths.
+--- @param dir string Directory of the package
+--- @param build_flags string[] Flags the binary is built with
+--- @param key string
+--- @return string
+function M.binary_path(dir, build_flags, key)
+  local build = vim.fn.sha256(dir .. "\n" .. table.concat(build_flags, "\0"))
+  return path.normalize_path(
+    M.cache_dir()
+      .. "/"
+      .. vim.fs.basename(dir)
+      .. "-"
+      .. build:sub(1, 12)
+      .. "-"
+      .. key:sub(1, 16)
+      .. ".test"
+  )
+end
+
+--- Remove the outdated test binaries of a package, built with the same flags.
+--- @param binary string The current binary of the package
+local function remove_outdated(binary)
+  local prefix = vim.fs.basename(binary):gsub("%-%x+%.test$", "-")
+  for name in vim.fs.dir(M.cache_dir()) do
+    if
+      vim.startswith(name, prefix)
+      and vim.endswith(name, ".test")
+      and name ~= vim.fs.basename(binary)
+    then
+      os.remove(M.cache_dir() .. "/" .. name)
+    end
+  end
+end
+
+--- Get the test binary of a package, building it if there is no up-to-date
+--- binary in the cache.
+--- @param dir string Directory of the package
+--- @param build_flags string[] Flags to build the binary with
+--- @param env table<string, string>|nil Environment to build the binary in
+--- @return string|nil binary Path to the test binary
+--- @return string|nil err Build output, if the build failed
+function M.ensure(dir, build_flags, env)
+  local key, err = M.key(dir, build_flags, env)
+  if not key then
+    return nil, err
+  end
+  local binary = M.binary_path(dir, build_flags, key)
+  if vim.uv.fs_stat(binary) then
+    logger.debug("Reusing prebuilt test binary: " .. binary)
+    return binary, nil
+  end
+
+  -- Build into a file of its own and move it into place, so that runs which
+  -- build the same binary at the same time never see a partial binary
+  vim.fn.mkdir(M.cache_dir(), "p")
+  local tmp_binary = binary
+    .. "."
+    .. vim.uv.os_getpid()
+    .. "-"
+    .. vim.uv.hrtime()
+    .. ".tmp"
+  local command = { "go", "test", "-c", "-o", tmp_binary }
+  vim.list_extend(command, build_flags)
+  table.insert(command, ".")
+  logger.info("Building test binary: " .. table.concat(command, " "))
+  local result = cmd.system(command, { cwd = dir, env = env, text = true })
+  if result.code ~= 0 or not vim.uv.fs_stat(tmp_binary) then
+    os.remove(tmp_binary)
+    -- NOTE: `go test -c` writes no binary for a package without tests
+    return nil, (result.stderr or "") .. (result.stdout or "")
+  end
+  local renamed, rename_err = vim.uv.fs_rename(tmp_binary, binary)
+  if not renamed then
+    os.remove(tmp_binary)
+    return nil, rename_err
+  end
+
+  remove_outdated(binary)
+  return binary, nil
+end
+
+return M
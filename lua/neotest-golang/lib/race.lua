@@ -0,0 +1,309 @@
+--- Parse the `WARNING: DATA RACE` reports of the race detector (`-race`) into
+--- structured records, and turn them into diagnostics.
+---
+--- A report looks like this:
+---
+---   ==================
+---   WARNING: DATA RACE
+---   Write at 0x00c00001c0b8 by goroutine 8:
+---     example.com/pkg.(*Counter).Inc()
+---         /home/user/pkg/counter.go:12 +0x44
+---
+---   Previous read at 0x00c00001c0b8 by goroutine 7:
+---     example.com/pkg.(*Counter).Value()
+---         /home/user/pkg/counter.go:16 +0x3a
+---
+---   Goroutine 8 (running) created at:
+---     example.com/pkg.TestCounter()
+---         /home/user/pkg/counter_test.go:10 +0x84
+---   ==================
+
+local file = require("neotest-golang.lib.file")
+local file_diagnostics = require("neotest-golang.lib.file_diagnostics")
+local path = require("neotest-golang.lib.path")
+local stack = require("neotest-golang.lib.stack")
+
+local M = {}
+
+--- @class RaceAccess
+--- @field action string The kind of access, e.g. "Write" or "Previous read"
+--- @field goroutine string The accessing goroutine, e.g. "goroutine 8"
+--- @field frames StackFrame[] Stack of the access, innermost first
+
+--- @class RaceGoroutine
+--- @field goroutine string The goroutine, e.g. "Goroutine 8 (running)"
+--- @field frames StackFrame[] Stack of where the goroutine was created
+
+--- @class RaceReport
+--- @field current? RaceAccess The access which triggered the report
+--- @field previous? RaceAccess The conflicting access which happened before
+--- @field created RaceGoroutine[] Creation sites of the involved goroutines
+--- @field variable? string Name of the raced variable, when it is a global
+--- @field subject? string What raced, memoized by `subject`
+
+--- Parse all race reports found in the output.
+--- @param lines string[] Lines of output
+--- @return RaceReport[]
+function M.parse(lines)
+  local reports = {}
+  local report = nil
+
+  local i = 1
+  while i <= #lines do
+    local line = vim.trim(lines[i])
+    local next_i = i + 1
+
+    if line == "WARNING: DATA RACE" then
+      report = { created = {} }
+      table.insert(reports, report)
+    elseif report and line:match("^=+$") then
+      report = nil
+    elseif report then
+      local action, goroutine = line:match("^(%a[%a ]*) at 0x%x+ by (.-):$")
+      local created = line:match("^(Goroutine %d+ %b()) created at:$")
+      local variable = line:match("^Location is global '([^']+)'")
+
+      if action then
+        local frames
+        frames, next_i = stack.parse_frames(lines, i + 1)
+        local access =
+          { action = action, goroutine = goroutine, frames = frames }
+        if action:match("^Previous ") then
+          report.previous = access
+        else
+          report.current = access
+        end
+      elseif created then
+        local frames
+        frames, next_i = stack.parse_frames(lines, i + 1)
+        table.insert(report.created, { goroutine = created, frames = frames })
+      elseif variable then
+        report.variable = variable
+      end
+    end
+
+    i = next_i
+  end
+
+  return reports
+end
+
+--- Determine if output parts contain a race report, without splitting them
+--- into lines. This is called for the output of every test.
+--- @param output_parts string[]
+--- @return boolean
+local function has_report(output_parts)
+  for _, part in ipairs(output_parts) do
+    if string.find(part, "WARNING: DATA RACE", 1, true) then
+      return true
+    end
+  end
+  return false
+end
+
+--- Parse all race reports found in output parts.
+--- @param output_parts string[]
+--- @return RaceReport[]
+function M.parse_parts(output_parts)
+  if not has_report(output_parts) then
+    return {}
+  end
+  return M.parse(stack.to_lines(output_parts))
+end
+
+--- Read a line of source code, if the file is available locally.
+--- @param filename string
+--- @param line_number integer
+--- @return string|nil
+local function read_source_line(filename, line_number)
+  local ok, lines = pcall(file.read_lines, filename)
+  if ok and lines[line_number] then
+    local source = vim.trim(lines[line_number])
+    if source ~= "" then
+      return source
+    end
+  end
+  return nil
+end
+
+--- Describe a frame's location, e.g. "counter.go:12".
+--- @param frame StackFrame
+--- @return string
+local function location(frame)
+  return path.get_filename_fast(frame.filename) .. ":" .. frame.line_number
+end
+
+--- Describe an access, e.g. "write at counter.go:12 by goroutine 8".
+--- @param access RaceAccess
+--- @return string
+local function describe_access(access)
+  local action = access.action:gsub("^Previous ", ""):lower()
+  local frame = stack.first_user_frame(access.frames)
+  if frame then
+    return action .. " at " .. location(frame) .. " by " .. access.goroutine
+  end
+  return action .. " by " .. access.goroutine
+end
+
+--- Describe what raced: the global variable, the source code of the access,
+--- or the function which accessed it. The source code is read once per
+--- report.
+--- @param report RaceReport
+--- @return string
+function M.subject(report)
+  if report.subject then
+    return report.subject
+  end
+  if report.variable then
+    report.subject = report.variable
+    return report.subject
+  end
+  local frame = report.current and stack.first_user_frame(report.current.frames)
+  if not frame then
+    report.subject = "unknown location"
+    return report.subject
+  end
+  report.subject = read_source_line(frame.filename, frame.line_number)
+    or stack.short_function(frame.func)
+  return report.subject
+end
+
+--- Summarize a report in a single line.
+--- @param report RaceReport
+--- @return string
+function M.summary(report)
+  local accesses = {}
+  if report.current then
+    table.insert(accesses, describe_access(report.current))
+  end
+  if report.previous then
+    table.insert(accesses, "previous " .. describe_access(report.previous))
+  end
+  return "DATA RACE on "
+    .. M.subject(report)
+    .. ": "
+    .. table.concat(accesses, ", ")
+end
+
+--- Build the diagnostics of a report: one for each access and one for each
+--- goroutine creation site, at the innermost frame outside of the standard
+--- library.
+--- @param report RaceReport
+--- @return {filename: string, line_number: integer, message: string, severity: integer, access: boolean}[]
+function M.diagnostics(report)
+  local diagnostics = {}
+
+  local function add(frame, message, severity, access)
+    if frame then
+      table.insert(diagnostics, {
+        filename = frame.filename,
+        line_number = frame.line_number,
+        message = message,
+        severity = severity,
+        access = access,
+      })
+    end
+  end
+
+  local subject = M.subject(report)
+  if report.current then
+    local message = "DATA RACE on "
+      .. subject
+      .. ": "
+      .. describe_access(report.current)
+    if report.previous then
+      message = message
+        .. ", conflicts with previous "
+        .. describe_access(report.previous)
+    end
+    add(
+      stack.first_user_frame(report.current.frames),
+      message,
+      vim.diagnostic.severity.ERROR,
+      true
+    )
+  end
+  if report.previous then
+    local message = "DATA RACE on "
+      .. subject
+      .. ": previous "
+      .. describe_access(report.previous)
+    if report.current then
+      message = message
+        .. ", conflicts with "
+        .. describe_access(report.current)
+    end
+    add(
+      stack.first_user_frame(report.previous.frames),
+      message,
+      vim.diagnostic.severity.ERROR,
+      true
+    )
+  end
+  for _, created in ipairs(report.created) do
+    add(
+      stack.first_user_frame(created.frames),
+      "DATA RACE on "
+        .. subject
+        .. ": "
+        .. created.goroutine
+        .. " created here",
+      vim.diagnostic.severity.WARN,
+      false
+    )
+  end
+
+  return diagnostics
+end
+
+--- Turn race reports into diagnostics. Diagnostics in the file of the given
+--- position are returned, all others are published on the buffer of their
+--- file. When neither access happened directly in the position's file, the
+--- summary is put on the innermost frame of the accesses inside that file,
+--- once per line.
+--- @param reports RaceReport[]
+--- @param position_id string|nil The position the output belongs to
+--- @return neotest.Error[]
+function M.process(reports, position_id)
+  ---@type neotest.Error[]
+  local errors = {}
+  local pos_file = file_diagnostics.position_file(position_id)
+  --- Lines which hold a summary already, by "file:line".
+  --- @type table<string, boolean>
+  local summarized = {}
+
+  for _, report in ipairs(reports) do
+    local access_in_pos_file = false
+    for _, diagnostic in ipairs(M.diagnostics(report)) do
+      local err = file_diagnostics.to_position_error(diagnostic, pos_file)
+      if err then
+        access_in_pos_file = access_in_pos_file or diagnostic.access
+        table.insert(errors, err)
+      end
+    end
+
+    if pos_file and not access_in_pos_file then
+      local accesses = { report.current, report.previous }
+      for _, access in pairs(accesses) do
+        for _, frame in ipairs(access.frames) do
+          if file_diagnostics.same_file(frame.filename, pos_file) then
+            local key = frame.filename .. ":" .. frame.line_number
+            if not summarized[key] then
+              summarized[key] = true
+              table.insert(errors, {
+                line = frame.line_number - 1,
+                message = M.summary(report),
+                severity = vim.diagnostic.severity.ERROR,
+              })
+            end
+            break
+          end
+        end
+      end
+    end
+  end
+
+  return errors
+end
+
+return M
@@ -0,0 +1,123 @@
+--- Helpers for replaying a saved `go test -json` log, e.g. a CI artifact,
+--- into the test tree without running any tests.
+
+local options = require("neotest-golang.options")
+require("neotest-golang.lib.types")
+
+local M = {}
+
+--- @class ReplayArgs
+--- @field filepath string Absolute path to the `go test -json` log
+--- @field path_mappings? table<string, string> Path prefix -> local path prefix
+
+--- Parse path mappings given as "from=to" arguments.
+--- @param args string[] Arguments like "/home/runner/work/repo/repo=~/code/repo"
+--- @return table<string, string> mappings Path prefix -> local path prefix
+--- @return string[] invalid Arguments which are not a mapping
+function M.parse_path_mappings(args)
+  local mappings = {}
+  local invalid = {}
+  for _, arg in ipairs(args) do
+    local from, to = arg:match("^(.-)=(.*)$")
+    if from and from ~= "" and to ~= "" then
+      mappings[from] = vim.fn.expand(to)
+    else
+      table.insert(invalid, arg)
+    end
+  end
+  return mappings, invalid
+end
+
+--- Get the configured path mappings, extended with the given mappings.
+--- @param mappings table<string, string>|nil Mappings which take precedence
+--- @return table<string, string>
+function M.get_path_mappings(mappings)
+  local configured = options.get().replay_path_mappings or {}
+  if type(configured) == "function" then
+    configured = configured()
+  end
+  return vim.tbl_extend("force", configured, mappings or {})
+end
+
+--- Replace path prefixes in text. The longest prefix is replaced first, so
+--- that nested mappings behave as expected.
+--- @param text string
+--- @param mappings table<string, string> Path prefix -> local path prefix
+--- @return string
+function M.remap_text(text, mappings)
+  local prefixes = vim.tbl_keys(mappings)
+  table.sort(prefixes, function(a, b)
+    return #a > #b
+  end)
+
+  for _, prefix in ipairs(prefixes) do
+    local replaced = {}
+    local start = 1
+    while true do
+      local i, j = text:find(prefix, start, true)
+      if not i then
+        table.insert(replaced, text:sub(start))
+        break
+      end
+      table.insert(replaced, text:sub(start, i - 1))
+      table.insert(replaced, mappings[prefix])
+      start = j + 1
+    end
+    text = table.concat(replaced)
+  end
+  return text
+end
+
+--- Remap the paths in the lines of a `go test -json` log. JSON lines are
+--- decoded, so that escaped paths (e.g. Windows paths) are remapped correctly.
+--- Other lines, like build errors written to stderr, are remapped as-is.
+--- @param lines string[] Lines of the log
+--- @param mappings table<string, string> Path prefix -> local path prefix
+--- @return string[]
+function M.remap_lines(lines, mappings)
+  if vim.tbl_isempty(mappings) then
+    return lines
+  end
+
+  local remapped = {}
+  for _, line in ipairs(lines) do
+    local ok, e = false, nil
+    if string.match(line, "^%s*{") then
+      ok, e = pcall(vim.json.decode, line)
+    end
+    if ok and type(e) == "table" then
+      if type(e.Output) == "string" then
+        e.Output = M.remap_text(e.Output, mappings)
+      end
+      table.insert(remapped, vim.json.encode(e))
+    else
+      table.insert(remapped, M.remap_text(line, mappings))
+    end
+  end
+  return remapped
+end
+
+--- Determine the exit code `go test` would have had when producing the log.
+--- @param gotest_output GoTestEvent[] Array of go test JSON events
+--- @return integer
+function M.exit_code(gotest_output)
+  for _, e in ipairs(gotest_output) do
+    if e.Action == "fail" then
+      return 1
+    end
+  end
+  return 0
+end
+
+--- Build the command which prints the log, so that its events are streamed
+--- the same way as the output of `go test -json`.
+--- @param filepath string
+--- @return string[]
+function M.print_command(filepath)
+  if vim.fn.has("win32") == 1 then
+    return { "cmd.exe", "/c", "type", filepath }
+  end
+  return { "cat", filepath }
+end
+
+return M
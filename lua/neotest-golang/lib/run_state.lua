@@ -0,0 +1,130 @@
+--- State of a single run: its extra args, and the results, diagnostics and
+--- metrics collected while its output streams in. Each runspec carries its own
+--- state in its context, so that runs which overlap, like a file run and a
+--- nearest-test run, don't mix their results or args.
+---
+--- Code which works on behalf of a run gets its state with `current()`. The
+--- state is bound with `with()` to the coroutine which builds the runspec,
+--- streams its output or collects its results, and unbound when it is done.
+--- Those may yield and interleave with other runs, but a coroutine only works
+--- on one run at a time. Commands, which work outside of a run, ask for the
+--- run which finished last with `last_finished()`.
+
+local logger = require("neotest-golang.lib.logging")
+
+local M = {}
+
+--- @class RunState
+--- @field pos_id string|nil The position which is run
+--- @field extra_args table The extra args the run was started with
+--- @field cached_results table<string, neotest.Result> Results streamed so far
+--- @field metrics StreamingMetrics|nil Performance metrics of the streaming
+--- @field failed_mappings table<string, boolean> Tests without a position
+--- @field file_diagnostics {by_file: table<string, FileDiagnostic[]>, seen: table<string, boolean>} Diagnostics in other files
+--- @field timeline table<string, TimelineTest[]> Timelines per package
+--- @field shuffle ShuffleRun Seeds of shuffled packages
+--- @field helper_files {resolved: table<string, string|false>, lines: table<string, string[]|false>} Files of diagnostics reported by helpers
+
+--- States bound to coroutines, which are dropped along with the coroutine.
+--- @type table<thread|table, RunState>
+local bound = setmetatable({}, { __mode = "k" })
+
+--- Key used outside of coroutines.
+local main = {}
+
+--- The state of the run whose results were collected last.
+--- @type RunState|nil
+local last_finished = nil
+
+--- Whether a coroutine can yield from within `pcall`, which LuaJIT and Lua
+--- 5.2+ allow, but Lua 5.1 does not.
+local yieldable_pcall = jit ~= nil or _VERSION ~= "Lua 5.1"
+
+--- @return thread|table
+local function key()
+  return coroutine.running() or main
+end
+
+--- Create the state of a new run.
+--- @param pos_id string|nil The position which is run
+--- @param extra_args table|nil The extra args of the run
+--- @return RunState
+function M.new(pos_id, extra_args)
+  --- @type RunState
+  local state = {
+    pos_id = pos_id,
+    extra_args = extra_args or {},
+    cached_results = {},
+    metrics = nil,
+    failed_mappings = {},
+    file_diagnostics = { by_file = {}, seen = {} },
+    timeline = {},
+    shuffle = { pos_id = pos_id, seeds = {} },
+    helper_files = { resolved = {}, lines = {} },
+  }
+  return state
+end
+
+--- Bind a state to the running coroutine, until the returned function is
+--- called, which restores the previously bound state. Prefer `with()`, which
+--- also restores it when raising.
+--- @param state RunState
+--- @return fun() unbind
+function M.bind(state)
+  local k = key()
+  local previous = bound[k]
+  bound[k] = state
+  return function()
+    bound[k] = previous
+  end
+end
+
+--- Mark a run as finished, once its results were collected.
+--- @param state RunState
+function M.finish(state)
+  last_finished = state
+end
+
+--- Get the state of the run the running coroutine works on. Raises when the
+--- coroutine doesn't work on a run, rather than handing out the state of
+--- another run.
+--- @return RunState
+function M.current()
+  local state = bound[key()]
+  if not state then
+    logger.error("No run state is bound to the running coroutine")
+  end
+  return state
+end
+
+--- Get the state of the run whose results were collected last.
+--- @return RunState|nil
+function M.last_finished()
+  return last_finished
+end
+
+--- Call a function with a state bound to the running coroutine, and restore
+--- the previously bound state afterwards, also when the function raises.
+--- NOTE: the function may yield, so it is only called with pcall where
+--- yielding from within pcall is allowed, i.e. not on Lua 5.1.
+--- @generic T
+--- @param state RunState
+--- @param fn fun(): T
+--- @return T
+function M.with(state, fn)
+  local unbind = M.bind(state)
+  if not yieldable_pcall then
+    local result = fn()
+    unbind()
+    return result
+  end
+
+  local ok, result = pcall(fn)
+  unbind()
+  if not ok then
+    error(result, 0)
+  end
+  return result
+end
+
+return M
@@ -0,0 +1,80 @@
+--- Keeps track of the seeds printed by `go test -shuffle`, so that a run which
+--- failed because of the order of its tests can be reproduced.
+
+local run_state = require("neotest-golang.lib.run_state")
+
+local M = {}
+
+--- @class ShuffleSeed
+--- @field package string Go package import path
+--- @field seed string The seed which was used to shuffle the tests
+--- @field status string The status of the package
+
+--- @class ShuffleRun
+--- @field pos_id string|nil The position which was run
+--- @field seeds ShuffleSeed[] Seeds, in the order the packages completed
+
+--- Parse the seed from the line printed by a shuffled test binary, e.g.
+--- "-test.shuffle 1700000000000000000".
+--- @param output string Output of a `go test -json` event
+--- @return string|nil seed
+function M.parse_seed(output)
+  return output:match("^%-test%.shuffle (%-?%d+)")
+end
+
+--- The seeds of the run which was shuffled last. Runs which are not shuffled
+--- don't report a seed, and keep the seeds of the last shuffled run around.
+--- @type ShuffleRun|nil
+local last_shuffled = nil
+
+--- Forget the seeds of the previous run, before a new run starts.
+--- @param pos_id string|nil The position which is about to be run
+function M.reset(pos_id)
+  run_state.current().shuffle = { pos_id = pos_id, seeds = {} }
+  last_shuffled = nil
+end
+
+--- Record the seed used for a package.
+--- @param package_import string Go package import path
+--- @param seed string The shuffle seed
+--- @param status string The status of the package
+function M.record(package_import, seed, status)
+  local shuffle = run_state.current().shuffle
+  table.insert(shuffle.seeds, {
+    package = package_import,
+    seed = seed,
+    status = status,
+  })
+  last_shuffled = shuffle
+end
+
+--- Get the seeds of the run which was shuffled last.
+--- @return ShuffleRun
+function M.get()
+  return last_shuffled or { seeds = {} }
+end
+
+--- Get the seeds worth re-running with: the seeds of failed packages, or all
+--- seeds if no package failed. Packages sharing a seed are only listed once.
+--- @param seeds ShuffleSeed[]
+--- @return ShuffleSeed[]
+function M.candidates(seeds)
+  local failed = vim.tbl_filter(function(entry)
+    return entry.status == "failed"
+  end, seeds)
+  if #failed == 0 then
+    failed = seeds
+  end
+
+  local candidates = {}
+  local seen = {}
+  for _, entry in ipairs(failed) do
+    if not seen[entry.seed] then
+      seen[entry.seed] = true
+      table.insert(candidates, entry)
+    end
+  end
+  return candidates
+end
+
+return M
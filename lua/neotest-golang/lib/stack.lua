@@ -0,0 +1,122 @@
+--- Helpers for the goroutine stack traces printed by Go, e.g. by the race
+--- detector, goleak and panics. A frame consists of two lines: the function,
+--- followed by an indented "file:line" location.
+---
+---   example.com/pkg.(*Counter).Inc()
+---       /home/user/pkg/counter.go:12 +0x44
+
+local M = {}
+
+--- @class StackFrame
+--- @field func string The function, e.g. "example.com/pkg.(*Counter).Inc"
+--- @field filename string Absolute path to the source file
+--- @field line_number integer 1-based line number
+
+--- Split output parts into lines, which stack traces are parsed from.
+--- @param output_parts string[]
+--- @return string[]
+function M.to_lines(output_parts)
+  local lines = {}
+  for _, part in ipairs(output_parts) do
+    vim.list_extend(lines, vim.split(part, "\n", { trimempty = true }))
+  end
+  return lines
+end
+
+--- Parse the location line of a stack frame.
+--- @param line string Line like "\t/home/user/pkg/counter.go:12 +0x44"
+--- @return string|nil filename
+--- @return integer|nil line_number
+function M.parse_location(line)
+  local filename, line_number = line:match("^%s+(%S.-%.go):(%d+)")
+  if not filename then
+    return nil, nil
+  end
+  return filename, tonumber(line_number)
+end
+
+--- Parse the function line of a stack frame, dropping call arguments.
+--- @param line string Line like "  example.com/pkg.(*Counter).Inc()"
+--- @return string|nil
+function M.parse_function(line)
+  local func = vim.trim(line)
+  if func == "" or func:match("%.go:%d+") then
+    return nil
+  end
+  -- Drop the arguments, e.g. "pkg.worker(0xc000012345, 0x1)" or "pkg.F(...)"
+  func = func:gsub("%([^()]*%)$", "")
+  return func
+end
+
+--- Parse consecutive frames, starting at the given line.
+--- @param lines string[] Lines of output
+--- @param start integer Index of the first function line
+--- @return StackFrame[] frames
+--- @return integer next Index of the first line after the frames
+function M.parse_frames(lines, start)
+  local frames = {}
+  local i = start
+  while i < #lines do
+    local func = M.parse_function(lines[i])
+    local filename, line_number = M.parse_location(lines[i + 1])
+    if not func or not filename then
+      break
+    end
+    table.insert(frames, {
+      func = func,
+      filename = filename,
+      line_number = line_number,
+    })
+    i = i + 2
+  end
+  return frames, i
+end
+
+--- Get the package import path of a frame's function, e.g. "example.com/pkg"
+--- for "example.com/pkg.(*Counter).Inc".
+--- @param func string
+--- @return string
+function M.package_of(func)
+  local dir, name = func:match("^(.*/)([^/]*)$")
+  if not dir then
+    dir, name = "", func
+  end
+  return dir .. name:gsub("%..*$", "")
+end
+
+--- Determine if a frame belongs to the Go standard library: its import path
+--- has no dot in its first element, and its file lives in GOROOT/src.
+--- @param frame StackFrame
+--- @return boolean
+function M.is_stdlib(frame)
+  local pkg = M.package_of(frame.func)
+  if pkg:match("^[^/]*%.") then
+    return false
+  end
+  local normalized = frame.filename:gsub("\\", "/")
+  return normalized:find("/src/" .. pkg .. "/", 1, true) ~= nil
+end
+
+--- Get the innermost frame which is not part of the standard library, or
+--- the innermost frame if all frames are.
+--- @param frames StackFrame[]
+--- @return StackFrame|nil
+function M.first_user_frame(frames)
+  for _, frame in ipairs(frames) do
+    if not M.is_stdlib(frame) then
+      return frame
+    end
+  end
+  return frames[1]
+end
+
+--- Get the name of a frame's function without its package path, e.g.
+--- "(*Counter).Inc" for "example.com/pkg.(*Counter).Inc".
+--- @param func string
+--- @return string
+function M.short_function(func)
+  local name = func:match("([^/]*)$")
+  return (name:gsub("^[^.]*%.", ""))
+end
+
+return M
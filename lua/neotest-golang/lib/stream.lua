@@ -1,16 +1,17 @@
+local file_diagnostics = require("neotest-golang.lib.file_diagnostics")
+local golden = require("neotest-golang.lib.golden")
 local json = require("neotest-golang.lib.json")
 local logger = require("neotest-golang.lib.logging")
 local mapping = require("neotest-golang.lib.mapping")
 local metrics = require("neotest-golang.lib.metrics")
 local options = require("neotest-golang.options")
 local results_stream = require("neotest-golang.results_stream")
+local run_state = require("neotest-golang.lib.run_state")
+local timeline = require("neotest-golang.lib.timeline")
 require("neotest-golang.lib.types")
 
 local M = {}
 
----@type table<string, neotest.Result>
-M.cached_results = {}
-
 ---Global stream strategy override for testing
 ---@type table|nil
 M._test_stream_strategy = nil
@@ -21,12 +22,20 @@ function M.set_test_strategy(strategy)
   M._test_stream_strategy = strategy
 end
 
+---Get the results cached by the stream of a run, see run_state.lua.
+---@param state RunState The state of the run
+---@return table<string, neotest.Result>
+function M.cached_results(state)
+  return state.cached_results
+end
+
 ---Atomically transfer ownership of cached results and clear the cache.
 ---This optimization eliminates the copy-then-clear pattern.
+---@param state RunState The state of the run
 ---@return table<string, neotest.Result>
-function M.transfer_cached_results()
-  local results = M.cached_results
-  M.cached_results = {}
+function M.transfer_cached_results(state)
+  local results = state.cached_results
+  state.cached_results = {}
   return results
 end
 
@@ -61,21 +70,39 @@ end
 ---Streaming continues until the returned `stop_filestream` function is called,
 ---typically by `results_finalize.lua` when ready to aggregate final results.
 ---
+---## Run State
+---
+---Results, metrics, timelines and diagnostics are collected in the state of the run
+---which is being built (see `run_state.lua`), also when Neotest calls the stream
+---function from another coroutine, so that overlapping runs don't mix them.
+---
 ---@param tree neotest.Tree The Neotest tree containing test positions
 ---@param golist_data table Output from `go list -json` containing package information
 ---@param json_filepath string|nil Path to gotestsum JSON output file (required for gotestsum runner)
+---@param runner "go"|"gotestsum"|nil Runner whose output is streamed, defaults to the configured runner
 ---@return function stream_function Function that processes test events and returns cached results
 ---@return function stop_function Function to stop streaming and clean up resources
-function M.new(tree, golist_data, json_filepath)
+function M.new(tree, golist_data, json_filepath, runner)
+  runner = runner or options.get().runner
+
+  -- The state of the run which is being built
+  local state = run_state.current()
+
   -- Start performance monitoring session
   metrics.start_session()
 
+  -- Forget the timeline of the previous run
+  timeline.reset()
+
+  -- Forget the diagnostics in other files of the previous run
+  file_diagnostics.reset()
+
   -- No-op filestream functions for gotestsum runner
   local filestream_data = function() end -- no-op
   local stop_filestream = function() end -- no-op
 
   -- Asynchronous file-based streaming strategy for gotestsum
-  if not M._test_stream_strategy and options.get().runner == "gotestsum" then
+  if not M._test_stream_strategy and runner == "gotestsum" then
     if not json_filepath then
       logger.error("JSON filepath is required for gotestsum runner streaming")
     end
@@ -87,7 +114,7 @@ function M.new(tree, golist_data, json_filepath)
 
   -- Synchronous file-based streaming strategy override for testing
   if M._test_stream_strategy then
-    if options.get().runner ~= "gotestsum" then
+    if runner ~= "gotestsum" then
       logger.error(
         "Custom stream strategy can only be used with gotestsum runner"
       )
@@ -112,58 +139,97 @@ function M.new(tree, golist_data, json_filepath)
       "Built position lookup with " .. vim.tbl_count(lookup) .. " mappings"
     )
 
+    -- Tests which use golden files, to find the files involved in failures
+    local golden_positions = golden.positions(tree)
+
     return function()
-      local lines = {}
-      if options.get().runner == "go" then
-        lines = data() -- capture `go test -json` output from stdout stream
-      elseif options.get().runner == "gotestsum" then
-        lines = filestream_data() or {} -- capture `go test -json` output from file stream
-
-        -- Validate that we have data or file exists
-        if #lines == 0 and json_filepath then
-          local file_stat = vim.uv.fs_stat(json_filepath)
-          if file_stat and file_stat.size > 0 then
-            logger.debug(
-              "Gotestsum file exists but no lines read yet, size: "
-                .. file_stat.size
-            )
-          elseif not file_stat then
-            logger.debug(
-              "Gotestsum JSON file does not exist yet: " .. json_filepath
-            )
+      return run_state.with(state, function()
+        local lines = {}
+        if runner == "go" then
+          lines = data() -- capture `go test -json` output from stdout stream
+        elseif runner == "gotestsum" then
+          lines = filestream_data() or {} -- capture `go test -json` output from file stream
+
+          -- Validate that we have data or file exists
+          if #lines == 0 and json_filepath then
+            local file_stat = vim.uv.fs_stat(json_filepath)
+            if file_stat and file_stat.size > 0 then
+              logger.debug(
+                "Gotestsum file exists but no lines read yet, size: "
+                  .. file_stat.size
+              )
+            elseif not file_stat then
+              logger.debug(
+                "Gotestsum JSON file does not exist yet: " .. json_filepath
+              )
+            end
+          elseif #lines > 0 then
+            logger.debug("Gotestsum read " .. #lines .. " lines from file")
           end
-        elseif #lines > 0 then
-          logger.debug("Gotestsum read " .. #lines .. " lines from file")
         end
-      end
 
-      ---@type GoTestEvent[]
-      gotest_events = json.decode_from_table(lines, true)
+        ---@type GoTestEvent[]
+        gotest_events = json.decode_from_table(lines, true)
 
-      -- Process all events synchronously
-      for _, gotest_event in ipairs(gotest_events) do
-        -- Record event processing for metrics
-        if gotest_event.Action then
-          metrics.record_event(gotest_event.Action)
-        end
-
-        accum =
-          results_stream.process_event(golist_data, accum, gotest_event, lookup)
-      end
-
-      -- Record memory usage metrics
-      metrics.record_accum_size(vim.tbl_count(accum))
-      metrics.record_cache_size(vim.tbl_count(M.cached_results))
+        -- Process all events synchronously
+        for _, gotest_event in ipairs(gotest_events) do
+          -- Record event processing for metrics
+          if gotest_event.Action then
+            metrics.record_event(gotest_event.Action)
+          end
 
-      -- Optimized: Direct cache population eliminates intermediate results and copy loop
-      results_stream.make_stream_results_with_cache(accum, M.cached_results)
+          accum = results_stream.process_event(
+            golist_data,
+            accum,
+            gotest_event,
+            lookup
+          )
+        end
 
-      -- Return the cache for compatibility with existing streaming interface
-      return M.cached_results
+        -- Record memory usage metrics
+        metrics.record_accum_size(vim.tbl_count(accum))
+        metrics.record_cache_size(vim.tbl_count(state.cached_results))
+
+        -- Optimized: Direct cache population eliminates intermediate results and copy loop
+        results_stream.make_stream_results_with_cache(
+          accum,
+          state.cached_results,
+          golist_data,
+          golden_positions
+        )
+
+        -- Return the cache for compatibility with existing streaming interface
+        return state.cached_results
+      end)
     end
   end
 
   return stream, stop_filestream
 end
 
+---Process complete `go test -json` output at once into the cached results,
+---e.g. output which was converted with test2json after a debug session.
+---@param tree neotest.Tree The Neotest tree containing test positions
+---@param golist_data table Output from `go list -json` containing package information
+---@param lines string[] Lines of `go test -json` output
+---@param state RunState The state of the run, which must be bound
+---@return table<string, neotest.Result>
+function M.process_lines(tree, golist_data, lines, state)
+  local lookup = mapping.build_position_lookup(tree, golist_data)
+  ---@type table<string, TestEntry>
+  local accum = {}
+  for _, gotest_event in ipairs(json.decode_from_table(lines, true)) do
+    accum =
+      results_stream.process_event(golist_data, accum, gotest_event, lookup)
+  end
+  local cached_results = M.cached_results(state)
+  results_stream.make_stream_results_with_cache(
+    accum,
+    cached_results,
+    golist_data,
+    golden.positions(tree)
+  )
+  return cached_results
+end
+
 return M
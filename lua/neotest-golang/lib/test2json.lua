@@ -0,0 +1,76 @@
+--- Convert the output of a test binary which was run directly, e.g. under a
+--- debugger, into `go test -json` events. The binary is run with
+--- `-test.v=test2json`, which makes it frame its output for `go tool
+--- test2json`, just like `go test -json` does.
+
+local cmd = require("neotest-golang.lib.cmd")
+local logger = require("neotest-golang.lib.logging")
+
+local M = {}
+
+--- The argument which makes a test binary print its output for test2json.
+M.verbose_arg = "-test.v=test2json"
+
+--- Replace the verbosity arguments of a test binary with the one for
+--- test2json.
+--- @param args string[]|nil Arguments of the test binary
+--- @return string[]
+function M.with_verbose_arg(args)
+  local result = {}
+  for _, arg in ipairs(args or {}) do
+    if not arg:match("^%-%-?test%.v$") and not arg:match("^%-%-?test%.v=") then
+      table.insert(result, arg)
+    end
+  end
+  table.insert(result, M.verbose_arg)
+  return result
+end
+
+--- Build the `go tool test2json` command for a package.
+--- @param import_path string|nil Import path of the tested package
+--- @return string[]
+function M.command(import_path)
+  local command = { "go", "tool", "test2json" }
+  if import_path then
+    vim.list_extend(command, { "-p", import_path })
+  end
+  return command
+end
+
+--- Build the command which runs a test binary through `go tool test2json`, so
+--- that its output is printed as `go test -json` events while it runs.
+--- @param import_path string|nil Import path of the tested package
+--- @param binary string Path to the test binary
+--- @param test_run_pattern string|nil Pattern of the tests to run
+--- @param args string[] Other arguments of the test binary
+--- @return string[]
+function M.binary_command(import_path, binary, test_run_pattern, args)
+  local command = M.command(import_path)
+  vim.list_extend(command, { "-t", binary })
+  if test_run_pattern then
+    vim.list_extend(command, { "-test.run", test_run_pattern })
+  end
+  return vim.list_extend(command, M.with_verbose_arg(args))
+end
+
+--- Convert the output of a test binary into `go test -json` lines.
+--- @async
+--- @param lines string[] Output of the test binary
+--- @param import_path string|nil Import path of the tested package
+--- @return string[] Lines of JSON events, empty if conversion failed
+function M.convert(lines, import_path)
+  if #lines == 0 then
+    return {}
+  end
+  local result = cmd.system(
+    M.command(import_path),
+    { stdin = table.concat(lines, "\n") .. "\n", text = true }
+  )
+  if result.code ~= 0 then
+    logger.warn({ "Could not convert test output with test2json: ", result })
+    return {}
+  end
+  return vim.split(result.stdout or "", "\n", { trimempty = true })
+end
+
+return M
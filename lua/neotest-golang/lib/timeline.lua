@@ -0,0 +1,293 @@
+--- Collects the timestamps of `go test -json` run/pause/cont/pass/fail/skip
+--- events and turns them into a per-package timeline, showing when each test
+--- was actually running and when it was paused, waiting for `t.Parallel()`
+--- tests to be allowed to continue.
+
+local run_state = require("neotest-golang.lib.run_state")
+require("neotest-golang.lib.types")
+
+local M = {}
+
+--- @class TimelineEvent
+--- @field action string The `go test -json` action
+--- @field time number Seconds since the Unix epoch
+
+--- @class TimelineSegment
+--- @field state "running"|"paused" What the test was doing
+--- @field start number Seconds since the Unix epoch
+--- @field stop number Seconds since the Unix epoch
+
+--- @class TimelineTest
+--- @field test string The `go test` test name
+--- @field status? string The final status of the test
+--- @field events TimelineEvent[] Timestamped events, in the order received
+
+--- Actions which are kept in a test's timeline.
+M.actions = {
+  start = true,
+  run = true,
+  pause = true,
+  cont = true,
+  pass = true,
+  fail = true,
+  skip = true,
+}
+
+--- Width of the bar, in characters, rendered for each test.
+M.bar_width = 60
+
+--- Characters used for rendering the bar of each test.
+M.chars = {
+  running = "█",
+  paused = "░",
+  idle = " ",
+}
+
+--- Days since 1970-01-01 for a proleptic Gregorian calendar date.
+--- See http://howardhinnant.github.io/date_algorithms.html#days_from_civil
+--- @param y integer
+--- @param m integer
+--- @param d integer
+--- @return integer
+local function days_from_civil(y, m, d)
+  if m <= 2 then
+    y = y - 1
+  end
+  local era = math.floor(y / 400)
+  local yoe = y - era * 400
+  local mp = (m + 9) % 12
+  local doy = math.floor((153 * mp + 2) / 5) + d - 1
+  local doe = yoe * 365 + math.floor(yoe / 4) - math.floor(yoe / 100) + doy
+  return era * 146097 + doe - 719468
+end
+
+--- Parse the RFC 3339 `Time` field of a `go test -json` event.
+--- @param str string|nil Timestamp like "2024-05-01T12:00:00.123456+02:00"
+--- @return number|nil Seconds since the Unix epoch, or nil if not parseable
+function M.parse_time(str)
+  if type(str) ~= "string" then
+    return nil
+  end
+
+  local y, mo, d, h, mi, s, frac, tz =
+    str:match("^(%d+)-(%d+)-(%d+)T(%d+):(%d+):(%d+)(%.?%d*)(.*)$")
+  if not y then
+    return nil
+  end
+
+  local offset = 0
+  if tz ~= "" and tz ~= "Z" then
+    local sign, tz_h, tz_m = tz:match("^([+-])(%d+):(%d+)$")
+    if not sign then
+      return nil
+    end
+    offset = tonumber(tz_h) * 3600 + tonumber(tz_m) * 60
+    if sign == "-" then
+      offset = -offset
+    end
+  end
+
+  local days = days_from_civil(tonumber(y), tonumber(mo), tonumber(d))
+  local seconds = days * 86400
+    + tonumber(h) * 3600
+    + tonumber(mi) * 60
+    + tonumber(s)
+  if frac ~= "" and frac ~= "." then
+    seconds = seconds + tonumber("0" .. frac)
+  end
+  return seconds - offset
+end
+
+--- Append a timestamped event to a timeline, if it is an event worth keeping.
+--- @param timeline TimelineEvent[] Timeline to append to
+--- @param e GoTestEvent The event data
+function M.append(timeline, e)
+  if not M.actions[e.Action] then
+    return
+  end
+  local time = M.parse_time(e.Time)
+  if time then
+    table.insert(timeline, { action = e.Action, time = time })
+  end
+end
+
+--- Clear the timeline of the run, before it starts.
+function M.reset()
+  run_state.current().timeline = {}
+end
+
+--- Record the complete timeline of a test.
+--- @param package_import string Go package import path
+--- @param test_name string Go test name
+--- @param events TimelineEvent[] Timestamped events of the test
+--- @param status string|nil Final status of the test
+function M.record(package_import, test_name, events, status)
+  -- Timelines of the run: package import path -> tests, in the order they were
+  -- completed.
+  local timelines = run_state.current().timeline
+  if not timelines[package_import] then
+    timelines[package_import] = {}
+  end
+  table.insert(timelines[package_import], {
+    test = test_name,
+    status = status,
+    events = events,
+  })
+end
+
+--- Get the timelines of the run whose results were collected last.
+--- @return table<string, TimelineTest[]>
+function M.get()
+  local state = run_state.last_finished()
+  return state and state.timeline or {}
+end
+
+--- Turn a test's events into segments of running and paused time.
+--- @param events TimelineEvent[] Timestamped events of a test
+--- @return TimelineSegment[]
+function M.segments(events)
+  local segments = {}
+  local current = nil
+
+  local function close(time)
+    if current then
+      current.stop = time
+      table.insert(segments, current)
+      current = nil
+    end
+  end
+
+  for _, event in ipairs(events) do
+    if event.action == "run" or event.action == "cont" then
+      close(event.time)
+      current = { state = "running", start = event.time }
+    elseif event.action == "pause" then
+      close(event.time)
+      current = { state = "paused", start = event.time }
+    elseif
+      event.action == "pass"
+      or event.action == "fail"
+      or event.action == "skip"
+    then
+      close(event.time)
+    end
+  end
+
+  return segments
+end
+
+--- Sum up the time spent in each state.
+--- @param segments TimelineSegment[]
+--- @return table<string, number> State -> seconds
+local function totals(segments)
+  local sums = { running = 0, paused = 0 }
+  for _, segment in ipairs(segments) do
+    sums[segment.state] = sums[segment.state] + (segment.stop - segment.start)
+  end
+  return sums
+end
+
+--- Render a bar where each character represents a slice of the package's
+--- wall clock time.
+--- @param segments TimelineSegment[]
+--- @param first number Start of the package, in seconds since the epoch
+--- @param span number Duration of the package, in seconds
+--- @return string
+local function render_bar(segments, first, span)
+  local cells = {}
+  for i = 1, M.bar_width do
+    -- Sample the middle of each cell
+    local time = first + span * (i - 0.5) / M.bar_width
+    local char = M.chars.idle
+    for _, segment in ipairs(segments) do
+      if time >= segment.start and time < segment.stop then
+        char = M.chars[segment.state]
+        break
+      end
+    end
+    table.insert(cells, char)
+  end
+  return table.concat(cells)
+end
+
+--- Render the timeline of one package into lines.
+--- @param package_import string Go package import path
+--- @param tests TimelineTest[] Tests of the package
+--- @return string[]
+function M.render_package(package_import, tests)
+  local rows = {}
+  local first, last = nil, nil
+  local name_width = 4
+
+  for _, test in ipairs(tests) do
+    local segments = M.segments(test.events)
+    if #segments > 0 then
+      table.insert(rows, { test = test, segments = segments })
+      local start = segments[1].start
+      local stop = segments[#segments].stop
+      first = first and math.min(first, start) or start
+      last = last and math.max(last, stop) or stop
+      name_width = math.max(name_width, #test.test)
+    end
+  end
+
+  if #rows == 0 then
+    return { package_import, "  (no timed test events)" }
+  end
+
+  -- Show the tests in the order they started
+  table.sort(rows, function(a, b)
+    if a.segments[1].start == b.segments[1].start then
+      return a.test.test < b.test.test
+    end
+    return a.segments[1].start < b.segments[1].start
+  end)
+
+  local span = math.max(last - first, 1e-6)
+  local lines = {
+    string.format("%s (%.2fs wall clock)", package_import, last - first),
+  }
+  for _, row in ipairs(rows) do
+    local sums = totals(row.segments)
+    table.insert(
+      lines,
+      string.format(
+        "  %-" .. name_width .. "s |%s| +%.2fs, ran %.2fs, paused %.2fs%s",
+        row.test.test,
+        render_bar(row.segments, first, span),
+        row.segments[1].start - first,
+        sums.running,
+        sums.paused,
+        row.test.status and (" (" .. row.test.status .. ")") or ""
+      )
+    )
+  end
+  return lines
+end
+
+--- Render the timelines of all packages into lines.
+--- @param timelines table<string, TimelineTest[]> Package import path -> tests
+--- @return string[]
+function M.render(timelines)
+  local packages = vim.tbl_keys(timelines)
+  table.sort(packages)
+
+  local lines = {
+    "Legend: "
+      .. M.chars.running
+      .. " running  "
+      .. M.chars.paused
+      .. " paused (waiting for parallel tests)",
+    "",
+  }
+  for _, package_import in ipairs(packages) do
+    vim.list_extend(
+      lines,
+      M.render_package(package_import, timelines[package_import])
+    )
+    table.insert(lines, "")
+  end
+  return lines
+end
+
+return M
@@ -4,11 +4,18 @@
 --- @field pos_id string Neotest tree position id.
 --- @field golist_data table<string, string> The 'go list' JSON data (lua table).
 --- @field errors? table<string> Non-gotest errors to show in the final output.
---- @field is_dap_active boolean? If true, parsing of test output will occur.
+--- @field is_dap_active boolean? If true, the test binary ran under DAP and its output is converted with test2json.
 --- @field skipped? boolean If true, the position has no tests and result parsing is skipped.
 --- @field test_output_json_filepath? string Gotestsum JSON filepath.
 --- @field stop_filestream fun() Stops the stream of test output.
 --- @field process_test_results? boolean Used in test.lua specifically
+--- @field golden_snapshot? {dir: string, files: table<string, string>} Golden files before updating them.
+--- @field runner? "go"|"gotestsum" Overrides the configured runner, e.g. when replaying a log.
+--- @field replay? boolean If true, a saved `go test -json` log is replayed.
+--- @field replay_filepath? string Temporary copy of the replayed log, with its paths remapped.
+--- @field attachable_binary? string Prebuilt test binary which the debugger can attach to.
+--- @field failure_breakpoint? FailureBreakpoint Temporary breakpoint where the debugged test failed.
+--- @field state? RunState The state of the run, which keeps it apart from runs which overlap.
 
 --- @class GoListItem
 --- @field ImportPath string The import path of the Go package
@@ -19,12 +26,19 @@
 --- @field GoMod? string Path to go.mod file
 --- @field TestGoFiles? string[] List of test files in the package
 --- @field XTestGoFiles? string[] List of external test files in the package
+--- @field Errors? string[] Errors loading the package or its dependencies
 
 --- Internal test metadata, required for processing.
 --- @class TestMetadata
 --- @field position_id? string The neotest position ID for this test
 --- @field output_parts string[] Raw output parts collected during streaming
 --- @field output_path? string Path to the finalized output file
+--- @field elapsed? number Elapsed time in seconds, as reported by the pass/fail/skip event
+--- @field golden_files? string[] Golden files involved in a failed test
+--- @field leaks? LeakedGoroutine[] Goroutines reported as leaked by goleak
+--- @field race_reports? RaceReport[] Data races reported by the race detector
+--- @field shuffle_seed? string Seed printed by `go test -shuffle`, for packages
+--- @field timeline? TimelineEvent[] Timestamped run/pause/cont/pass/fail/skip events, while streaming
 --- @field state? "streaming"|"streamed"|"finalized" State of the test entry's processing
 
 --- The accumulated test data. This holds both the Neotest result for the test and also internal metadata.
@@ -35,7 +49,7 @@
 --- The `go test -json` event structure.
 --- @class GoTestEvent
 --- @field Time? string ISO 8601 timestamp when the event occurred
---- @field Action "start"|"run"|"output"|"build-output"|"skip"|"fail"|"pass" Test action
+--- @field Action "start"|"run"|"pause"|"cont"|"output"|"build-output"|"skip"|"fail"|"pass" Test action
 --- @field Package? string Package name being tested
 --- @field Test? string Test name (present when Action relates to a specific test)
 --- @field Elapsed? number Time elapsed in seconds
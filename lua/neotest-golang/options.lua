@@ -10,17 +10,26 @@ local M = {}
 ---@field gotestsum_args string[]|fun(): string[] Arguments for gotestsum command
 ---@field go_list_args string[]|fun(): string[] Arguments for go list command
 ---@field dap_go_opts table|fun(): table DAP configuration for dap-go
----@field dap_mode string|fun(): string "dap-go" or "manual"
+---@field dap_mode string|fun(): string "dap-go", "manual" or "dlv"
 ---@field dap_manual_config table|fun(): table Manual DAP configuration
+---@field dap_dlv_opts table|fun(): table Options of the built-in delve adapter
 ---@field env table|fun(): table Environment variables
 ---@field filter_dirs string[]|fun(): string[] Filtered directories (deprecated, use filter_dir_patterns)
 ---@field filter_dir_patterns string[]|fun(): string[] Glob patterns for filtering directories
 ---@field testify_enabled boolean Enable testify suite support
 ---@field testify_import_identifier string Regex pattern for testify import identifiers
+---@field diagnostic_parsers (string|DiagnosticParser)[] Parsers for the output of assertion libraries
+---@field diagnostic_rules {pattern: string, severity: string|integer, files?: string|string[], packages?: string|string[]}[] Rules to classify diagnostics by their message
+---@field show_log_hints boolean Show logged messages as hints
 ---@field colorize_test_output boolean Enable colored test output
 ---@field warn_test_name_dupes boolean Warn about duplicate test names
 ---@field log_level integer Vim log level
 ---@field sanitize_output boolean Sanitize test output
+---@field slow_test_threshold number Seconds after which a test is flagged as slow (0 disables)
+---@field export_results {junit?: string, tap?: string}|fun(): {junit?: string, tap?: string} Paths to export JUnit XML/TAP reports to
+---@field replay_path_mappings table<string, string>|fun(): table<string, string> Path prefixes to translate when replaying a log
+---@field prebuilt_test_binaries boolean Run tests from cached, prebuilt test binaries
+---@field persistent_discovery_cache boolean Keep discovered tests on disk across sessions
 ---@field dev_notifications boolean Enable development notifications (experimental)
 ---@field performance_monitoring boolean Enable streaming performance metrics collection (experimental)
 
@@ -31,29 +40,77 @@ local opts = {
   gotestsum_args = { "--format=standard-verbose" }, -- NOTE: can also be a function
   go_list_args = {}, -- NOTE: can also be a function
   dap_go_opts = {}, -- NOTE: can also be a function
-  dap_mode = "dap-go", -- NOTE: or "manual" ; can also be a function
+  dap_mode = "dap-go", -- NOTE: or "manual" or "dlv" ; can also be a function
   dap_manual_config = {}, -- NOTE: can also be a function
+  dap_dlv_opts = {}, -- NOTE: can also be a function
   env = {}, -- NOTE: can also be a function
   filter_dirs = { ".git", "node_modules", ".venv", "venv" }, -- DEPRECATED: use filter_dir_patterns instead
   filter_dir_patterns = {}, -- NOTE: can also be a function
   testify_enabled = false,
   testify_import_identifier = "^(suite)$",
+  diagnostic_parsers = {
+    "testify",
+    "gotest_tools",
+    "quicktest",
+    "is",
+    "gomega",
+    "gomock",
+  },
+  diagnostic_rules = {},
+  show_log_hints = true,
   colorize_test_output = true,
   warn_test_name_dupes = true,
   log_level = vim.log.levels.WARN,
   sanitize_output = false,
+  slow_test_threshold = 0,
+  export_results = {}, -- NOTE: can also be a function
+  replay_path_mappings = {}, -- NOTE: can also be a function
+  prebuilt_test_binaries = false,
+  persistent_discovery_cache = false,
 
   -- experimental, for now undocumented, options
   dev_notifications = false,
   performance_monitoring = false,
 }
 
+--- Drop the diagnostic rules whose pattern is not a valid regex, so that they
+--- are reported once instead of for every diagnostic.
+---@param rules table[]|nil
+---@return table[]|nil
+local function valid_diagnostic_rules(rules)
+  if type(rules) ~= "table" then
+    return rules
+  end
+  local diagnostics = require("neotest-golang.lib.diagnostics")
+  local valid = {}
+  for _, rule in ipairs(rules) do
+    local regex, err = nil, "not a string"
+    if type(rule.pattern) == "string" then
+      regex, err = diagnostics.rule_regex(rule.pattern)
+    end
+    if regex then
+      table.insert(valid, rule)
+    else
+      require("neotest-golang.lib.logging").warn(
+        "Invalid pattern of diagnostic rule: "
+          .. tostring(rule.pattern)
+          .. " ("
+          .. tostring(err)
+          .. ")",
+        true
+      )
+    end
+  end
+  return valid
+end
+
 ---@param user_opts NeotestGolangOptions?
 function M.setup(user_opts)
   if type(user_opts) == "table" and not vim.tbl_isempty(user_opts) then
     for k, v in pairs(user_opts) do
       opts[k] = v
     end
+    opts.diagnostic_rules = valid_diagnostic_rules(opts.diagnostic_rules)
   end
 end
 
@@ -68,6 +125,9 @@ function M.set(updated_opts)
   for k, v in pairs(updated_opts) do
     opts[k] = v
   end
+  if updated_opts.diagnostic_rules then
+    opts.diagnostic_rules = valid_diagnostic_rules(opts.diagnostic_rules)
+  end
   return opts
 end
 
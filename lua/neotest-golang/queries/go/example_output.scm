@@ -0,0 +1,19 @@
+; ============================================================================
+; RESPONSIBILITY: Output comments of Example functions
+; ============================================================================
+; Captures Example functions and all comments, so that the comments within an
+; Example function can be checked for the `// Output:` or
+; `// Unordered output:` comment which `go test` compares the printed output
+; with. Examples without such a comment are compiled, but never run.
+;
+; Example with captures:
+;   func ExampleAdd() {     // @example.name = "ExampleAdd"
+;     fmt.Println(Add(2, 2))
+;     // Output: 4          // @example.comment
+;   }                       // @example.definition = entire function
+; ============================================================================
+((function_declaration
+  name: (identifier) @example.name)
+  (#lua-match? @example.name "^Example")) @example.definition
+
+(comment) @example.comment
@@ -4,6 +4,8 @@ local lib = require("neotest.lib")
 
 local discovery_cache = require("neotest-golang.lib.discovery_cache")
 local dupe = require("neotest-golang.lib.dupe")
+local examples = require("neotest-golang.lib.examples")
+local golden = require("neotest-golang.lib.golden")
 local logger = require("neotest-golang.lib.logging")
 local options = require("neotest-golang.options")
 local query_loader = require("neotest-golang.lib.query_loader")
@@ -39,27 +41,9 @@ function M.has_go_parser()
   return false
 end
 
---- Detect test names in Go *._test.go files.
---- Uses caching to avoid redundant parsing when the file hasn't changed.
---- This prevents performance issues when DAP-UI or other plugins trigger
---- multiple buffer events rapidly.
---- @param file_path string Absolute path to the Go test file
---- @return neotest.Tree|nil Tree of detected tests, or nil if parsing failed
-function M.detect_tests(file_path)
-  local cached = discovery_cache.get(file_path)
-  if cached then
-    return cached
-  end
-
-  if not M.has_go_parser() then
-    logger.error(
-      "Go tree-sitter parser not found. Install with :TSInstall go",
-      true
-    )
-    return nil
-  end
-
-  local opts = { nested_tests = true }
+--- Build the queries which test files are parsed with.
+--- @return string
+function M.build_query()
   local query = M.test_function
     .. M.table_tests_list
     .. M.table_tests_loop
@@ -84,19 +68,62 @@ function M.detect_tests(file_path)
       .. testify.query.table_tests_list_query
   end
 
+  return query
+end
+
+--- Detect test names in Go *._test.go files.
+--- Uses caching to avoid redundant parsing when the file hasn't changed.
+--- This prevents performance issues when DAP-UI or other plugins trigger
+--- multiple buffer events rapidly.
+--- @param file_path string Absolute path to the Go test file
+--- @return neotest.Tree|nil Tree of detected tests, or nil if parsing failed
+function M.detect_tests(file_path)
+  local query = M.build_query()
+
+  local cached = discovery_cache.get(file_path, query)
+  if cached then
+    return cached
+  end
+
+  if not M.has_go_parser() then
+    logger.error(
+      "Go tree-sitter parser not found. Install with :TSInstall go",
+      true
+    )
+    return nil
+  end
+
+  local opts = { nested_tests = true }
+
+  -- Stamp the file before reading it, so that a change while it is parsed
+  -- leads to another stamp, and the tree is cached under the key of the
+  -- source it was parsed from
+  local stamp = discovery_cache.stamp(file_path, query)
+
+  -- Read the file once, for parsing and for marking its tests below
+  local source = lib.files.read(file_path)
+  local lines = vim.split(source, "\n", { plain = true })
+
   ---@type neotest.Tree
-  local tree = lib.treesitter.parse_positions(file_path, query, opts)
+  local tree =
+    lib.treesitter.parse_positions_from_string(file_path, source, query, opts)
 
   if options.get().testify_enabled == true then
     tree = testify.tree_modification.modify_neotest_tree(file_path, tree)
   end
 
+  -- Mark tests which compare against golden files
+  tree = golden.mark_tree(file_path, tree, lines)
+
+  -- Mark Example functions which are never run, for lack of output comment
+  tree = examples.mark_tree(file_path, tree, source)
+
   -- Check for duplicate subtests in the tree
   if options.get().warn_test_name_dupes then
     dupe.warn_duplicate_tests(tree)
   end
 
-  discovery_cache.set(file_path, tree)
+  discovery_cache.set(file_path, tree, query, stamp, source)
   return tree
 end
 
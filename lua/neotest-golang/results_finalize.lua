@@ -12,12 +12,29 @@ local M = {}
 
 --- Finalize test results by creating root result and populating missing aggregated results.
 --- This is the main orchestrator that processes test output and fills in missing file/directory results.
+--- The results are collected in the state of the run, so that runs which overlap don't mix them.
 --- @async
 --- @param spec neotest.RunSpec
 --- @param result neotest.StrategyResult
 --- @param tree neotest.Tree
 --- @return table<string, neotest.Result>
 function M.test_results(spec, result, tree)
+  local state = spec.context.state
+  return lib.run_state.with(state, function()
+    local results = M.finalize(spec, result, tree)
+    -- Commands like the timeline work on the run which finished last
+    lib.run_state.finish(state)
+    return results
+  end)
+end
+
+--- Finalize test results of the current run, see `test_results`.
+--- @async
+--- @param spec neotest.RunSpec
+--- @param result neotest.StrategyResult
+--- @param tree neotest.Tree
+--- @return table<string, neotest.Result>
+function M.finalize(spec, result, tree)
   --- @type RunspecContext
   local context = spec.context
 
@@ -34,22 +51,41 @@ function M.test_results(spec, result, tree)
   -- Report any failed position mappings collected during streaming
   lib.mapping.report_failed_mappings()
 
+  -- Show diagnostics outside of the tests' files, e.g. of data races. The
+  -- output of a debugged test binary is only processed below.
+  if not context.is_dap_active then
+    lib.file_diagnostics.publish()
+  end
+
+  -- An attachable run can no longer be attached to, its binary is kept in the
+  -- cache of prebuilt test binaries
+  if context.attachable_binary then
+    require("neotest-golang.features.dap.attach").unregister(
+      context.attachable_binary
+    )
+  end
+
+  -- The breakpoint where a debugged test failed is only for its session, which
+  -- may also have never started
+  if context.failure_breakpoint then
+    require("neotest-golang.features.dap.failure_breakpoint").remove(
+      context.failure_breakpoint
+    )
+  end
+
+  -- The remapped copy of a replayed log is no longer needed
+  if context.replay and context.replay_filepath then
+    os.remove(context.replay_filepath)
+  end
+
   -- Get final cached results after streaming is complete (atomic transfer)
   ---@type table<string, neotest.Result>
-  local results = lib.stream.transfer_cached_results()
+  local results = lib.stream.transfer_cached_results(context.state)
 
   --- Final Neotest results, the way Neotest wants it returned.
   --- @type table<string, neotest.Result>
   local skipped_result = {}
 
-  if context.is_dap_active then
-    -- return early if test result processing is not desired.
-    skipped_result[context.pos_id] = {
-      status = "skipped",
-    }
-    return skipped_result
-  end
-
   if context.skipped then
     -- The position has no tests to run (e.g. a file without Go test functions),
     -- so there is no test output to parse.
@@ -61,12 +97,30 @@ function M.test_results(spec, result, tree)
 
   --- The runner to use for running tests.
   --- @type string
-  local runner = options.get().runner
+  local runner = context.runner or options.get().runner
 
   --- The output from the test command, as captured by stdout.
   --- @type table<string>
   local output = {}
-  if runner == "go" then
+  if context.is_dap_active then
+    -- The debugged test binary framed its output for test2json
+    output = M.dap_output(spec, result)
+    if #output == 0 then
+      -- The output of the test binary was not captured, e.g. with a DAP
+      -- configuration which shows it in a terminal instead.
+      skipped_result[context.pos_id] = {
+        status = "skipped",
+      }
+      return skipped_result
+    end
+    lib.stream.process_lines(tree, context.golist_data, output, context.state)
+    results = vim.tbl_extend(
+      "force",
+      results,
+      lib.stream.transfer_cached_results(context.state)
+    )
+    lib.file_diagnostics.publish()
+  elseif runner == "go" then
     if not result.output then
       logger.error("Go test output file is missing")
     end
@@ -92,11 +146,53 @@ function M.test_results(spec, result, tree)
   --- @type GoTestEvent[]
   local gotest_output = lib.json.decode_from_table(output, true)
 
+  if context.replay or context.is_dap_active then
+    -- The exit code is the one of printing the replayed log or of the
+    -- debugger, not the one of the `go test` run which produced the output.
+    result = vim.tbl_extend(
+      "force",
+      result,
+      { code = lib.replay.exit_code(gotest_output) }
+    )
+  end
+
   -- Populate missing file results with aggregated data from child tests (bottom-up)
   results = M.populate_missing_file_results(tree, results)
 
+  -- Summarize the golden files which an update run changed on disk
+  local golden_summary = nil
+  if context.golden_snapshot then
+    golden_summary = lib.golden.summary_lines(
+      lib.golden.changes(context.golden_snapshot),
+      context.golden_snapshot.dir
+    )
+    logger.info(table.concat(golden_summary, "\n"))
+  end
+
   -- Register root node result in the cached results
-  results[pos.id] = M.create_root_result(results[pos.id], result, gotest_output)
+  results[pos.id] = M.create_root_result(
+    results[pos.id],
+    result,
+    gotest_output,
+    golden_summary
+  )
+
+  -- Example functions without output comment are compiled, but never run
+  results = lib.examples.mark_not_run(tree, results)
+
+  -- Export JUnit XML and/or TAP reports, if configured. Relative paths are
+  -- resolved from the project, not from the package which was tested.
+  local export_paths = lib.export.get_paths()
+  if export_paths.junit or export_paths.tap then
+    local lookup = lib.mapping.build_position_lookup(tree, context.golist_data)
+    lib.export.write(
+      results,
+      gotest_output,
+      lookup,
+      vim.fn.getcwd(),
+      export_paths
+    )
+  end
 
   -- Track missing results
   local missing = {}
@@ -235,8 +331,14 @@ end
 --- @param results_data table Previous results data (may be nil)
 --- @param result neotest.StrategyResult Test execution result
 --- @param gotest_output GoTestEvent[] Array of go test JSON events
+--- @param golden_summary? string[] Summary of the golden files changed by an update run
 --- @return neotest.Result The root result for the executed position
-function M.create_root_result(results_data, result, gotest_output)
+function M.create_root_result(
+  results_data,
+  result,
+  gotest_output,
+  golden_summary
+)
   local status = "passed"
   if result.code ~= 0 then
     status = "failed"
@@ -254,6 +356,27 @@ function M.create_root_result(results_data, result, gotest_output)
   -- Single-pass colorization of all parts
   local full_output = lib.colorize.colorize_parts(output_parts)
 
+  -- Flag tests which exceeded the slow test threshold, slowest first
+  local slow_summary =
+    lib.duration.slow_summary_lines(lib.duration.slow_tests(gotest_output))
+  if #slow_summary > 0 then
+    logger.info(table.concat(slow_summary, "\n"))
+    table.insert(full_output, "")
+    vim.list_extend(full_output, slow_summary)
+  end
+
+  -- Point out packages which leaked goroutines
+  local leak_summary = lib.goleak.summary_lines(gotest_output)
+  if #leak_summary > 0 then
+    table.insert(full_output, "")
+    vim.list_extend(full_output, leak_summary)
+  end
+
+  if golden_summary then
+    table.insert(full_output, "")
+    vim.list_extend(full_output, golden_summary)
+  end
+
   local output = lib.path.normalize_path(async.fn.tempname())
   lib.file.write_lines_async(output, full_output)
 
@@ -264,6 +387,21 @@ function M.create_root_result(results_data, result, gotest_output)
   }
 end
 
+--- Convert the output of a debugged test binary into `go test -json` lines.
+--- @async
+--- @param spec neotest.RunSpec
+--- @param result neotest.StrategyResult
+--- @return string[]
+function M.dap_output(spec, result)
+  if not result.output or vim.fn.filereadable(result.output) ~= 1 then
+    return {}
+  end
+  local lines = lib.file.read_lines_async(result.output)
+  local import_path =
+    M.get_package_import_path(spec.cwd, spec.context.golist_data or {})
+  return lib.test2json.convert(lines, import_path)
+end
+
 --- Get package import path from directory position ID using golist data
 --- @param pos_id string Directory position ID
 --- @param golist_data table The golist data
@@ -7,10 +7,19 @@
 local colorize = require("neotest-golang.lib.colorize")
 local convert = require("neotest-golang.lib.convert")
 local diagnostics = require("neotest-golang.lib.diagnostics")
+local diff = require("neotest-golang.lib.diff")
+local duration = require("neotest-golang.lib.duration")
+local examples = require("neotest-golang.lib.examples")
+local failures = require("neotest-golang.lib.failures")
 local file = require("neotest-golang.lib.file")
+local golden = require("neotest-golang.lib.golden")
+local goleak = require("neotest-golang.lib.goleak")
 local mapping = require("neotest-golang.lib.mapping")
 local metrics = require("neotest-golang.lib.metrics")
 local path = require("neotest-golang.lib.path")
+local race = require("neotest-golang.lib.race")
+local shuffle = require("neotest-golang.lib.shuffle")
+local timeline = require("neotest-golang.lib.timeline")
 require("neotest-golang.lib.types")
 
 local async = require("neotest.async")
@@ -72,6 +81,13 @@ function M.process_package(golist_data, accum, e, id)
   then
     if e.Output then
       table.insert(accum[id].metadata.output_parts, e.Output)
+
+      -- Keep the seed printed by `go test -shuffle`, to be able to reproduce
+      -- the order of the tests.
+      local seed = shuffle.parse_seed(e.Output)
+      if seed then
+        accum[id].metadata.shuffle_seed = seed
+      end
     end
   end
 
@@ -102,6 +118,15 @@ function M.process_package(golist_data, accum, e, id)
     end
 
     accum[id].metadata.state = "streamed"
+    accum[id].metadata.elapsed = e.Elapsed
+
+    if accum[id].metadata.shuffle_seed then
+      shuffle.record(
+        e.Package,
+        accum[id].metadata.shuffle_seed,
+        accum[id].result.status
+      )
+    end
 
     if e.Output then
       -- NOTE: this does not ever happen, it seems.
@@ -132,6 +157,7 @@ function M.process_test(accum, e, id, position_lookup)
       metadata = {
         state = "streaming",
         output_parts = {},
+        timeline = {},
       },
     }
     if e.Output then
@@ -139,6 +165,11 @@ function M.process_test(accum, e, id, position_lookup)
     end
   end
 
+  -- Keep the time of run/pause/cont and terminal events, for the timeline.
+  if accum[id] and accum[id].metadata.state == "streaming" then
+    timeline.append(accum[id].metadata.timeline, e)
+  end
+
   -- Record output for test.
   if
     accum[id]
@@ -177,12 +208,21 @@ function M.process_test(accum, e, id, position_lookup)
     end
 
     accum[id].metadata.state = "streamed"
+    accum[id].metadata.elapsed = e.Elapsed
 
     if e.Output then
       -- NOTE: this does not ever happen, it seems.
       table.insert(accum[id].metadata.output_parts, e.Output)
     end
 
+    timeline.record(
+      e.Package,
+      e.Test,
+      accum[id].metadata.timeline,
+      accum[id].result.status
+    )
+    accum[id].metadata.timeline = nil
+
     local pos_id = mapping.get_pos_id(position_lookup, e.Package, e.Test)
     if pos_id then
       accum[id].metadata.position_id = pos_id
@@ -211,7 +251,9 @@ end
 ---
 ---4. **Result Finalization**: Creates final `neotest.Result` objects with:
 ---   - Test status (passed/failed/skipped)
----   - Path to output file (nil if no output)
+---   - Path to output file (nil if no output), headed by the elapsed time
+---   - Short text with the elapsed time, when reported by `go test`, the
+---     raced variable of data races and leaked goroutines
 ---   - Processed error diagnostics with line numbers
 ---
 ---5. **Cache Population**: Updates the provided cache directly with position ID as key,
@@ -224,26 +266,97 @@ end
 ---
 ---@param accum table<string, TestEntry> Accumulated test data from streaming (internal format)
 ---@param cache table<string, neotest.Result> The result cache to populate (Neotest format)
-function M.make_stream_results_with_cache(accum, cache)
+---@param golist_data? table The 'go list -json' output, to locate diagnostics in other files
+---@param golden_positions? table<string, boolean> Tests which use golden files, see `golden.positions`
+function M.make_stream_results_with_cache(
+  accum,
+  cache,
+  golist_data,
+  golden_positions
+)
   for _, test_entry in pairs(accum) do
     if test_entry.metadata.position_id ~= nil then
       if test_entry.metadata.state ~= "finalized" then
         if test_entry.metadata.output_parts then
-          test_entry.result.errors = diagnostics.process_diagnostics(test_entry)
+          test_entry.result.errors =
+            diagnostics.process_diagnostics(test_entry, golist_data)
+
+          -- Output mismatches of failed Example functions
+          if test_entry.result.status == "failed" then
+            vim.list_extend(
+              test_entry.result.errors,
+              examples.process(test_entry)
+            )
+          end
+
+          -- Expected/actual values of failed assertions, for a side-by-side diff
+          diff.record(test_entry.metadata.position_id, test_entry.result.errors)
+
+          -- Where a failed test panicked or failed last, to debug it there
+          failures.record(
+            test_entry.metadata.position_id,
+            test_entry.result.status,
+            test_entry.result.errors,
+            test_entry.metadata.output_parts
+          )
+
+          -- Data races reported by `-race`, possibly in other files
+          local reports = race.parse_parts(test_entry.metadata.output_parts)
+          if #reports > 0 then
+            test_entry.metadata.race_reports = reports
+            vim.list_extend(
+              test_entry.result.errors,
+              race.process(reports, test_entry.metadata.position_id)
+            )
+          end
+
+          -- Goroutines reported as leaked by go.uber.org/goleak
+          if goleak.has_report(test_entry.metadata.output_parts) then
+            local leaks = goleak.parse_parts(test_entry.metadata.output_parts)
+            if #leaks > 0 then
+              test_entry.metadata.leaks = leaks
+              vim.list_extend(
+                test_entry.result.errors,
+                goleak.process(leaks, test_entry.metadata.position_id)
+              )
+            end
+          end
+
+          -- Golden files which a failed test compared its output against
+          if
+            test_entry.result.status == "failed"
+            and golden_positions
+            and golden_positions[test_entry.metadata.position_id]
+          then
+            local test_name =
+              convert.pos_id_to_go_test_name(test_entry.metadata.position_id)
+            if test_name then
+              test_entry.metadata.golden_files = golden.involved_files(
+                test_entry.metadata.output_parts,
+                path.get_directory(
+                  path.extract_file_path_from_pos_id(
+                    test_entry.metadata.position_id
+                  )
+                ),
+                test_name
+              )
+            end
+          end
         end
 
         -- Only generate output path and write when there's actual content
         if
-          test_entry.metadata.output_parts
-          and #test_entry.metadata.output_parts > 0
+          (
+            test_entry.metadata.output_parts
+            and #test_entry.metadata.output_parts > 0
+          ) or test_entry.metadata.elapsed
         then
           local temp_path = async.fn.tempname()
           if temp_path and temp_path ~= "" then
             test_entry.metadata.output_path = path.normalize_path(temp_path)
 
             -- Write file synchronously - ensures availability when result is cached
-            local output_lines =
-              colorize.colorize_parts(test_entry.metadata.output_parts)
+            local output_lines = M.output_lines(test_entry)
 
             local success = pcall(
               file.write_lines,
@@ -276,6 +389,7 @@ function M.make_stream_results_with_cache(accum, cache)
         output = test_entry.metadata.output_path, -- nil if no output parts
         errors = test_entry.result.errors,
       }
+      result.short = M.short(test_entry)
 
       test_entry.metadata.state = "finalized"
       cache[test_entry.metadata.position_id] = result
@@ -283,4 +397,56 @@ function M.make_stream_results_with_cache(accum, cache)
   end
 end
 
+---Build the lines of the output file of a test: its colorized output below
+---headers for the elapsed time, data races, leaked goroutines and golden files.
+---@param test_entry TestEntry
+---@return string[]
+function M.output_lines(test_entry)
+  local output_lines =
+    colorize.colorize_parts(test_entry.metadata.output_parts or {})
+  local golden_files = test_entry.metadata.golden_files or {}
+  for i, golden_file in ipairs(golden_files) do
+    table.insert(output_lines, i, "=== Golden file: " .. golden_file .. " ===")
+  end
+  if test_entry.metadata.leaks then
+    local leak_lines = goleak.header_lines(test_entry.metadata.leaks)
+    for i, line in ipairs(leak_lines) do
+      table.insert(output_lines, i, line)
+    end
+  end
+  local race_reports = test_entry.metadata.race_reports or {}
+  for i, report in ipairs(race_reports) do
+    table.insert(output_lines, i, "=== " .. race.summary(report) .. " ===")
+  end
+  if test_entry.metadata.elapsed then
+    table.insert(output_lines, 1, duration.header(test_entry.metadata.elapsed))
+  end
+  return output_lines
+end
+
+---Build the short text of a test's result, e.g. "failed in 0.52s [slow]",
+---with the raced variable and leaked goroutines appended.
+---@param test_entry TestEntry
+---@return string|nil
+function M.short(test_entry)
+  local status = test_entry.result.status
+  local short = nil
+  if test_entry.metadata.elapsed then
+    short = duration.short(status, test_entry.metadata.elapsed)
+  end
+  if test_entry.metadata.race_reports then
+    short = (short or status)
+      .. " [DATA RACE on "
+      .. race.subject(test_entry.metadata.race_reports[1])
+      .. "]"
+  end
+  if test_entry.metadata.leaks then
+    short = (short or status)
+      .. " ["
+      .. goleak.summary(test_entry.metadata.leaks)
+      .. "]"
+  end
+  return short
+end
+
 return M
@@ -0,0 +1,101 @@
+--- Helpers to build the command and context around running tests as a prebuilt
+--- test binary, which the debugger can attach to while it is running.
+
+local attach = require("neotest-golang.features.dap.attach")
+local go_test_args = require("neotest-golang.features.dap.go_test_args")
+local lib = require("neotest-golang.lib")
+local logger = require("neotest-golang.lib.logging")
+local options = require("neotest-golang.options")
+local file = require("neotest-golang.runspec.file")
+
+local M = {}
+
+--- Build flags which keep the test binary debuggable.
+M.debug_build_flags = { "-gcflags=all=-N -l" }
+
+--- Get the `-test.run` pattern of a file or test position.
+--- @param pos neotest.Position
+--- @return string|nil
+local function test_run_pattern(pos)
+  if pos.type == "file" then
+    return file.get_regexp(pos.path)
+  end
+  local test_name = lib.convert.pos_id_to_go_test_name(pos.id)
+  return test_name and lib.convert.to_exact_test_run_pattern(test_name)
+end
+
+--- Build runspec which runs a file or test from a prebuilt test binary. The
+--- binary is run by `go tool test2json`, so that its output streams into the
+--- tree just like the output of `go test -json`.
+--- @param pos neotest.Position Position data for the file or test
+--- @param tree neotest.Tree Neotest tree containing test structure
+--- @return neotest.RunSpec|nil Runspec for executing the tests
+function M.build(pos, tree)
+  if pos.type ~= "file" and pos.type ~= "test" then
+    logger.warn("Only files and tests can be run attachable", true)
+    return nil
+  end
+
+  local dir = lib.path.get_directory(pos.path)
+  local golist_data, golist_errors = lib.cmd.golist_data(dir)
+
+  local import_path = nil
+  for _, item in ipairs(golist_data) do
+    if item.Dir == dir then
+      import_path = item.ImportPath
+    end
+  end
+
+  local resolved = go_test_args.resolve()
+  local build_flags = vim.list_extend(
+    vim.deepcopy(M.debug_build_flags),
+    resolved.build_flags
+  )
+  local binary, build_error =
+    lib.prebuilt.ensure(dir, build_flags, go_test_args.resolve_env())
+  if not binary then
+    logger.error("Could not build the test binary:\n" .. build_error)
+    return nil -- NOTE: logger.error will throw an error, but the LSP doesn't see it.
+  end
+
+  local command = lib.test2json.binary_command(
+    import_path,
+    binary,
+    test_run_pattern(pos),
+    resolved.args
+  )
+
+  local env = lib.extra_args.get().env or options.get().env
+  if type(env) == "function" then
+    env = env()
+  end
+
+  local stream, stop_filestream = lib.stream.new(tree, golist_data, nil, "go")
+
+  --- @type RunspecContext
+  local context = {
+    pos_id = pos.id,
+    state = lib.run_state.current(),
+    golist_data = golist_data,
+    errors = golist_errors,
+    stop_filestream = stop_filestream,
+    runner = "go",
+    attachable_binary = binary,
+  }
+
+  --- @type neotest.RunSpec
+  local run_spec = {
+    command = command,
+    cwd = dir,
+    context = context,
+    env = env,
+    stream = stream,
+  }
+
+  attach.register(binary, dir)
+
+  logger.debug({ "RunSpec:", run_spec })
+  return run_spec
+end
+
+return M
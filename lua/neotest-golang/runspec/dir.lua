@@ -90,15 +90,7 @@ function M.build(pos, tree)
     return nil -- NOTE: logger.error will throw an error, but the LSP doesn't see it.
   end
 
-  local golist_data, golist_error = lib.cmd.golist_data(pos.path)
-
-  local errors = nil
-  if golist_error ~= nil then
-    if errors == nil then
-      errors = {}
-    end
-    table.insert(errors, golist_error)
-  end
+  local golist_data, golist_errors = lib.cmd.golist_data(pos.path)
 
   local package_import_path = find_go_package_import_path(pos, golist_data)
   if not package_import_path then
@@ -120,10 +112,12 @@ function M.build(pos, tree)
   --- @type RunspecContext
   local context = {
     pos_id = pos.id,
+    state = lib.run_state.current(),
     golist_data = golist_data,
-    errors = errors,
+    errors = golist_errors,
     test_output_json_filepath = json_filepath,
     stop_filestream = stop_filestream,
+    golden_snapshot = lib.golden.snapshot_for_update(pos.path),
   }
 
   --- @type neotest.RunSpec
@@ -5,6 +5,7 @@ local find = require("neotest-golang.lib.find")
 local lib = require("neotest-golang.lib")
 local logger = require("neotest-golang.lib.logging")
 local options = require("neotest-golang.options")
+local prebuilt = require("neotest-golang.runspec.prebuilt")
 
 local M = {}
 
@@ -29,15 +30,7 @@ function M.build(pos, tree, strategy)
 
   local go_mod_folderpath = lib.path.get_directory(go_mod_filepath)
   local pos_path_folderpath = lib.path.get_directory(pos.path)
-  local golist_data, golist_error = lib.cmd.golist_data(pos_path_folderpath)
-
-  local errors = nil
-  if golist_error ~= nil then
-    if errors == nil then
-      errors = {}
-    end
-    table.insert(errors, golist_error)
-  end
+  local golist_data, golist_errors = lib.cmd.golist_data(pos_path_folderpath)
 
   -- find the go package that corresponds to the pos.path
   local package_name = "./..."
@@ -78,6 +71,16 @@ function M.build(pos, tree, strategy)
     -- NOTE: could also fall back to running on a per-test basis by using a bare return
   end
 
+  -- Run from the cached test binary of the package, if enabled
+  local runner = nil
+  if package_name ~= "./..." and prebuilt.enabled(strategy) then
+    local prebuilt_cmd =
+      prebuilt.command(pos_path_folderpath, golist_data, regexp)
+    if prebuilt_cmd then
+      test_cmd, json_filepath, runner = prebuilt_cmd, nil, "go"
+    end
+  end
+
   local runspec_strategy = nil
   if strategy == "dap" then
     dap.assert_dap_prerequisites()
@@ -92,15 +95,18 @@ function M.build(pos, tree, strategy)
   end
 
   local stream, stop_filestream =
-    lib.stream.new(tree, golist_data, json_filepath)
+    lib.stream.new(tree, golist_data, json_filepath, runner)
 
   --- @type RunspecContext
   local context = {
     pos_id = pos.id,
+    state = lib.run_state.current(),
     golist_data = golist_data,
-    errors = errors,
+    errors = golist_errors,
     test_output_json_filepath = json_filepath,
     stop_filestream = stop_filestream,
+    golden_snapshot = lib.golden.snapshot_for_update(pos_path_folderpath),
+    runner = runner,
   }
 
   --- @type neotest.RunSpec
@@ -128,6 +134,7 @@ function M.return_skipped(pos)
   --- @type RunspecContext
   local context = {
     pos_id = pos.id,
+    state = lib.run_state.current(),
     golist_data = {}, -- no golist output
     skipped = true, -- no tests to run, skip result parsing
     stop_filestream = function() end, -- no stream to stop
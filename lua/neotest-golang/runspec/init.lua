@@ -4,8 +4,11 @@ require("neotest-golang.lib.types")
 
 local M = {}
 
+M.attachable = require("neotest-golang.runspec.attachable")
 M.dir = require("neotest-golang.runspec.dir")
 M.file = require("neotest-golang.runspec.file")
+M.prebuilt = require("neotest-golang.runspec.prebuilt")
+M.replay = require("neotest-golang.runspec.replay")
 M.test = require("neotest-golang.runspec.test")
 
 return M
@@ -0,0 +1,72 @@
+--- Helpers to build the command which runs tests from the prebuilt test binary
+--- of their package, instead of `go test`.
+
+local go_test_args = require("neotest-golang.features.dap.go_test_args")
+local lib = require("neotest-golang.lib")
+local logger = require("neotest-golang.lib.logging")
+local options = require("neotest-golang.options")
+
+local M = {}
+
+--- Timeout of the test binary, which `go test` passes on by default.
+M.default_timeout = "10m"
+
+--- Determine if the arguments of a test binary set a timeout.
+--- @param args string[]
+--- @return boolean
+local function has_timeout(args)
+  for _, arg in ipairs(args) do
+    if arg:match("^%-%-?test%.timeout") then
+      return true
+    end
+  end
+  return false
+end
+
+--- Determine if tests are to be run from prebuilt test binaries.
+--- @param strategy string|nil Strategy to use (e.g., "dap" for debugging)
+--- @return boolean
+function M.enabled(strategy)
+  return options.get().prebuilt_test_binaries == true and strategy ~= "dap"
+end
+
+--- Build the command which runs tests of a package from its prebuilt test
+--- binary, through `go tool test2json`.
+--- @param dir string Directory of the package
+--- @param golist_data GoListItem[] The 'go list' data
+--- @param test_run_pattern string|nil Pattern of the tests to run
+--- @return string[]|nil command The command, or nil to run `go test` instead
+function M.command(dir, golist_data, test_run_pattern)
+  local resolved = go_test_args.resolve(true)
+  local binary, build_error = lib.prebuilt.ensure(
+    dir,
+    resolved.build_flags,
+    go_test_args.resolve_env()
+  )
+  if not binary then
+    logger.warn({ "Could not build the test binary: ", build_error })
+    return nil
+  end
+
+  local import_path = nil
+  for _, item in ipairs(golist_data) do
+    if item.Dir == dir then
+      import_path = item.ImportPath
+    end
+  end
+
+  -- Like `go test`, stop a run which hangs
+  local args = resolved.args
+  if not has_timeout(args) then
+    table.insert(args, "-test.timeout=" .. M.default_timeout)
+  end
+
+  return lib.test2json.binary_command(
+    import_path,
+    binary,
+    test_run_pattern,
+    args
+  )
+end
+
+return M
@@ -0,0 +1,64 @@
+--- Helpers to build the command and context around replaying a saved
+--- `go test -json` log, instead of running tests.
+
+local lib = require("neotest-golang.lib")
+local logger = require("neotest-golang.lib.logging")
+local path = require("neotest-golang.lib.path")
+
+local M = {}
+
+--- Build runspec for replaying a `go test -json` log into the tree of the
+--- given position. The log is printed by the command and then processed just
+--- like the output of `go test -json`, regardless of the configured runner.
+--- @param pos neotest.Position Position data for the directory, file or test
+--- @param tree neotest.Tree Neotest tree containing test structure
+--- @param replay ReplayArgs The log to replay
+--- @return neotest.RunSpec|nil Runspec for replaying the log
+function M.build(pos, tree, replay)
+  if vim.fn.filereadable(replay.filepath) ~= 1 then
+    logger.error("Cannot replay unreadable file: " .. replay.filepath)
+    return nil -- NOTE: logger.error will throw an error, but the LSP doesn't see it.
+  end
+
+  local cwd = pos.path
+  if pos.type ~= "dir" then
+    cwd = path.get_directory(pos.path)
+  end
+
+  local golist_data, golist_errors = lib.cmd.golist_data(cwd)
+
+  -- Translate paths of the machine which produced the log into local paths
+  local mappings = lib.replay.get_path_mappings(replay.path_mappings)
+  local lines =
+    lib.replay.remap_lines(lib.file.read_lines(replay.filepath), mappings)
+  local replay_filepath = path.normalize_path(vim.fn.tempname())
+  lib.file.write_lines(replay_filepath, lines)
+
+  local stream, stop_filestream =
+    lib.stream.new(tree, golist_data, nil, "go")
+
+  --- @type RunspecContext
+  local context = {
+    pos_id = pos.id,
+    state = lib.run_state.current(),
+    golist_data = golist_data,
+    errors = golist_errors,
+    stop_filestream = stop_filestream,
+    runner = "go",
+    replay = true,
+    replay_filepath = replay_filepath,
+  }
+
+  --- @type neotest.RunSpec
+  local run_spec = {
+    command = lib.replay.print_command(replay_filepath),
+    cwd = cwd,
+    context = context,
+    stream = stream,
+  }
+
+  logger.debug({ "RunSpec:", run_spec })
+  return run_spec
+end
+
+return M
@@ -1,10 +1,13 @@
 --- Helpers to build the command and context around running a single test.
 
 local dap = require("neotest-golang.features.dap")
+local failure_breakpoint =
+  require("neotest-golang.features.dap.failure_breakpoint")
 local find = require("neotest-golang.lib.find")
 local lib = require("neotest-golang.lib")
 local logger = require("neotest-golang.lib.logging")
 local options = require("neotest-golang.options")
+local prebuilt = require("neotest-golang.runspec.prebuilt")
 
 local M = {}
 
@@ -16,15 +19,7 @@ local M = {}
 function M.build(pos, tree, strategy)
   local pos_path_folderpath = lib.path.get_directory(pos.path)
 
-  local golist_data, golist_error = lib.cmd.golist_data(pos_path_folderpath)
-
-  local errors = nil
-  if golist_error ~= nil then
-    if errors == nil then
-      errors = {}
-    end
-    table.insert(errors, golist_error)
-  end
+  local golist_data, golist_errors = lib.cmd.golist_data(pos_path_folderpath)
 
   local test_name = lib.convert.pos_id_to_go_test_name(pos.id)
   if not test_name then
@@ -38,12 +33,35 @@ function M.build(pos, tree, strategy)
     test_name_regex
   )
 
+  -- Run from the cached test binary of the package, if enabled
+  local runner = nil
+  if prebuilt.enabled(strategy) then
+    local prebuilt_cmd = prebuilt.command(
+      pos_path_folderpath,
+      golist_data,
+      lib.convert.to_exact_test_run_pattern(test_name)
+    )
+    if prebuilt_cmd then
+      test_cmd, json_filepath, runner = prebuilt_cmd, nil, "go"
+    end
+  end
+
   local runspec_strategy = nil
+  local breakpoint = nil
   if strategy == "dap" then
     dap.assert_dap_prerequisites()
-    runspec_strategy = dap.get_dap_config(pos_path_folderpath, test_name_regex)
+    local test_run_pattern =
+      dap.test_run_pattern(pos_path_folderpath, test_name)
+    if test_run_pattern == nil then
+      return nil
+    end
+    runspec_strategy =
+      dap.get_dap_config(pos_path_folderpath, test_run_pattern)
     logger.debug("DAP strategy used: " .. vim.inspect(runspec_strategy))
     dap.setup_debugging(pos_path_folderpath)
+    if lib.extra_args.get().debug_at_failure then
+      breakpoint = failure_breakpoint.set(pos.id)
+    end
   end
 
   local env = lib.extra_args.get().env or options.get().env
@@ -52,16 +70,20 @@ function M.build(pos, tree, strategy)
   end
 
   local stream, stop_filestream =
-    lib.stream.new(tree, golist_data, json_filepath)
+    lib.stream.new(tree, golist_data, json_filepath, runner)
 
   --- @type RunspecContext
   local context = {
     pos_id = pos.id,
+    state = lib.run_state.current(),
     golist_data = golist_data,
-    errors = errors,
+    errors = golist_errors,
     process_test_results = true,
     test_output_json_filepath = json_filepath,
     stop_filestream = stop_filestream,
+    golden_snapshot = lib.golden.snapshot_for_update(pos_path_folderpath),
+    runner = runner,
+    failure_breakpoint = breakpoint,
   }
 
   --- @type neotest.RunSpec
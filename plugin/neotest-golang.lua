@@ -0,0 +1,6 @@
+if vim.g.loaded_neotest_golang then
+  return
+end
+vim.g.loaded_neotest_golang = true
+
+require("neotest-golang.commands").setup()
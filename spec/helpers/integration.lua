@@ -27,162 +27,163 @@ local path = require("neotest-golang.lib.path")
 
 local M = {}
 
---- Execute command synchronously and return strategy result
+--- Run command synchronously, within an async context
+--- @async
 --- @param run_spec neotest.RunSpec The built run specification
 --- @return table strategy_result The execution result from strategy
-local function execute_command(run_spec)
-  local nio = require("nio")
-
-  return nio.tests.with_async_context(function()
-    -- Normalize env and cwd
-    local env = run_spec.env
-    if env and vim.tbl_isempty(env) then
-      env = nil
-    end
-
-    print("Go test command:", vim.inspect(run_spec.command))
-    print("Working directory:", run_spec.cwd)
+local function run_command(run_spec)
+  -- Normalize env and cwd
+  local env = run_spec.env
+  if env and vim.tbl_isempty(env) then
+    env = nil
+  end
 
-    -- Run the process synchronously
-    local sys = vim
-      .system(run_spec.command, {
-        cwd = run_spec.cwd,
-        env = env,
-        text = true,
-      })
-      :wait()
+  print("Go test command:", vim.inspect(run_spec.command))
+  print("Working directory:", run_spec.cwd)
 
-    -- Persist stdout/stderr to a temp file for debugging/fallbacks
-    local output_path = nil
-    if
-      (sys.stdout and sys.stdout ~= "") or (sys.stderr and sys.stderr ~= "")
-    then
-      output_path = path.normalize_path(vim.fn.tempname())
-      local lines = {}
-      if sys.stdout and sys.stdout ~= "" then
-        for line in sys.stdout:gmatch("[^\r\n]+") do
-          table.insert(lines, line)
-        end
+  -- Run the process synchronously
+  local sys = vim
+    .system(run_spec.command, {
+      cwd = run_spec.cwd,
+      env = env,
+      text = true,
+    })
+    :wait()
+
+  -- Persist stdout/stderr to a temp file for debugging/fallbacks
+  local output_path = nil
+  if
+    (sys.stdout and sys.stdout ~= "") or (sys.stderr and sys.stderr ~= "")
+  then
+    output_path = path.normalize_path(vim.fn.tempname())
+    local lines = {}
+    if sys.stdout and sys.stdout ~= "" then
+      for line in sys.stdout:gmatch("[^\r\n]+") do
+        table.insert(lines, line)
       end
-      if sys.stderr and sys.stderr ~= "" then
-        table.insert(lines, "")
-        table.insert(lines, "=== stderr ===")
-        for line in sys.stderr:gmatch("[^\r\n]+") do
-          table.insert(lines, line)
-        end
+    end
+    if sys.stderr and sys.stderr ~= "" then
+      table.insert(lines, "")
+      table.insert(lines, "=== stderr ===")
+      for line in sys.stderr:gmatch("[^\r\n]+") do
+        table.insert(lines, line)
       end
-      file.write_lines_async(output_path, lines)
     end
+    file.write_lines_async(output_path, lines)
+  end
 
-    print("Exit code:", sys.code, "Output path:", output_path)
+  print("Exit code:", sys.code, "Output path:", output_path)
 
-    return {
-      code = sys.code or 1,
-      output = output_path,
-    }
-  end)
+  return {
+    code = sys.code or 1,
+    output = output_path,
+  }
 end
 
---- Execute command with true streaming using adapter's stream functionality
+--- Execute command synchronously and return strategy result
 --- @param run_spec neotest.RunSpec The built run specification
---- @param tree neotest.Tree The discovered test tree
 --- @return table strategy_result The execution result from strategy
-local function execute_command_streaming(run_spec, tree)
+local function execute_command(run_spec)
   local nio = require("nio")
+  return nio.tests.with_async_context(run_command, run_spec)
+end
 
-  return nio.tests.with_async_context(function()
-    print("[STREAMING] Go test command:", vim.inspect(run_spec.command))
-    print("[STREAMING] Working directory:", run_spec.cwd)
-    print("[STREAMING] Using adapter's streaming functionality...")
+--- Run command with true streaming using adapter's stream functionality,
+--- within an async context
+--- @async
+--- @param run_spec neotest.RunSpec The built run specification
+--- @return table strategy_result The execution result from strategy
+local function run_command_streaming(run_spec)
+  local nio = require("nio")
 
-    -- Normalize env
-    local env = run_spec.env
-    if env and vim.tbl_isempty(env) then
-      env = nil
-    end
+  print("[STREAMING] Go test command:", vim.inspect(run_spec.command))
+  print("[STREAMING] Working directory:", run_spec.cwd)
+  print("[STREAMING] Using adapter's streaming functionality...")
 
-    local start_time = vim.fn.reltime()
-    local output_lines = {}
+  -- Normalize env
+  local env = run_spec.env
+  if env and vim.tbl_isempty(env) then
+    env = nil
+  end
 
-    -- Use vim.system() async with streaming
-    local future = nio.control.future()
+  local start_time = vim.fn.reltime()
+  local output_lines = {}
 
-    vim.system(run_spec.command, {
-      cwd = run_spec.cwd,
-      env = env,
-      text = true,
-      stdout = function(err, chunk)
-        if chunk then
-          for line in chunk:gmatch("[^\r\n]+") do
-            table.insert(output_lines, line)
-          end
+  -- Use vim.system() async with streaming
+  local future = nio.control.future()
+
+  vim.system(run_spec.command, {
+    cwd = run_spec.cwd,
+    env = env,
+    text = true,
+    stdout = function(err, chunk)
+      if chunk then
+        for line in chunk:gmatch("[^\r\n]+") do
+          table.insert(output_lines, line)
         end
-      end,
-    }, function(obj)
-      future.set(obj)
-    end)
+      end
+    end,
+  }, function(obj)
+    future.set(obj)
+  end)
 
-    -- Wait for completion
-    local sys = future.wait()
-    local elapsed_time = vim.fn.reltimestr(vim.fn.reltime(start_time))
+  -- Wait for completion
+  local sys = future.wait()
+  local elapsed_time = vim.fn.reltimestr(vim.fn.reltime(start_time))
 
-    -- Create output file
-    local output_path = nil
-    if #output_lines > 0 then
-      output_path = path.normalize_path(nio.fn.tempname())
-      file.write_lines_async(output_path, output_lines)
-    end
+  -- Create output file
+  local output_path = nil
+  if #output_lines > 0 then
+    output_path = path.normalize_path(nio.fn.tempname())
+    file.write_lines_async(output_path, output_lines)
+  end
 
-    -- Process streaming results using adapter's stream function if available
-    if run_spec.stream then
-      print("[STREAMING] Using adapter's stream function to process output...")
-      local stream_fn = run_spec.stream(function()
-        return output_lines
-      end)
-      -- Call the stream function to process all events
-      stream_fn()
-    end
+  -- Process streaming results using adapter's stream function if available
+  if run_spec.stream then
+    print("[STREAMING] Using adapter's stream function to process output...")
+    local stream_fn = run_spec.stream(function()
+      return output_lines
+    end)
+    -- Call the stream function to process all events
+    stream_fn()
+  end
 
-    print(
-      "[STREAMING] Process completed in",
-      elapsed_time,
-      "seconds, exit code:",
-      sys.code,
-      "processed",
-      #output_lines,
-      "lines, output path:",
-      output_path
-    )
+  print(
+    "[STREAMING] Process completed in",
+    elapsed_time,
+    "seconds, exit code:",
+    sys.code,
+    "processed",
+    #output_lines,
+    "lines, output path:",
+    output_path
+  )
 
-    return {
-      code = sys.code or 1,
-      output = output_path,
-    }
-  end)
+  return {
+    code = sys.code or 1,
+    output = output_path,
+  }
 end
 
---- Execute a real test using the adapter's build_spec and results methods directly
---- This bypasses neotest.run.run and calls the adapter interface directly
----
---- Position ID Format Examples:
---- "/path/to/directory"                                    -- Directory (all tests)
---- "/path/to/file_test.go"                                 -- File (all tests in file)
---- "/path/to/file_test.go::TestFunction"                   -- Specific test
---- "/path/to/file_test.go::TestFunction::\"SubTest\""        -- Subtest
---- "/path/to/file_test.go::TestFunction::\"SubTest\"::\"TableTest\"" -- Nested subtest
----
+--- Execute command with true streaming using adapter's stream functionality
+--- @param run_spec neotest.RunSpec The built run specification
+--- @param tree neotest.Tree The discovered test tree
+--- @return table strategy_result The execution result from strategy
+local function execute_command_streaming(run_spec, tree)
+  local nio = require("nio")
+  return nio.tests.with_async_context(run_command_streaming, run_spec)
+end
+
+--- Discover the tree of a position and build its run spec
 --- @param position_id string Neotest position ID (directory, file, or test position)
---- @param opts ExecutionOpts? Optional execution configuration
---- @return AdapterExecutionResult result Complete execution result
-function M.execute_adapter_direct(position_id, opts)
+--- @return neotest.Tree tree The tree of the position
+--- @return neotest.Tree full_tree The tree the position was discovered in
+--- @return neotest.RunSpec run_spec The built run specification
+local function build_execution(position_id)
   -- Validate arguments
   assert(position_id, "position_id is required")
   assert(type(position_id) == "string", "position_id must be a string")
 
-  -- Extract options with defaults
-  opts = opts or {}
-  local use_blocking = opts.use_blocking or false
-
   -- Parse position ID to extract components
   -- Handle Windows drive letters (C:, D:, etc.) by looking for :: test separators specifically
   local base_path, test_components
@@ -251,11 +252,6 @@ function M.execute_adapter_direct(position_id, opts)
   local nio = require("nio")
   local adapter = require("neotest-golang")
 
-  -- Set up test stream strategy for integration tests
-  local lib_stream = require("neotest-golang.lib.stream")
-  local test_strategy = require("neotest-golang.lib.stream_strategy.test")
-  lib_stream.set_test_strategy(test_strategy)
-
   local tree, full_tree
 
   if inferred_type == "file" then
@@ -365,6 +361,37 @@ function M.execute_adapter_direct(position_id, opts)
   assert(run_spec, "Failed to build run spec for " .. position_id)
   assert(run_spec.command, "Run spec should have a command")
 
+  return tree, full_tree, run_spec
+end
+
+--- Execute a real test using the adapter's build_spec and results methods directly
+--- This bypasses neotest.run.run and calls the adapter interface directly
+---
+--- Position ID Format Examples:
+--- "/path/to/directory"                                    -- Directory (all tests)
+--- "/path/to/file_test.go"                                 -- File (all tests in file)
+--- "/path/to/file_test.go::TestFunction"                   -- Specific test
+--- "/path/to/file_test.go::TestFunction::\"SubTest\""        -- Subtest
+--- "/path/to/file_test.go::TestFunction::\"SubTest\"::\"TableTest\"" -- Nested subtest
+---
+--- @param position_id string Neotest position ID (directory, file, or test position)
+--- @param opts ExecutionOpts? Optional execution configuration
+--- @return AdapterExecutionResult result Complete execution result
+function M.execute_adapter_direct(position_id, opts)
+  -- Extract options with defaults
+  opts = opts or {}
+  local use_blocking = opts.use_blocking or false
+
+  local nio = require("nio")
+  local adapter = require("neotest-golang")
+
+  -- Set up test stream strategy for integration tests
+  local lib_stream = require("neotest-golang.lib.stream")
+  local test_strategy = require("neotest-golang.lib.stream_strategy.test")
+  lib_stream.set_test_strategy(test_strategy)
+
+  local _, full_tree, run_spec = build_execution(position_id)
+
   -- Execute the command (streaming by default, sync if blocking is requested)
   local strategy_result
 
@@ -413,7 +440,7 @@ end
 --- @param tree neotest.Tree The discovered test tree
 --- @param golist_data table The 'go list -json' output
 --- @param output_path string Path to the test output file
---- @param context table|nil The run spec context (contains gotestsum JSON file path)
+--- @param context table The run spec context (contains gotestsum JSON file path)
 --- @return table<string, neotest.Result> Individual test results
 function M.process_test_output_manually(tree, golist_data, output_path, context)
   local options = require("neotest-golang.options")
@@ -453,26 +480,32 @@ function M.process_test_output_manually(tree, golist_data, output_path, context)
   -- Build position lookup table
   local position_lookup = lib.mapping.build_position_lookup(tree, golist_data)
 
-  -- Process events using the same logic as streaming
-  local accum = {}
+  -- Collect the results in the state of the run, like its stream does
+  local state = context.state
+  return lib.run_state.with(state, function()
+    -- Process events using the same logic as streaming
+    local accum = {}
+
+    for _, gotest_event in ipairs(gotest_output) do
+      accum = results_stream.process_event(
+        golist_data,
+        accum,
+        gotest_event,
+        position_lookup
+      )
+    end
 
-  for _, gotest_event in ipairs(gotest_output) do
-    accum = results_stream.process_event(
-      golist_data,
+    -- Convert to stream results using optimized direct cache population
+    results_stream.make_stream_results_with_cache(
       accum,
-      gotest_event,
-      position_lookup
+      lib.stream.cached_results(state),
+      golist_data,
+      lib.golden.positions(tree)
     )
-  end
 
-  -- Convert to stream results using optimized direct cache population
-  results_stream.make_stream_results_with_cache(
-    accum,
-    lib.stream.cached_results
-  )
-
-  -- Return a reference to the updated cache
-  return lib.stream.cached_results
+    -- Return a reference to the updated cache
+    return lib.stream.cached_results(state)
+  end)
 end
 
 --- Validate diagnostic errors for specific test positions
@@ -561,6 +594,10 @@ function M.validate_diagnostic_errors(results, position_id, expected_errors)
 end
 
 --- Execute multiple tests concurrently with streaming
+---
+--- All run specs are built before any command runs, and the commands, their
+--- streams and their results then overlap, the way runs do which are started
+--- while other runs are still running.
 --- @param position_ids string[] List of position IDs to execute concurrently
 --- @param use_blocking boolean? Whether to use blocking execution instead of streaming (default: false)
 --- @return table<string, AdapterExecutionResult> results Map of position_id to execution result
@@ -573,9 +610,21 @@ function M.execute_adapter_concurrent(position_ids, use_blocking)
   use_blocking = use_blocking or false
 
   local nio = require("nio")
+  local adapter = require("neotest-golang")
 
-  return nio.tests.with_async_context(function()
-    local futures = {}
+  -- Set up test stream strategy for integration tests
+  local lib_stream = require("neotest-golang.lib.stream")
+  local test_strategy = require("neotest-golang.lib.stream_strategy.test")
+  lib_stream.set_test_strategy(test_strategy)
+
+  -- Build all run specs up front
+  local executions = {}
+  for _, position_id in ipairs(position_ids) do
+    local _, full_tree, run_spec = build_execution(position_id)
+    executions[position_id] = { full_tree = full_tree, run_spec = run_spec }
+  end
+
+  local results = nio.tests.with_async_context(function()
     local results = {}
 
     print(
@@ -587,51 +636,58 @@ function M.execute_adapter_concurrent(position_ids, use_blocking)
     local start_time = vim.fn.reltime()
 
     -- Launch all tests concurrently
-    for i, position_id in ipairs(position_ids) do
-      local future = nio.control.future()
-      futures[position_id] = future
-
-      -- Launch each execution in parallel
-      nio.run(function()
-        local success, result = pcall(
-          M.execute_adapter_direct,
-          position_id,
-          { use_blocking = use_blocking }
-        )
+    local tasks = {}
+    for _, position_id in ipairs(position_ids) do
+      local execution = executions[position_id]
+      table.insert(tasks, function()
+        local success, result = pcall(function()
+          local strategy_result
+          if use_blocking then
+            strategy_result = run_command(execution.run_spec)
+          else
+            strategy_result = run_command_streaming(execution.run_spec)
+          end
+
+          if strategy_result.output then
+            M.process_test_output_manually(
+              execution.full_tree,
+              execution.run_spec.context.golist_data,
+              strategy_result.output,
+              execution.run_spec.context
+            )
+          end
+
+          ---@type AdapterExecutionResult
+          return {
+            tree = execution.full_tree,
+            results = adapter.results(
+              execution.run_spec,
+              strategy_result,
+              execution.full_tree
+            ),
+            run_spec = execution.run_spec,
+            strategy_result = strategy_result,
+          }
+        end)
+
         if success then
-          future.set({ success = true, result = result })
+          results[position_id] = result
+          print(string.format("[CONCURRENT] ✅ Completed: %s", position_id))
         else
-          future.set({ success = false, error = result })
+          print(
+            string.format(
+              "[CONCURRENT] ❌ Failed: %s - %s",
+              position_id,
+              result
+            )
+          )
+          results[position_id] = { error = result }
         end
       end)
-
-      print(
-        string.format(
-          "[CONCURRENT] Launched test %d/%d: %s",
-          i,
-          #position_ids,
-          position_id
-        )
-      )
     end
 
-    -- Collect all results
-    for position_id, future in pairs(futures) do
-      local outcome = future.wait()
-      if outcome.success then
-        results[position_id] = outcome.result
-        print(string.format("[CONCURRENT] ✅ Completed: %s", position_id))
-      else
-        print(
-          string.format(
-            "[CONCURRENT] ❌ Failed: %s - %s",
-            position_id,
-            outcome.error
-          )
-        )
-        results[position_id] = { error = outcome.error }
-      end
-    end
+    -- Wait for all tests to complete
+    nio.gather(tasks)
 
     local elapsed_time = vim.fn.reltimestr(vim.fn.reltime(start_time))
     print(
@@ -644,6 +700,11 @@ function M.execute_adapter_concurrent(position_ids, use_blocking)
 
     return results
   end)
+
+  -- Reset test strategy to avoid state leakage between tests
+  lib_stream.set_test_strategy(nil)
+
+  return results
 end
 
 return M
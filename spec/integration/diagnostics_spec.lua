@@ -62,9 +62,30 @@ describe("Integration: diagnostics test", function()
                 severity = 4,
               },
               {
-                message = "assertion failed: ",
+                message = table.concat({
+                  "assertion failed: ",
+                  "--- ←",
+                  "+++ →",
+                  "  int(",
+                  "- \t1,",
+                  "+ \t2,",
+                  "  )",
+                }, "\n"),
                 line = 40,
                 severity = 1,
+                comparison = {
+                  expected = " int(\n \t2,\n )",
+                  actual = " int(\n \t1,\n )",
+                  diff = {
+                    "--- ←",
+                    "+++ →",
+                    "  int(",
+                    "- \t1,",
+                    "+ \t2,",
+                    "  )",
+                  },
+                  removed = "actual",
+                },
               },
             },
           },
@@ -141,9 +162,30 @@ describe("Integration: diagnostics test", function()
             status = "failed",
             errors = {
               {
-                message = "assertion failed: ",
+                message = table.concat({
+                  "assertion failed: ",
+                  "--- ←",
+                  "+++ →",
+                  "  int(",
+                  "- \t1,",
+                  "+ \t2,",
+                  "  )",
+                }, "\n"),
                 line = 40,
                 severity = 1,
+                comparison = {
+                  expected = " int(\n \t2,\n )",
+                  actual = " int(\n \t1,\n )",
+                  diff = {
+                    "--- ←",
+                    "+++ →",
+                    "  int(",
+                    "- \t1,",
+                    "+ \t2,",
+                    "  )",
+                  },
+                  removed = "actual",
+                },
               },
             },
           },
@@ -179,6 +221,7 @@ describe("Integration: diagnostics test", function()
       want.run_spec.stream = got.run_spec.stream
       want.run_spec.strategy = got.run_spec.strategy
       want.run_spec.context.golist_data = got.run_spec.context.golist_data
+      want.run_spec.context.state = got.run_spec.context.state
       want.run_spec.context.stop_filestream =
         got.run_spec.context.stop_filestream
       want.run_spec.context.test_output_json_filepath =
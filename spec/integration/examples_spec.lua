@@ -31,7 +31,30 @@ describe("Integration: Example functions", function()
           -- File-level result - should fail due to failing examples
           [position_id] = {
             status = "failed",
-            errors = {},
+            errors = {
+              {
+                message = "output mismatch (-want +got):\n-5\n+6",
+                line = 32,
+                severity = 1,
+                comparison = {
+                  expected = "5",
+                  actual = "6",
+                  diff = { "-5", "+6" },
+                  removed = "expected",
+                },
+              },
+              {
+                message = "output mismatch (-want +got):\n-100\n+15",
+                line = 55,
+                severity = 1,
+                comparison = {
+                  expected = "100",
+                  actual = "15",
+                  diff = { "-100", "+15" },
+                  removed = "expected",
+                },
+              },
+            },
           },
           -- Regular test result
           [position_id .. "::TestAdd"] = {
@@ -51,7 +74,19 @@ describe("Integration: Example functions", function()
           -- Example function that fails (incorrect output comment)
           [position_id .. "::ExampleAdd_failing"] = {
             status = "failed",
-            errors = {},
+            errors = {
+              {
+                message = "output mismatch (-want +got):\n-5\n+6",
+                line = 32,
+                severity = 1,
+                comparison = {
+                  expected = "5",
+                  actual = "6",
+                  diff = { "-5", "+6" },
+                  removed = "expected",
+                },
+              },
+            },
           },
           -- Example for Multiply (should pass)
           [position_id .. "::ExampleMultiply"] = {
@@ -66,7 +101,24 @@ describe("Integration: Example functions", function()
           -- Example with method-style naming (should fail)
           [position_id .. "::ExampleCalculator_Add"] = {
             status = "failed",
-            errors = {},
+            errors = {
+              {
+                message = "output mismatch (-want +got):\n-100\n+15",
+                line = 55,
+                severity = 1,
+                comparison = {
+                  expected = "100",
+                  actual = "15",
+                  diff = { "-100", "+15" },
+                  removed = "expected",
+                },
+              },
+            },
+          },
+          -- Example without output comment (compiled, but never run)
+          [position_id .. "::ExampleCalculator"] = {
+            status = "skipped",
+            short = "not run: Example function has no output comment",
           },
         },
         run_spec = {
@@ -100,6 +152,7 @@ describe("Integration: Example functions", function()
       want.run_spec.stream = got.run_spec.stream
       want.run_spec.strategy = got.run_spec.strategy
       want.run_spec.context.golist_data = got.run_spec.context.golist_data
+      want.run_spec.context.state = got.run_spec.context.state
       want.run_spec.context.stop_filestream =
         got.run_spec.context.stop_filestream
       want.run_spec.context.test_output_json_filepath =
@@ -185,12 +238,36 @@ describe("Integration: Example functions", function()
         -- File-level result
         [file_id] = {
           status = "failed",
-          errors = {},
+          errors = {
+            {
+              message = "output mismatch (-want +got):\n-5\n+6",
+              line = 32,
+              severity = 1,
+              comparison = {
+                expected = "5",
+                actual = "6",
+                diff = { "-5", "+6" },
+                removed = "expected",
+              },
+            },
+          },
         },
         -- Individual failing example
         [position_id] = {
           status = "failed",
-          errors = {},
+          errors = {
+            {
+              message = "output mismatch (-want +got):\n-5\n+6",
+              line = 32,
+              severity = 1,
+              comparison = {
+                expected = "5",
+                actual = "6",
+                diff = { "-5", "+6" },
+                removed = "expected",
+              },
+            },
+          },
         },
       },
       run_spec = {
@@ -223,6 +300,7 @@ describe("Integration: Example functions", function()
     want.run_spec.stream = got.run_spec.stream
     want.run_spec.strategy = got.run_spec.strategy
     want.run_spec.context.golist_data = got.run_spec.context.golist_data
+    want.run_spec.context.state = got.run_spec.context.state
     want.run_spec.context.stop_filestream = got.run_spec.context.stop_filestream
     want.run_spec.context.test_output_json_filepath =
       got.run_spec.context.test_output_json_filepath
@@ -313,6 +391,7 @@ describe("Integration: Example functions", function()
     want.run_spec.stream = got.run_spec.stream
     want.run_spec.strategy = got.run_spec.strategy
     want.run_spec.context.golist_data = got.run_spec.context.golist_data
+    want.run_spec.context.state = got.run_spec.context.state
     want.run_spec.context.stop_filestream = got.run_spec.context.stop_filestream
     want.run_spec.context.test_output_json_filepath =
       got.run_spec.context.test_output_json_filepath
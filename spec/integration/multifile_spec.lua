@@ -81,6 +81,7 @@ describe("Integration: multifile test", function()
       want.run_spec.stream = got.run_spec.stream
       want.run_spec.strategy = got.run_spec.strategy
       want.run_spec.context.golist_data = got.run_spec.context.golist_data
+      want.run_spec.context.state = got.run_spec.context.state
       want.run_spec.context.stop_filestream =
         got.run_spec.context.stop_filestream
       want.run_spec.context.test_output_json_filepath =
@@ -100,4 +101,88 @@ describe("Integration: multifile test", function()
       assert.are.same(vim.inspect(want), vim.inspect(got))
     end
   )
+
+  it("overlapping file runs only report their own tests", function()
+    -- ===== ARRANGE =====
+    local test_options = options.get()
+    test_options.runner = "gotestsum"
+    options.set(test_options)
+
+    local pos_id_dir =
+      path.normalize_path(vim.uv.cwd() .. "/tests/go/internal/multifile")
+    local pos_id_first =
+      path.normalize_path(pos_id_dir .. "/first_file_test.go")
+    local pos_id_second =
+      path.normalize_path(pos_id_dir .. "/second_file_test.go")
+
+    -- ===== ACT =====
+    local got =
+      integration.execute_adapter_concurrent({ pos_id_first, pos_id_second })
+
+    -- ===== ASSERT =====
+    local first = got[pos_id_first]
+    local second = got[pos_id_second]
+    assert.is_nil(first.error)
+    assert.is_nil(second.error)
+    assert.are_not.equal(
+      first.run_spec.context.state,
+      second.run_spec.context.state
+    )
+
+    assert.are.equal("passed", first.results[pos_id_first].status)
+    assert.are.equal(
+      "passed",
+      first.results[pos_id_first .. "::TestOne"].status
+    )
+    assert.is_nil(first.results[pos_id_second])
+    assert.is_nil(first.results[pos_id_second .. "::TestTwo"])
+
+    assert.are.equal("passed", second.results[pos_id_second].status)
+    assert.are.equal(
+      "passed",
+      second.results[pos_id_second .. "::TestTwo"].status
+    )
+    assert.is_nil(second.results[pos_id_first])
+    assert.is_nil(second.results[pos_id_first .. "::TestOne"])
+  end)
+
+  it("overlapping file runs keep each other's diagnostics", function()
+    -- ===== ARRANGE =====
+    local test_options = options.get()
+    test_options.runner = "gotestsum"
+    options.set(test_options)
+
+    local pos_id_dir =
+      path.normalize_path(vim.uv.cwd() .. "/tests/go/internal/helpers")
+    local pos_id_first = path.normalize_path(pos_id_dir .. "/first_test.go")
+    local pos_id_second = path.normalize_path(pos_id_dir .. "/second_test.go")
+    local helpers_file = path.normalize_path(pos_id_dir .. "/helpers_test.go")
+
+    -- ===== ACT =====
+    local got =
+      integration.execute_adapter_concurrent({ pos_id_first, pos_id_second })
+
+    -- ===== ASSERT =====
+    assert.is_nil(got[pos_id_first].error)
+    assert.is_nil(got[pos_id_second].error)
+
+    -- Both runs report a failure in the helper's file, under their own
+    -- namespace, which is published once their results are collected
+    local bufnr = vim.fn.bufadd(helpers_file)
+    local function messages()
+      local found = {}
+      for _, diagnostic in ipairs(vim.diagnostic.get(bufnr)) do
+        found[diagnostic.message] = true
+      end
+      return found
+    end
+    vim.wait(1000, function()
+      return vim.tbl_count(messages()) == 2
+    end)
+
+    assert.are.same({
+      ["check failed: first"] = true,
+      ["check failed: second"] = true,
+    }, messages())
+  end)
 end)
@@ -61,6 +61,7 @@ describe("Integration: nested subpackage2 test", function()
       want.run_spec.stream = got.run_spec.stream
       want.run_spec.strategy = got.run_spec.strategy
       want.run_spec.context.golist_data = got.run_spec.context.golist_data
+      want.run_spec.context.state = got.run_spec.context.state
       want.run_spec.context.stop_filestream =
         got.run_spec.context.stop_filestream
       want.run_spec.context.test_output_json_filepath =
@@ -144,6 +145,7 @@ describe("Integration: nested subpackage2 test", function()
       want.run_spec.stream = got.run_spec.stream
       want.run_spec.strategy = got.run_spec.strategy
       want.run_spec.context.golist_data = got.run_spec.context.golist_data
+      want.run_spec.context.state = got.run_spec.context.state
       want.run_spec.context.stop_filestream =
         got.run_spec.context.stop_filestream
       want.run_spec.context.test_output_json_filepath =
@@ -226,6 +228,7 @@ describe("Integration: nested subpackage2 test", function()
       want.run_spec.stream = got.run_spec.stream
       want.run_spec.strategy = got.run_spec.strategy
       want.run_spec.context.golist_data = got.run_spec.context.golist_data
+      want.run_spec.context.state = got.run_spec.context.state
       want.run_spec.context.stop_filestream =
         got.run_spec.context.stop_filestream
       want.run_spec.context.test_output_json_filepath =
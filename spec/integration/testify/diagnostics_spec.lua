@@ -31,7 +31,12 @@ describe("Integration: testify suites diagnostics", function()
       }
 
       local test_consecutive_failures_errors = {
-        { line = 24, message = "Not equal:", severity = 1 },
+        {
+          line = 24,
+          message = "Not equal:",
+          severity = 1,
+          comparison = { expected = "1", actual = "2" },
+        },
         { line = 26, message = "Should be true", severity = 1 },
         {
           line = 28,
@@ -46,6 +51,17 @@ describe("Integration: testify suites diagnostics", function()
           line = 36,
           message = "Not equal:: values should match",
           severity = 1,
+          comparison = {
+            expected = '"expected"',
+            actual = '"actual"',
+            diff = {
+              "--- Expected",
+              "+++ Actual",
+              "@@ -1 +1 @@",
+              "-expected",
+              "+actual",
+            },
+          },
         },
         { line = 38, message = "manual error in between", severity = 4 },
         {
@@ -128,6 +144,7 @@ describe("Integration: testify suites diagnostics", function()
       want.run_spec.stream = got_copy.run_spec.stream
       want.run_spec.strategy = got_copy.run_spec.strategy
       want.run_spec.context.golist_data = got_copy.run_spec.context.golist_data
+      want.run_spec.context.state = got_copy.run_spec.context.state
       want.run_spec.context.stop_filestream =
         got_copy.run_spec.context.stop_filestream
       want.run_spec.context.test_output_json_filepath =
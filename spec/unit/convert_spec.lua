@@ -230,6 +230,37 @@ describe("Convert go test name to regex pattern", function()
   end)
 end)
 
+describe("Rewrite subtest names like go test", function()
+  it("replaces each space with an underscore", function()
+    assert.are_equal(
+      "two__spaces_and_a_tab",
+      lib.convert.rewrite_subtest_name("two  spaces and\ta tab")
+    )
+    assert.are_equal(
+      "no-break_space",
+      lib.convert.rewrite_subtest_name("no-break\194\160space")
+    )
+  end)
+
+  it("escapes non-printable characters", function()
+    assert.are_equal(
+      "bell\\a_null\\x00",
+      lib.convert.rewrite_subtest_name("bell\a null\0")
+    )
+  end)
+end)
+
+describe("Convert go test name to exact -test.run pattern", function()
+  it("escapes all regexp characters and anchors each level", function()
+    assert.are_equal(
+      "^TestNames$/^Period_\\._50%_\\(2\\)_\\[1\\]_a\\|b$",
+      lib.convert.to_exact_test_run_pattern(
+        "TestNames/Period_._50%_(2)_[1]_a|b"
+      )
+    )
+  end)
+end)
+
 describe("file_path_to_import_path", function()
   it("finds matching import path", function()
     local file_path = "/path/to/pkg/subdir/file_test.go"
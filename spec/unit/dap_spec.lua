@@ -0,0 +1,195 @@
+local _ = require("plenary")
+local dap = require("neotest-golang.features.dap")
+local run_state = require("neotest-golang.lib.run_state")
+
+describe("DAP -test.run pattern", function()
+  local test_path = vim.uv.cwd() .. "/tests/go/internal/specialchars"
+  local unbind
+
+  before_each(function()
+    unbind = run_state.bind(run_state.new())
+  end)
+
+  after_each(function()
+    unbind()
+  end)
+
+  it("matches a subtest exactly after listing its top-level test", function()
+    assert.are_equal(
+      "^TestNames$/^Brackets_\\[1\\]_\\(2\\)_\\{3\\}_are_ok$",
+      dap.test_run_pattern(test_path, "TestNames/Brackets_[1]_(2)_{3}_are_ok")
+    )
+  end)
+
+  it("returns nil when no test matches", function()
+    assert.is_nil(dap.test_run_pattern(test_path, "TestMissing/subtest"))
+  end)
+
+  it("lists the tests in the environment of the run", function()
+    local options = require("neotest-golang.options")
+    options.set({ env = { GOFLAGS = "-mod=invalid" } })
+    local tests, err = dap.list_tests(test_path, "^TestNames$")
+    options.set({ env = {} })
+
+    assert.is_nil(tests)
+    assert.is_truthy(err:find("-mod", 1, true))
+  end)
+end)
+
+describe("DAP go test arguments", function()
+  local go_test_args = require("neotest-golang.features.dap.go_test_args")
+  local options = require("neotest-golang.options")
+  local unbind
+
+  before_each(function()
+    unbind = run_state.bind(run_state.new())
+  end)
+
+  after_each(function()
+    unbind()
+    options.set({ go_test_args = { "-v", "-race", "-count=1" }, env = {} })
+  end)
+
+  it("splits go test args into build flags and binary args", function()
+    assert.are.same(
+      {
+        build_flags = { "-tags=integration", "-gcflags=all=-N -l" },
+        args = { "-test.v", "-test.timeout=30s", "-update", "-golden" },
+      },
+      go_test_args.translate({
+        "-v",
+        "-race",
+        "-json",
+        "-tags",
+        "integration",
+        "-timeout=30s",
+        "-gcflags=all=-N -l",
+        "-update",
+        "-args",
+        "-golden",
+      })
+    )
+  end)
+
+  it("drops -list, which would keep the debugged test from running", function()
+    assert.are.same(
+      { build_flags = {}, args = { "-test.v" } },
+      go_test_args.translate({ "-v", "-list", "^Test" })
+    )
+  end)
+
+  it("builds with -cover when a coverage profile is written", function()
+    assert.are.same(
+      {
+        build_flags = { "-cover" },
+        args = { "-test.coverprofile=cover.out" },
+      },
+      go_test_args.translate({ "-coverprofile=cover.out" })
+    )
+    assert.are.same(
+      {
+        build_flags = { "-cover" },
+        args = { "-test.coverprofile=cover.out" },
+      },
+      go_test_args.translate({ "-cover", "-coverprofile=cover.out" })
+    )
+  end)
+
+  it("carries the configured arguments and env into the DAP config", function()
+    require("neotest-golang.lib.goenv").set_version_for_testing("go1.22.0")
+    options.set({
+      go_test_args = { "-v", "-race", "-tags=integration" },
+      env = { DATABASE_URL = "postgres://localhost" },
+    })
+
+    assert.are.same({
+      program = "/repo/pkg",
+      args = { "-test.run", "^TestQuery$", "-test.v", "-test.fullpath" },
+      buildFlags = "-trimpath -tags=integration",
+      env = { DATABASE_URL = "postgres://localhost" },
+    }, go_test_args.apply({
+      program = "/repo/pkg",
+      args = { "-test.run", "^TestQuery$" },
+      buildFlags = "-trimpath",
+    }))
+  end)
+end)
+
+describe("DAP built-in delve adapter", function()
+  local dap_dlv = require("neotest-golang.features.dap.dap_dlv")
+  local options = require("neotest-golang.options")
+
+  after_each(function()
+    options.set({ dap_dlv_opts = {} })
+  end)
+
+  it("starts dlv dap on a free port in the package directory", function()
+    options.set({ dap_dlv_opts = { path = "/go/bin/dlv", detached = false } })
+
+    assert.are.same({
+      type = "server",
+      port = "${port}",
+      executable = {
+        command = "/go/bin/dlv",
+        args = { "dap", "-l", "127.0.0.1:${port}" },
+        cwd = "/repo/pkg",
+        detached = false,
+      },
+      options = { initialize_timeout_sec = 20 },
+    }, dap_dlv.adapter("/repo/pkg"))
+  end)
+
+  it("registers one adapter which starts delve in the config's cwd", function()
+    local adapters = {}
+    package.loaded["dap"] = { adapters = adapters }
+    options.set({ dap_dlv_opts = { detached = false } })
+
+    dap_dlv.setup_debugging("/repo/a")
+    local adapter = adapters[dap_dlv.adapter_name]
+    dap_dlv.setup_debugging("/repo/b")
+    package.loaded["dap"] = nil
+
+    assert.are.equal(adapter, adapters[dap_dlv.adapter_name])
+    local resolved = {}
+    for _, cwd in ipairs({ "/repo/a", "/repo/b" }) do
+      adapter(function(server)
+        table.insert(resolved, server.executable.cwd)
+      end, dap_dlv.get_dap_config(cwd))
+    end
+    assert.are.same({ "/repo/a", "/repo/b" }, resolved)
+  end)
+
+  it("launches the test with build flags and substitute paths", function()
+    local substitute_path = { { from = "/repo", to = "/app" } }
+    options.set({
+      dap_dlv_opts = {
+        build_flags = "-trimpath",
+        substitute_path = substitute_path,
+      },
+    })
+
+    assert.are.same({
+      type = dap_dlv.adapter_name,
+      name = "Neotest-golang",
+      request = "launch",
+      mode = "test",
+      program = "/repo/pkg",
+      cwd = "/repo/pkg",
+      outputMode = "remote",
+      args = { "-test.run", "^TestQuery$" },
+      buildFlags = "-trimpath",
+      substitutePath = substitute_path,
+    }, dap_dlv.get_dap_config("/repo/pkg", "^TestQuery$"))
+  end)
+
+  it("attaches to a running test binary", function()
+    assert.are.same({
+      type = dap_dlv.adapter_name,
+      name = "Neotest-golang attach",
+      request = "attach",
+      mode = "local",
+      processId = 4242,
+      cwd = "/repo/pkg",
+    }, dap_dlv.get_attach_config("/repo/pkg", 4242))
+  end)
+end)
@@ -0,0 +1,191 @@
+local _ = require("plenary")
+local diagnostic_parsers = require("neotest-golang.lib.diagnostic_parsers")
+local diagnostics = require("neotest-golang.lib.diagnostics")
+local run_state = require("neotest-golang.lib.run_state")
+local options = require("neotest-golang.options")
+
+local test_file = "/repo/pkg/service_test.go"
+
+--- Process the output of a test in the test file.
+--- @param output_parts string[]
+--- @return neotest.Error[]
+local function process(output_parts)
+  return diagnostics.process_diagnostics({
+    metadata = {
+      position_id = test_file .. "::TestService",
+      output_parts = output_parts,
+    },
+  })
+end
+
+describe("diagnostic parsers", function()
+  local default_parsers = vim.deepcopy(options.get().diagnostic_parsers)
+  local unbind
+
+  before_each(function()
+    options.set({ diagnostic_parsers = vim.deepcopy(default_parsers) })
+    diagnostic_parsers.clear_registered()
+    unbind = run_state.bind(run_state.new())
+  end)
+
+  after_each(function()
+    unbind()
+  end)
+
+  it("runs custom parsers with their own context", function()
+    local mycheck = {
+      name = "mycheck",
+      parse = function(line, context)
+        if line:match("^CHECK") then
+          context.count = (context.count or 0) + 1
+          return {
+            filename = "service_test.go",
+            line_number = 10 + context.count,
+            message = "check " .. context.count,
+            severity = vim.diagnostic.severity.ERROR,
+          }
+        end
+      end,
+    }
+    options.set({ diagnostic_parsers = { mycheck } })
+
+    assert.are.same({
+      { line = 10, message = "check 1", severity = 1 },
+      { line = 11, message = "check 2", severity = 1 },
+    }, process({ "CHECK\n", "CHECK\n" }))
+  end)
+
+  it("keeps going when a parser fails", function()
+    diagnostic_parsers.register({
+      name = "broken",
+      parse = function()
+        error("boom")
+      end,
+    })
+
+    assert.are.same({
+      { line = 4, message = "panic: oops", severity = 1 },
+    }, process({ "    service_test.go:5: panic: oops\n" }))
+  end)
+
+  it("resolves the parsers once for the output of a test", function()
+    local get = diagnostic_parsers.get
+    local calls = 0
+    diagnostic_parsers.get = function()
+      calls = calls + 1
+      return get()
+    end
+
+    local ok, result = pcall(process, {
+      "    service_test.go:5: first\n",
+      "    service_test.go:6: second\n",
+      "    service_test.go:7: third\n",
+    })
+    diagnostic_parsers.get = get
+
+    assert.is_true(ok, tostring(result))
+    assert.are.equal(3, #result)
+    assert.are.equal(1, calls)
+  end)
+
+  it("refines gotest.tools and matryer/is failures", function()
+    assert.are.same({
+      {
+        line = 11,
+        message = "assertion failed: 1 (got int) != 2 (want int)",
+        severity = 1,
+        comparison = { expected = "2", actual = "1" },
+      },
+      {
+        line = 12,
+        message = "// the answer: 41 != 42",
+        severity = 1,
+        comparison = { expected = "42", actual = "41" },
+      },
+      { line = 13, message = "not true: ok", severity = 1 },
+      { line = 14, message = "still a hint", severity = 4 },
+    }, process({
+      "    service_test.go:12: assertion failed: 1 (got int) != 2 (want int)\n",
+      "    service_test.go:13: // the answer: 41 != 42\n",
+      "    service_test.go:14: not true: ok\n",
+      "    service_test.go:15: still a hint\n",
+    }))
+  end)
+
+  it("parses quicktest reports", function()
+    assert.are.same({
+      {
+        line = 11,
+        message = "values are not equal: the answer",
+        severity = 1,
+        comparison = { expected = "int(42)", actual = "int(41)" },
+      },
+    }, process({
+      "    service_test.go:12: \n",
+      "        error:\n",
+      "          values are not equal\n",
+      "        comment:\n",
+      "          the answer\n",
+      "        got:\n",
+      "          int(41)\n",
+      "        want:\n",
+      "          int(42)\n",
+      "        stack:\n",
+      "          " .. test_file .. ":12\n",
+      "            qt.Assert(t, got, qt.Equals, 42)\n",
+      "        \n",
+      "--- FAIL: TestService (0.00s)\n",
+    }))
+  end)
+
+  it("parses gomega reports", function()
+    assert.are.same({
+      {
+        line = 14,
+        message = "Expected\n    <int>: 41\nto equal\n    <int>: 42",
+        severity = 1,
+        comparison = { expected = "<int>: 42", actual = "<int>: 41" },
+      },
+    }, process({
+      "    service_test.go:15: \n",
+      "        Expected\n",
+      "            <int>: 41\n",
+      "        to equal\n",
+      "            <int>: 42\n",
+    }))
+  end)
+
+  it("places gomock failures at the location in the message", function()
+    local errors = process({
+      "    controller.go:137: Unexpected call to *mocks.MockStore.Get([2]) at "
+        .. test_file
+        .. ":30 because: \n",
+      "        expected call at "
+        .. test_file
+        .. ":25 doesn't match the argument at index 0.\n",
+      "        Got: 2 (int)\n",
+      "    controller.go:269: missing call(s) to "
+        .. "*mocks.MockStore.Put(is anything) "
+        .. test_file
+        .. ":26\n",
+      "    controller.go:269: aborting test due to missing call(s)\n",
+    })
+
+    assert.are.same({
+      {
+        line = 29,
+        message = "Unexpected call to *mocks.MockStore.Get([2]) because: \n"
+          .. "expected call at "
+          .. test_file
+          .. ":25 doesn't match the argument at index 0.\n"
+          .. "Got: 2 (int)",
+        severity = 1,
+      },
+      {
+        line = 25,
+        message = "missing call(s) to *mocks.MockStore.Put(is anything)",
+        severity = 1,
+      },
+    }, errors)
+  end)
+end)
@@ -238,6 +238,16 @@ describe("integration: stream classification", function()
 end)
 
 describe("process_diagnostics", function()
+  local unbind
+
+  before_each(function()
+    unbind = lib.run_state.bind(lib.run_state.new())
+  end)
+
+  after_each(function()
+    unbind()
+  end)
+
   it("filters diagnostics by test filename", function()
     local test_entry = {
       metadata = {
@@ -323,6 +333,143 @@ describe("process_diagnostics", function()
     end
   )
 
+  it("merges indented continuation lines into the message", function()
+    local test_entry = {
+      metadata = {
+        position_id = "/abs/path/my_test.go::TestMultiLine",
+        output_parts = {
+          "    my_test.go:10: expected 1\n",
+          "        got 2\n",
+          "        \n",
+          "    my_test.go:12: second\n",
+          "--- FAIL: TestMultiLine (0.00s)\n",
+        },
+      },
+    }
+
+    local errs = lib.diagnostics.process_diagnostics(test_entry)
+
+    assert.equals(2, #errs)
+    assert.equals("expected 1\ngot 2", errs[1].message)
+    assert.equals(9, errs[1].line)
+    assert.equals(vim.diagnostic.severity.ERROR, errs[1].severity)
+    assert.equals("second", errs[2].message)
+    assert.equals(11, errs[2].line)
+  end)
+
+  it("keeps the last multi-line message when the output ends", function()
+    local test_entry = {
+      metadata = {
+        position_id = "/abs/path/my_test.go::TestFatal",
+        output_parts = {
+          "    my_test.go:20: could not connect:\n        timeout\n",
+        },
+      },
+    }
+
+    local errs = lib.diagnostics.process_diagnostics(test_entry)
+
+    assert.equals(1, #errs)
+    assert.equals("could not connect:\ntimeout", errs[1].message)
+  end)
+
+  it("matches full paths printed with -fullpath by absolute path", function()
+    local test_entry = {
+      metadata = {
+        position_id = "/abs/path/handler_test.go::TestHandler",
+        output_parts = {
+          "    /abs/path/handler_test.go:10: panic: boom\n",
+          "    /abs/other/handler_test.go:11: other package\n",
+        },
+      },
+    }
+
+    local errs = lib.diagnostics.process_diagnostics(test_entry)
+
+    assert.equals(1, #errs)
+    assert.equals(9, errs[1].line)
+    assert.equals("panic: boom", errs[1].message)
+  end)
+
+  describe("classification rules", function()
+    local options = require("neotest-golang.options")
+
+    before_each(function()
+      options.set({ diagnostic_rules = {}, show_log_hints = true })
+    end)
+
+    after_each(function()
+      options.set({ diagnostic_rules = {}, show_log_hints = true })
+    end)
+
+    local golist_data = {
+      { ImportPath = "example.com/repo/integration", Dir = "/abs/integration" },
+    }
+
+    local function process()
+      return lib.diagnostics.process_diagnostics({
+        metadata = {
+          position_id = "/abs/integration/db_test.go::TestDB",
+          output_parts = {
+            "    db_test.go:10: retrying after error: timeout\n",
+            "    db_test.go:11: connected\n",
+            "    db_test.go:12: DEPRECATED: old schema\n",
+          },
+        },
+      }, golist_data)
+    end
+
+    it("classifies messages by the first matching rule in scope", function()
+      options.set({
+        diagnostic_rules = {
+          { pattern = "error:", severity = "hint", files = "**/unit/**" },
+          { pattern = "^retrying", severity = "info" },
+          { pattern = "error:", severity = "hint" },
+          {
+            pattern = "^DEPRECATED",
+            severity = vim.diagnostic.severity.WARN,
+            packages = { "example.com/repo/integration" },
+          },
+        },
+      })
+
+      local errs = process()
+      assert.equals(vim.diagnostic.severity.INFO, errs[1].severity)
+      assert.equals(vim.diagnostic.severity.HINT, errs[2].severity)
+      assert.equals(vim.diagnostic.severity.WARN, errs[3].severity)
+    end)
+
+    it("matches rules as very magic regexes", function()
+      options.set({
+        diagnostic_rules = {
+          { pattern = "^(retrying|connected)", severity = "info" },
+          { pattern = "deprecated", severity = "warn" },
+        },
+      })
+
+      local errs = process()
+      assert.equals(vim.diagnostic.severity.INFO, errs[1].severity)
+      assert.equals(vim.diagnostic.severity.INFO, errs[2].severity)
+      -- Patterns are case-sensitive
+      assert.equals(vim.diagnostic.severity.HINT, errs[3].severity)
+    end)
+
+    it("keeps the default classification without rules", function()
+      local errs = process()
+      assert.equals(vim.diagnostic.severity.ERROR, errs[1].severity)
+      assert.equals(vim.diagnostic.severity.HINT, errs[2].severity)
+      assert.equals(vim.diagnostic.severity.HINT, errs[3].severity)
+    end)
+
+    it("suppresses log hints", function()
+      options.set({ show_log_hints = false })
+
+      local errs = process()
+      assert.equals(1, #errs)
+      assert.equals("retrying after error: timeout", errs[1].message)
+    end)
+  end)
+
   describe("Windows path handling in position_id", function()
     it(
       "filters diagnostics by Windows test filename from position_id",
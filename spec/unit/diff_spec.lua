@@ -0,0 +1,114 @@
+local _ = require("plenary")
+local diff = require("neotest-golang.lib.diff")
+local lib = require("neotest-golang.lib")
+local options = require("neotest-golang.options")
+
+local test_file = "/repo/pkg/parse_test.go"
+
+describe("diff", function()
+  it("splits a diff into its removed and added sides", function()
+    local removed, added = diff.split({
+      "--- Expected",
+      "+++ Actual",
+      "@@ -1,2 +1,2 @@",
+      " same",
+      "-old",
+      "+new",
+    })
+    assert.are.same({ "same", "old" }, removed)
+    assert.are.same({ "same", "new" }, added)
+  end)
+
+  it("knows which side of a go-cmp diff is the actual value", function()
+    assert.are.equal(
+      "expected",
+      diff.parse_cmp_header("Parse() mismatch (-want +got):").removed
+    )
+    assert.are.equal(
+      "actual",
+      diff.parse_cmp_header("Parse() mismatch (-got +want):").removed
+    )
+    assert.is_nil(diff.parse_cmp_header("values differ"))
+  end)
+
+  it("attaches go-cmp diffs to the diagnostic of their message", function()
+    local errors = lib.diagnostics.process_diagnostics({
+      metadata = {
+        position_id = test_file .. "::TestParse",
+        output_parts = {
+          "=== RUN   TestParse\n",
+          "    parse_test.go:12: Parse() mismatch (-want +got):\n",
+          "          main.Config{\n",
+          '        - \tName: "a",\n',
+          '        + \tName: "b",\n',
+          "          }\n",
+          "    parse_test.go:14: done\n",
+          "--- FAIL: TestParse (0.00s)\n",
+        },
+      },
+    })
+
+    assert.are.equal(2, #errors)
+    assert.are.equal("Parse() mismatch (-want +got):", errors[1].message)
+    assert.are.same({
+      expected = ' main.Config{\n \tName: "a",\n }',
+      actual = ' main.Config{\n \tName: "b",\n }',
+      diff = {
+        "  main.Config{",
+        '- \tName: "a",',
+        '+ \tName: "b",',
+        "  }",
+      },
+      removed = "expected",
+    }, errors[1].comparison)
+    assert.are.equal("done", errors[2].message)
+    assert.is_nil(errors[2].comparison)
+  end)
+
+  it("collects testify's expected and actual values and diff", function()
+    options.setup({ testify_enabled = true })
+
+    local errors = lib.diagnostics.process_diagnostics({
+      metadata = {
+        position_id = test_file .. "::TestEqual",
+        output_parts = {
+          "    parse_test.go:37: \n",
+          "        \tError Trace:\t" .. test_file .. ":37\n",
+          "        \tError:      \tNot equal: \n",
+          '        \t            \texpected: "expected"\n',
+          '        \t            \tactual  : "actual"\n',
+          "        \t            \t\n",
+          "        \t            \tDiff:\n",
+          "        \t            \t--- Expected\n",
+          "        \t            \t+++ Actual\n",
+          "        \t            \t@@ -1 +1 @@\n",
+          "        \t            \t-expected\n",
+          "        \t            \t+actual\n",
+          "        \tTest:       \tTestEqual\n",
+          "--- FAIL: TestEqual (0.00s)\n",
+        },
+      },
+    })
+
+    assert.are.equal(1, #errors)
+    local comparison = errors[1].comparison
+    assert.are.equal('"expected"', comparison.expected)
+    assert.are.equal('"actual"', comparison.actual)
+    local expected, actual = diff.sides(comparison)
+    assert.are.same({ "expected" }, expected)
+    assert.are.same({ "actual" }, actual)
+
+    options.setup({ testify_enabled = false })
+  end)
+
+  it("finds the comparison closest to the cursor", function()
+    diff.record(test_file .. "::TestA", {
+      { line = 4, message = "a", comparison = { expected = "1" } },
+      { line = 20, message = "b", comparison = { expected = "2" } },
+    })
+    assert.are.equal("1", diff.find(test_file, 10).expected)
+    assert.are.equal("2", diff.find(test_file, 20).expected)
+    assert.are.equal("1", diff.find(test_file, 0).expected)
+    assert.is_nil(diff.find("/repo/pkg/other_test.go", 4))
+  end)
+end)
@@ -1,5 +1,7 @@
 local _ = require("plenary")
+local Tree = require("neotest.types").Tree
 local discovery_cache = require("neotest-golang.lib.discovery_cache")
+local options = require("neotest-golang.options")
 
 describe("Discovery cache", function()
   local test_file = vim.fn.tempname() .. "_test.go"
@@ -52,6 +54,183 @@ describe("Discovery cache", function()
       local result = discovery_cache.get(test_file)
       assert.is_nil(result)
     end)
+
+    it("returns nil after modification within the same second", function()
+      discovery_cache.set(test_file, mock_tree)
+
+      local file = io.open(test_file, "w")
+      if file then
+        file:write("package main\n\n// modified\n")
+        file:close()
+      end
+
+      local result = discovery_cache.get(test_file)
+      assert.is_nil(result)
+    end)
+
+    it("does not read an unchanged file again", function()
+      discovery_cache.set(test_file, mock_tree)
+
+      local file = require("neotest-golang.lib.file")
+      local read_lines = file.read_lines
+      local reads = 0
+      file.read_lines = function(...)
+        reads = reads + 1
+        return read_lines(...)
+      end
+      local result = discovery_cache.get(test_file)
+      file.read_lines = read_lines
+
+      assert.are.same(mock_tree, result)
+      assert.are.equal(0, reads)
+    end)
+
+    it("keeps the tree of a touched file with the same content", function()
+      discovery_cache.set(test_file, mock_tree)
+
+      local now = os.time() + 10
+      vim.uv.fs_utime(test_file, now, now)
+
+      assert.are.same(mock_tree, discovery_cache.get(test_file))
+    end)
+
+    it("returns nil when the queries change", function()
+      discovery_cache.set(test_file, mock_tree, "(function_declaration)")
+      local result = discovery_cache.get(test_file, "(method_declaration)")
+      assert.is_nil(result)
+    end)
+
+    it("keys the tree by the source it was parsed from", function()
+      local stamp = discovery_cache.stamp(test_file)
+      local source = "package main\n"
+      discovery_cache.set(test_file, mock_tree, nil, stamp, source)
+      assert.are.same(mock_tree, discovery_cache.get(test_file))
+
+      -- The file changes while it is parsed
+      stamp = discovery_cache.stamp(test_file)
+      local file = io.open(test_file, "w")
+      if file then
+        file:write("package main\n\n// modified\n")
+        file:close()
+      end
+      discovery_cache.set(test_file, mock_tree, nil, stamp, source)
+
+      assert.is_nil(discovery_cache.get(test_file))
+    end)
+
+    it("returns nil when another test file of the package changes", function()
+      local other_file = vim.fs.dirname(test_file)
+        .. "/other_"
+        .. vim.fs.basename(test_file)
+      local file = io.open(other_file, "w")
+      if file then
+        file:write("package main\n")
+        file:close()
+      end
+      discovery_cache.set(test_file, mock_tree)
+
+      file = io.open(other_file, "w")
+      if file then
+        file:write(
+          "package main\n\n"
+            .. 'var update = flag.Bool("update", false, "")\n'
+        )
+        file:close()
+      end
+      local result = discovery_cache.get(test_file)
+      os.remove(other_file)
+
+      assert.is_nil(result)
+    end)
+  end)
+
+  describe("on disk", function()
+    local cache_dir = vim.fn.tempname()
+    local original_cache_dir = discovery_cache.cache_dir
+
+    local function make_tree()
+      return Tree.from_list({
+        {
+          type = "file",
+          id = test_file,
+          path = test_file,
+          name = "test_file",
+          range = { 0, 0, 3, 0 },
+        },
+        {
+          {
+            type = "test",
+            id = test_file .. "::TestOne",
+            path = test_file,
+            name = "TestOne",
+            range = { 2, 0, 2, 30 },
+            golden = true,
+          },
+        },
+      }, function(pos)
+        return pos.id
+      end)
+    end
+
+    before_each(function()
+      discovery_cache.cache_dir = function()
+        return cache_dir
+      end
+      options.set({ persistent_discovery_cache = true })
+    end)
+
+    after_each(function()
+      discovery_cache.clear(true)
+      discovery_cache.cache_dir = original_cache_dir
+      options.set({ persistent_discovery_cache = false })
+    end)
+
+    it("restores the tree in a new session", function()
+      local tree = make_tree()
+      discovery_cache.set(test_file, tree)
+
+      -- A new session starts with an empty in-memory cache
+      discovery_cache.clear()
+      local result = discovery_cache.get(test_file)
+
+      assert.is_not_nil(result)
+      assert.are.same(tree:to_list(), result:to_list())
+      assert.are.same(
+        tree:get_key(test_file .. "::TestOne"):data(),
+        result:get_key(test_file .. "::TestOne"):data()
+      )
+    end)
+
+    it("does not restore the tree of a modified file", function()
+      discovery_cache.set(test_file, make_tree())
+      discovery_cache.clear()
+
+      local file = io.open(test_file, "w")
+      if file then
+        file:write("package main\n\n// modified\n")
+        file:close()
+      end
+
+      assert.is_nil(discovery_cache.get(test_file))
+    end)
+
+    it("does not restore the tree with other options", function()
+      discovery_cache.set(test_file, make_tree())
+      discovery_cache.clear()
+
+      options.set({ testify_enabled = true })
+      local result = discovery_cache.get(test_file)
+      options.set({ testify_enabled = false })
+
+      assert.is_nil(result)
+    end)
+
+    it("does not restore the tree once invalidated", function()
+      discovery_cache.set(test_file, make_tree())
+      discovery_cache.invalidate(test_file)
+
+      assert.is_nil(discovery_cache.get(test_file))
+    end)
   end)
 
   describe("invalidate", function()
@@ -0,0 +1,69 @@
+local _ = require("plenary")
+local duration = require("neotest-golang.lib.duration")
+local options = require("neotest-golang.options")
+
+describe("duration", function()
+  before_each(function()
+    options.setup({ slow_test_threshold = 0 })
+  end)
+
+  after_each(function()
+    options.setup({ slow_test_threshold = 0 })
+  end)
+
+  it("formats elapsed seconds like go test", function()
+    assert.are.equal("0.00s", duration.format(0))
+    assert.are.equal("0.52s", duration.format(0.52))
+    assert.are.equal("12.35s", duration.format(12.345))
+  end)
+
+  it("does not flag slow tests when the threshold is disabled", function()
+    assert.is_false(duration.is_slow(100))
+    assert.are.equal("passed in 1.00s", duration.short("passed", 1))
+    assert.are.equal("=== Elapsed: 1.00s ===", duration.header(1))
+    assert.are.same(
+      {},
+      duration.slow_tests({
+        { Action = "pass", Package = "pkg", Test = "TestA", Elapsed = 100 },
+      })
+    )
+  end)
+
+  it("flags tests at or above the threshold", function()
+    options.setup({ slow_test_threshold = 1 })
+
+    assert.is_false(duration.is_slow(0.99))
+    assert.is_true(duration.is_slow(1))
+    assert.is_false(duration.is_slow(nil))
+    assert.are.equal("failed in 2.00s [slow]", duration.short("failed", 2))
+    assert.are.equal(
+      "=== Elapsed: 2.00s (slow, threshold 1.00s) ===",
+      duration.header(2)
+    )
+  end)
+
+  it("lists slow tests slowest first", function()
+    options.setup({ slow_test_threshold = 1 })
+
+    local events = {
+      { Action = "run", Package = "pkg", Test = "TestA" },
+      { Action = "pass", Package = "pkg", Test = "TestA", Elapsed = 1.5 },
+      { Action = "pass", Package = "pkg", Test = "TestFast", Elapsed = 0.1 },
+      { Action = "fail", Package = "pkg", Test = "TestB", Elapsed = 3 },
+      { Action = "pass", Package = "pkg", Elapsed = 10 }, -- package event
+    }
+
+    local slow = duration.slow_tests(events)
+
+    assert.are.same({
+      { package = "pkg", test = "TestB", elapsed = 3 },
+      { package = "pkg", test = "TestA", elapsed = 1.5 },
+    }, slow)
+
+    local lines = duration.slow_summary_lines(slow)
+    assert.are.equal("=== Slow tests (threshold 1.00s): 2 ===", lines[1])
+    assert.are.equal("     3.00s  pkg TestB", lines[2])
+    assert.are.equal("     1.50s  pkg TestA", lines[3])
+    assert.are.same({}, duration.slow_summary_lines({}))
+  end)
+end)
@@ -0,0 +1,94 @@
+local _ = require("plenary")
+local examples = require("neotest-golang.lib.examples")
+
+describe("examples", function()
+  it("detects output comments like go test", function()
+    assert.is_true(examples.is_output_comment("// Output: 4"))
+    assert.is_true(examples.is_output_comment("// Output:"))
+    assert.is_true(examples.is_output_comment("// Unordered output:"))
+    assert.is_true(examples.is_output_comment("//output: 4"))
+    assert.is_false(examples.is_output_comment("// Prints the output: 4"))
+    assert.is_false(examples.is_output_comment("// Example for Add"))
+  end)
+
+  it("parses the got and want blocks of a failed Example", function()
+    local mismatch = examples.parse_mismatch({
+      "=== RUN   ExampleAdd\n",
+      "--- FAIL: ExampleAdd (0.00s)\n",
+      "got:\n",
+      "6\n",
+      "want:\n",
+      "5\n",
+    })
+    assert.are.same({ got = { "6" }, want = { "5" } }, mismatch)
+  end)
+
+  it("ignores output of Examples which did not fail", function()
+    assert.is_nil(examples.parse_mismatch({
+      "=== RUN   ExampleAdd\n",
+      "got:\n",
+      "--- PASS: ExampleAdd (0.00s)\n",
+    }))
+  end)
+
+  it("diffs the wanted and the printed lines", function()
+    assert.are.same(
+      { " 6", "-20", "+21", " 30", "+40" },
+      examples.diff_lines({ "6", "20", "30" }, { "6", "21", "30", "40" })
+    )
+  end)
+
+  it("finds the output comments of Example functions", function()
+    local rows = examples.find_output_comments(
+      vim.uv.cwd() .. "/tests/go/internal/examples/examples_test.go"
+    )
+    assert.are.equal(25, rows["ExampleAdd"])
+    assert.are.equal(46, rows["ExampleMultiply_second"])
+    assert.is_false(rows["ExampleCalculator"])
+    assert.is_nil(rows["TestAdd"])
+  end)
+
+  it("finds the output comments in the source it is given", function()
+    local source = table.concat({
+      "package examples",
+      "",
+      'import "fmt"',
+      "",
+      "func ExampleHello() {",
+      '\tfmt.Println("hello")',
+      "\t// Output: hello",
+      "}",
+      "",
+      "func ExampleSilent() {}",
+    }, "\n")
+    local rows =
+      examples.find_output_comments("/does/not/exist_test.go", source)
+    assert.are.equal(6, rows["ExampleHello"])
+    assert.is_false(rows["ExampleSilent"])
+  end)
+
+  it("only takes the last comment of the body as output comment", function()
+    local source = table.concat({
+      "package examples",
+      "",
+      'import "fmt"',
+      "",
+      "func ExampleHello() {",
+      '\tfmt.Println("hello")',
+      "\t// Output:",
+      "\t// hello",
+      "}",
+      "",
+      "func ExampleCommented() {",
+      '\tfmt.Println("hello")',
+      "\t// Output: hello",
+      "",
+      "\t// Not compared any more",
+      "}",
+    }, "\n")
+    local rows =
+      examples.find_output_comments("/does/not/exist_test.go", source)
+    assert.are.equal(6, rows["ExampleHello"])
+    assert.is_false(rows["ExampleCommented"])
+  end)
+end)
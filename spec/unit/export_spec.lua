@@ -0,0 +1,193 @@
+local _ = require("plenary")
+local export = require("neotest-golang.lib.export")
+
+local pkg = "example.com/repo/pkg"
+local file_path = "/repo/pkg/file_test.go"
+
+local gotest_output = {
+  { Action = "start", Package = pkg },
+  { Action = "run", Package = pkg, Test = "TestPass" },
+  {
+    Action = "output",
+    Package = pkg,
+    Test = "TestPass",
+    Output = "=== RUN   TestPass\n",
+  },
+  { Action = "pass", Package = pkg, Test = "TestPass", Elapsed = 0.01 },
+  { Action = "run", Package = pkg, Test = "TestFail" },
+  { Action = "run", Package = pkg, Test = "TestFail/sub_case" },
+  {
+    Action = "output",
+    Package = pkg,
+    Test = "TestFail/sub_case",
+    Output = "    file_test.go:12: got 1 & want <2>\n",
+  },
+  {
+    Action = "fail",
+    Package = pkg,
+    Test = "TestFail/sub_case",
+    Elapsed = 0.02,
+  },
+  { Action = "fail", Package = pkg, Test = "TestFail", Elapsed = 0.03 },
+  { Action = "run", Package = pkg, Test = "TestSkip" },
+  { Action = "skip", Package = pkg, Test = "TestSkip", Elapsed = 0 },
+  { Action = "output", Package = pkg, Output = "FAIL\n" },
+  { Action = "fail", Package = pkg, Elapsed = 0.5 },
+}
+
+local lookup = {
+  [pkg .. "::TestPass"] = file_path .. "::TestPass",
+  [pkg .. "::TestFail"] = file_path .. "::TestFail",
+  [pkg .. "::TestFail/sub_case"] = file_path .. '::TestFail::"sub case"',
+  [pkg .. "::TestSkip"] = file_path .. "::TestSkip",
+}
+
+local results = {
+  [file_path .. '::TestFail::"sub case"'] = {
+    status = "failed",
+    errors = {
+      {
+        line = 11,
+        message = "got 1 & want <2>",
+        severity = vim.diagnostic.severity.ERROR,
+      },
+      {
+        line = 5,
+        message = "just a log",
+        severity = vim.diagnostic.severity.HINT,
+      },
+    },
+  },
+}
+
+describe("export.build_report", function()
+  it("builds the package and test hierarchy from the events", function()
+    local packages = export.build_report(results, gotest_output, lookup)
+
+    assert.are.equal(1, #packages)
+    assert.are.equal(pkg, packages[1].name)
+    assert.are.equal("failed", packages[1].status)
+    assert.are.equal(0.5, packages[1].elapsed)
+    assert.are.same({ "FAIL" }, packages[1].output)
+
+    local tests = packages[1].tests
+    assert.are.same(
+      { "TestPass", "TestFail", "TestFail/sub_case", "TestSkip" },
+      vim.tbl_map(function(t)
+        return t.name
+      end, tests)
+    )
+    assert.are.same(
+      { "passed", "failed", "failed", "skipped" },
+      vim.tbl_map(function(t)
+        return t.status
+      end, tests)
+    )
+    assert.are.same({ "=== RUN   TestPass" }, tests[1].output)
+    assert.are.same(
+      { file_path .. ":12: got 1 & want <2>" },
+      tests[3].messages
+    )
+  end)
+end)
+
+describe("export.to_junit", function()
+  it("renders escaped JUnit XML", function()
+    local packages = export.build_report(results, gotest_output, lookup)
+    local xml = table.concat(export.to_junit(packages), "\n")
+
+    assert.is_truthy(
+      xml:find(
+        '<testsuites tests="4" failures="2" errors="0" skipped="1" time="0.500">',
+        1,
+        true
+      )
+    )
+    assert.is_truthy(
+      xml:find(
+        '<testcase classname="example.com/repo/pkg" name="TestFail/sub_case" time="0.020">',
+        1,
+        true
+      )
+    )
+    assert.is_truthy(
+      xml:find("got 1 &amp; want &lt;2&gt;", 1, true),
+      "failure message must be escaped"
+    )
+    assert.is_truthy(xml:find('<skipped message="skipped"></skipped>', 1, true))
+  end)
+
+  it("reports a failed package without failed tests as TestMain", function()
+    local packages = export.build_report({}, {
+      { Action = "output", Package = pkg, Output = "build failed\n" },
+      { Action = "fail", Package = pkg, Elapsed = 0 },
+    }, {})
+    local xml = table.concat(export.to_junit(packages), "\n")
+
+    assert.is_truthy(xml:find('name="TestMain"', 1, true))
+    assert.is_truthy(xml:find("<system-out>build failed</system-out>", 1, true))
+  end)
+end)
+
+describe("export.to_tap", function()
+  it("renders one test point per test", function()
+    local packages = export.build_report(results, gotest_output, lookup)
+    local lines = export.to_tap(packages)
+
+    assert.are.equal("TAP version 13", lines[1])
+    assert.are.equal("1..4", lines[2])
+    assert.are.equal("# " .. pkg, lines[3])
+    assert.is_true(vim.tbl_contains(lines, "ok 1 - " .. pkg .. " TestPass"))
+    assert.is_true(
+      vim.tbl_contains(lines, "not ok 3 - " .. pkg .. " TestFail/sub_case")
+    )
+    assert.is_true(
+      vim.tbl_contains(lines, "ok 4 - " .. pkg .. " TestSkip # SKIP")
+    )
+    assert.is_true(
+      vim.tbl_contains(lines, "    " .. file_path .. ":12: got 1 & want <2>")
+    )
+  end)
+
+  it("indents every line of multi-line messages", function()
+    local multiline_results = {
+      [file_path .. '::TestFail::"sub case"'] = {
+        status = "failed",
+        errors = {
+          {
+            line = 11,
+            message = "Error: Not equal:\nexpected: 2\nactual  : 1",
+            severity = vim.diagnostic.severity.ERROR,
+          },
+        },
+      },
+    }
+    local packages =
+      export.build_report(multiline_results, gotest_output, lookup)
+    local lines = export.to_tap(packages)
+
+    assert.is_true(
+      vim.tbl_contains(lines, "    " .. file_path .. ":12: Error: Not equal:")
+    )
+    assert.is_true(vim.tbl_contains(lines, "    expected: 2"))
+    assert.is_true(vim.tbl_contains(lines, "    actual  : 1"))
+    assert.is_false(vim.tbl_contains(lines, "expected: 2"))
+  end)
+end)
+
+describe("export.write", function()
+  it("resolves relative paths from the given root", function()
+    local nio = require("nio")
+    local root = vim.fn.tempname()
+
+    nio.tests.with_async_context(function()
+      export.write(results, gotest_output, lookup, root, {
+        tap = "reports/report.tap",
+      })
+    end)
+
+    local lines = vim.fn.readfile(root .. "/reports/report.tap")
+    vim.fn.delete(root, "rf")
+    assert.are.equal("TAP version 13", lines[1])
+  end)
+end)
@@ -3,6 +3,16 @@ local lib = require("neotest-golang.lib")
 local options = require("neotest-golang.options")
 
 describe("Extra args", function()
+  local unbind
+
+  before_each(function()
+    unbind = lib.run_state.bind(lib.run_state.new())
+  end)
+
+  after_each(function()
+    unbind()
+  end)
+
   it("Can't be nil even if set to nil", function()
     lib.extra_args.set(nil)
     assert.are.same({}, lib.extra_args.get())
@@ -0,0 +1,106 @@
+local _ = require("plenary")
+local failure_breakpoint =
+  require("neotest-golang.features.dap.failure_breakpoint")
+local failures = require("neotest-golang.lib.failures")
+
+local test_file = vim.fn.tempname() .. "_test.go"
+local position_id = test_file .. "::TestGet"
+
+describe("failure breakpoint", function()
+  local listeners, set, removed
+
+  --- Notify the listeners of an event or request of a session.
+  local function notify(phase, name, session, ...)
+    for _, listener in pairs(listeners[phase][name]) do
+      listener(session, ...)
+    end
+  end
+
+  before_each(function()
+    listeners = { before = {}, after = {} }
+    for _, name in ipairs({
+      "event_initialized",
+      "event_terminated",
+      "event_exited",
+      "disconnect",
+      "launch",
+    }) do
+      listeners.before[name] = {}
+      listeners.after[name] = {}
+    end
+    set, removed = {}, {}
+    package.loaded["dap"] = { listeners = listeners }
+    package.loaded["dap.breakpoints"] = {
+      get = function()
+        return {}
+      end,
+      set = function(_, bufnr, line)
+        table.insert(set, { bufnr = bufnr, line = line })
+      end,
+      remove = function(bufnr, line)
+        table.insert(removed, { bufnr = bufnr, line = line })
+      end,
+    }
+
+    failures.record(position_id, "failed", {
+      { line = 11, message = "boom", severity = 1 },
+    }, { "    get_test.go:12: boom\n" })
+  end)
+
+  after_each(function()
+    package.loaded["dap"] = nil
+    package.loaded["dap.breakpoints"] = nil
+  end)
+
+  it("is set for the session which starts, until it ends", function()
+    local handle = failure_breakpoint.set(position_id)
+    assert.is_not_nil(handle)
+    assert.are.equal(0, #set)
+
+    notify("before", "event_initialized", { id = 1 })
+    assert.are.same({ { bufnr = vim.fn.bufnr(test_file), line = 12 } }, set)
+
+    -- Another session ending leaves the breakpoint alone
+    notify("after", "event_terminated", { id = 2 })
+    vim.wait(10)
+    assert.are.equal(0, #removed)
+
+    notify("after", "event_terminated", { id = 1 })
+    vim.wait(100, function()
+      return #removed > 0
+    end)
+    assert.are.same(set, removed)
+    assert.is_nil(listeners.after.event_exited[handle.key])
+  end)
+
+  it("is removed when its session fails to launch", function()
+    failure_breakpoint.set(position_id)
+    notify("before", "event_initialized", { id = 3 })
+    notify("after", "launch", { id = 3 }, { message = "could not launch" })
+
+    vim.wait(100, function()
+      return #removed > 0
+    end)
+    assert.are.same(set, removed)
+  end)
+
+  it("forgets its listeners when the session never started", function()
+    local handle = failure_breakpoint.set(position_id)
+    failure_breakpoint.remove(handle)
+
+    assert.is_nil(listeners.before.event_initialized[handle.key])
+    assert.is_nil(listeners.after.launch[handle.key])
+    notify("before", "event_initialized", { id = 4 })
+    assert.are.equal(0, #set)
+  end)
+
+  it("is not set without a recorded failure", function()
+    local notify_fn = vim.notify
+    vim.notify = function() end
+    local handle = failure_breakpoint.set(test_file .. "::TestPassed")
+    vim.notify = notify_fn
+
+    assert.is_nil(handle)
+    assert.is_nil(next(listeners.before.event_initialized))
+  end)
+end)
@@ -0,0 +1,49 @@
+local _ = require("plenary")
+local failures = require("neotest-golang.lib.failures")
+
+local test_file = "/home/user/pkg/list_test.go"
+local position_id = test_file .. "::TestGet"
+
+describe("failure locations", function()
+  it("uses the innermost frame of a panic outside the stdlib", function()
+    failures.record(position_id, "failed", {
+      { line = 11, message = "panic: boom", severity = 1 },
+    }, {
+      "=== RUN   TestGet\n",
+      "--- FAIL: TestGet (0.00s)\n",
+      "panic: runtime error: index out of range [3] with length 3 "
+        .. "[recovered]\n",
+      "\tpanic: runtime error: index out of range [3] with length 3\n",
+      "\n",
+      "goroutine 7 [running]:\n",
+      "testing.tRunner.func1.2({0x5a1f20, 0xc000018030})\n",
+      "\t/usr/local/go/src/testing/testing.go:1632 +0x230\n",
+      "panic({0x5a1f20?, 0xc000018030?})\n",
+      "\t/usr/local/go/src/runtime/panic.go:770 +0x132\n",
+      "example.com/pkg.Get(...)\n",
+      "\t/home/user/pkg/list.go:8\n",
+      "example.com/pkg.TestGet(0xc000007860)\n",
+      "\t" .. test_file .. ":12 +0x1d\n",
+    })
+
+    assert.are.same(
+      { filename = "/home/user/pkg/list.go", line_number = 8 },
+      failures.get(position_id)
+    )
+  end)
+
+  it("uses the last failed assertion, and forgets passed tests", function()
+    failures.record(position_id, "failed", {
+      { line = 11, message = "first", severity = 1 },
+      { line = 15, message = "second", severity = 1 },
+      { line = 16, message = "logged", severity = 4 },
+    }, { "    list_test.go:12: first\n" })
+    assert.are.same(
+      { filename = test_file, line_number = 16 },
+      failures.get(position_id)
+    )
+
+    failures.record(position_id, "passed", {}, {})
+    assert.is_nil(failures.get(position_id))
+  end)
+end)
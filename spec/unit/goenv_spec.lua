@@ -148,4 +148,38 @@ describe("Go environment utilities", function()
       assert.is_false(result)
     end)
   end)
+
+  describe("go version", function()
+    after_each(function()
+      goenv.clear_cache()
+    end)
+
+    it("parses release, pre-release and development versions", function()
+      assert.are.same(
+        { major = 1, minor = 21 },
+        goenv.parse_version("go1.21.0")
+      )
+      assert.are.same(
+        { major = 1, minor = 22 },
+        goenv.parse_version("go1.22rc1")
+      )
+      assert.are.same(
+        { major = 1, minor = 23 },
+        goenv.parse_version("devel go1.23-abc123 Mon Jan 1")
+      )
+      assert.is_nil(goenv.parse_version(""))
+    end)
+
+    it("compares the version with a minimum version", function()
+      goenv.set_version_for_testing("go1.21.5")
+      assert.is_true(goenv.is_go_version_at_least(1, 21))
+      assert.is_false(goenv.is_go_version_at_least(1, 22))
+
+      goenv.set_version_for_testing("go1.20.14")
+      assert.is_false(goenv.is_go_version_at_least(1, 21))
+
+      goenv.set_version_for_testing(nil)
+      assert.is_false(goenv.is_go_version_at_least(1, 0))
+    end)
+  end)
 end)
@@ -0,0 +1,172 @@
+local _ = require("plenary")
+local Tree = require("neotest.types").Tree
+local extra_args = require("neotest-golang.lib.extra_args")
+local golden = require("neotest-golang.lib.golden")
+local run_state = require("neotest-golang.lib.run_state")
+
+describe("golden", function()
+  local unbind
+
+  before_each(function()
+    unbind = run_state.bind(run_state.new())
+  end)
+
+  after_each(function()
+    unbind()
+  end)
+
+  it("finds update flags declared with flag.Bool and flag.BoolVar", function()
+    assert.are.same(
+      { name = "update", variable = "update", deref = true },
+      golden.find_update_flag_in_lines({
+        "package foo",
+        'var update = flag.Bool("update", false, "update golden files")',
+      })
+    )
+    assert.are.same(
+      { name = "update-golden", variable = "regen", deref = false },
+      golden.find_update_flag_in_lines({
+        'flag.BoolVar(&regen, "update-golden", false, "regenerate")',
+      })
+    )
+  end)
+
+  it("ignores flags which are not about updating", function()
+    assert.is_nil(golden.find_update_flag_in_lines({
+      'var verbose = flag.Bool("verbose", false, "more output")',
+    }))
+  end)
+
+  it("detects tests which reference golden files", function()
+    local flag = { name = "update", variable = "update", deref = true }
+    assert.is_true(golden.references_golden({
+      'want := filepath.Join("testdata", t.Name()+".golden")',
+    }, nil))
+    assert.is_true(golden.references_golden({
+      "g := goldie.New(t)",
+      "golden.Assert(t, got, name)",
+    }, nil))
+    assert.is_true(golden.references_golden({
+      "if *update {",
+    }, flag))
+    assert.is_false(golden.references_golden({
+      "if updated {",
+      "  t.Fatal(update)",
+    }, flag))
+  end)
+
+  it("marks tests with the lines of the file", function()
+    local file_path = "/repo/pkg/golden_test.go"
+    local tree = Tree.from_list({
+      {
+        type = "file",
+        id = file_path,
+        path = file_path,
+        range = { 0, 0, 6, 0 },
+      },
+      {
+        {
+          type = "test",
+          id = file_path .. "::TestGolden",
+          path = file_path,
+          range = { 2, 0, 4, 1 },
+        },
+        {
+          {
+            type = "test",
+            id = file_path .. '::TestGolden::"case"',
+            path = file_path,
+            range = { 4, 0, 4, 1 },
+          },
+        },
+      },
+      {
+        {
+          type = "test",
+          id = file_path .. "::TestPlain",
+          path = file_path,
+          range = { 5, 0, 5, 20 },
+        },
+      },
+    }, function(pos)
+      return pos.id
+    end)
+
+    golden.mark_tree(file_path, tree, {
+      "package pkg",
+      "",
+      "func TestGolden(t *testing.T) {",
+      '  want := filepath.Join("testdata", t.Name()+".golden")',
+      "}",
+      "func TestPlain(t *testing.T) {}",
+    })
+
+    assert.are.same({
+      [file_path .. "::TestGolden"] = true,
+      [file_path .. '::TestGolden::"case"'] = true,
+    }, golden.positions(tree))
+  end)
+
+  it("marks tests with the update flag of another file", function()
+    local dir = vim.fn.tempname()
+    vim.fn.mkdir(dir, "p")
+    vim.fn.writefile({
+      "package pkg",
+      'var update = flag.Bool("update", false, "update golden files")',
+    }, dir .. "/main_test.go")
+    local file_path = dir .. "/render_test.go"
+    local tree = Tree.from_list({
+      {
+        type = "file",
+        id = file_path,
+        path = file_path,
+        range = { 0, 0, 5, 0 },
+      },
+      {
+        {
+          type = "test",
+          id = file_path .. "::TestRender",
+          path = file_path,
+          range = { 2, 0, 4, 1 },
+        },
+      },
+    }, function(pos)
+      return pos.id
+    end)
+
+    golden.mark_tree(file_path, tree, {
+      "package pkg",
+      "",
+      "func TestRender(t *testing.T) {",
+      "  if *update {",
+      "}",
+    })
+    vim.fn.delete(dir, "rf")
+
+    assert.are.same(
+      { [file_path .. "::TestRender"] = true },
+      golden.positions(tree)
+    )
+  end)
+
+  it("passes the update flag to the test binary", function()
+    assert.are.same({}, golden.update_args())
+    extra_args.set({ golden_update = "update" })
+    assert.are.same({ "-args", "-update" }, golden.update_args())
+  end)
+
+  it("summarizes the changed golden files", function()
+    assert.are.same(
+      { "=== Golden files: none changed ===" },
+      golden.summary_lines({}, "/repo/pkg")
+    )
+    assert.are.same({
+      "=== Golden files: 2 changed ===",
+      "     added  testdata/TestNew.golden",
+      "  modified  testdata/TestOld.golden",
+    }, golden.summary_lines({
+      { path = "/repo/pkg/testdata/TestNew.golden", change = "added" },
+      { path = "/repo/pkg/testdata/TestOld.golden", change = "modified" },
+    }, "/repo/pkg"))
+  end)
+end)
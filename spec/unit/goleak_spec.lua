@@ -0,0 +1,133 @@
+local _ = require("plenary")
+local file_diagnostics = require("neotest-golang.lib.file_diagnostics")
+local goleak = require("neotest-golang.lib.goleak")
+local run_state = require("neotest-golang.lib.run_state")
+
+local pkg = "example.com/repo/pkg"
+local source_file = "/repo/pkg/worker.go"
+local test_file = "/repo/pkg/worker_test.go"
+
+local verify_test_main_output = {
+  "PASS\n",
+  "goleak: Errors on successful test run: found unexpected goroutines:\n",
+  "[Goroutine 7 in state chan receive, with example.com/repo/pkg.worker on top of the stack:\n",
+  "goroutine 7 [chan receive]:\n",
+  "example.com/repo/pkg.worker(0xc000012345)\n",
+  "\t" .. source_file .. ":10 +0x25\n",
+  "created by example.com/repo/pkg.Start in goroutine 6\n",
+  "\t" .. source_file .. ":5 +0x6f\n",
+  " Goroutine 8 in state select, with example.com/repo/pkg.TestStart.func1 on top of the stack:\n",
+  "goroutine 8 [select]:\n",
+  "example.com/repo/pkg.TestStart.func1()\n",
+  "\t" .. test_file .. ":20 +0x30\n",
+  "created by example.com/repo/pkg.TestStart in goroutine 6\n",
+  "\t" .. test_file .. ":18 +0x44\n",
+  "]\n",
+  "FAIL\t" .. pkg .. "\t0.312s\n",
+}
+
+describe("goleak", function()
+  local unbind
+
+  before_each(function()
+    unbind = run_state.bind(run_state.new())
+    file_diagnostics.reset()
+  end)
+
+  after_each(function()
+    unbind()
+  end)
+
+  it("parses the leaked goroutines and where they were created", function()
+    local leaks = goleak.parse_parts(verify_test_main_output)
+    assert.are.equal(2, #leaks)
+
+    assert.are.equal("7", leaks[1].id)
+    assert.are.equal("chan receive", leaks[1].state)
+    assert.are.equal("example.com/repo/pkg.worker", leaks[1].top)
+    assert.are.same({
+      {
+        func = "example.com/repo/pkg.worker",
+        filename = source_file,
+        line_number = 10,
+      },
+    }, leaks[1].frames)
+    assert.are.same({
+      func = "example.com/repo/pkg.Start",
+      filename = source_file,
+      line_number = 5,
+    }, leaks[1].created_by)
+
+    assert.are.equal("8", leaks[2].id)
+    assert.are.equal(18, leaks[2].created_by.line_number)
+  end)
+
+  it("parses reports of goleak.VerifyNone in a test", function()
+    local leaks = goleak.parse_parts({
+      "    worker_test.go:25: found unexpected goroutines:\n",
+      "        [Goroutine 9 in state sleep, with time.Sleep on top of the stack:\n",
+      "        goroutine 9 [sleep]:\n",
+      "        time.Sleep(0x3b9aca00)\n",
+      "        \t/usr/local/go/src/runtime/time.go:300 +0xf8\n",
+      "        created by example.com/repo/pkg.Start in goroutine 6\n",
+      "        \t" .. source_file .. ":5 +0x6f\n",
+      "        ]\n",
+    })
+    assert.are.equal(1, #leaks)
+    assert.are.equal("time.Sleep", leaks[1].top)
+    assert.are.equal(5, leaks[1].created_by.line_number)
+  end)
+
+  it("ignores output without leaks", function()
+    assert.is_false(goleak.has_report({ "PASS\n", "ok\tpkg\t0.1s\n" }))
+    assert.is_true(goleak.has_report(verify_test_main_output))
+    assert.are.same({}, goleak.parse_parts({ "PASS\n", "ok\tpkg\t0.1s\n" }))
+  end)
+
+  it("summarizes and describes the leaks", function()
+    local leaks = goleak.parse_parts(verify_test_main_output)
+    assert.are.equal(
+      "goroutine leak: 2 goroutines still running",
+      goleak.summary(leaks)
+    )
+    assert.are.equal(
+      "goroutine 7 [chan receive] in worker, created at worker.go:5",
+      goleak.describe(leaks[1])
+    )
+  end)
+
+  it("places diagnostics where the goroutines were created", function()
+    local leaks = goleak.parse_parts(verify_test_main_output)
+
+    -- The package result has no file, so all diagnostics are published
+    assert.are.same({}, goleak.process(leaks, "/repo/pkg"))
+    assert.are.equal(5, file_diagnostics.get()[source_file][1].line_number)
+    assert.are.equal(18, file_diagnostics.get()[test_file][1].line_number)
+
+    -- For a test, diagnostics in its file become errors of the test
+    local errors = goleak.process(leaks, test_file .. "::TestStart")
+    assert.are.same({
+      {
+        line = 17,
+        message = "goroutine leak: goroutine 8 [select] in TestStart.func1 "
+          .. "was started here and never finished",
+        severity = vim.diagnostic.severity.ERROR,
+      },
+    }, errors)
+  end)
+
+  it("summarizes leaks per package for the whole run", function()
+    local gotest_output = {}
+    for _, output in ipairs(verify_test_main_output) do
+      table.insert(
+        gotest_output,
+        { Action = "output", Package = pkg, Output = output }
+      )
+    end
+    assert.are.same({
+      "=== " .. pkg .. ": goroutine leak: 2 goroutines still running ===",
+      "===   goroutine 7 [chan receive] in worker, created at worker.go:5",
+      "===   goroutine 8 [select] in TestStart.func1, created at worker_test.go:18",
+    }, goleak.summary_lines(gotest_output))
+  end)
+end)
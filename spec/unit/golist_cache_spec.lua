@@ -0,0 +1,162 @@
+local _ = require("plenary")
+local lib = require("neotest-golang.lib")
+local path = require("neotest-golang.lib.path")
+
+describe("go list cache", function()
+  local tests_filepath = path.normalize_path(vim.uv.cwd() .. "/tests/go")
+  local positions_filepath =
+    path.normalize_path(tests_filepath .. "/internal/positions")
+
+  before_each(function()
+    lib.golist.clear()
+  end)
+
+  after_each(function()
+    lib.golist.clear()
+  end)
+
+  it("runs go list from the module root", function()
+    assert.are.equal(tests_filepath, lib.golist.module_root(positions_filepath))
+  end)
+
+  it("returns the packages in and below the directory", function()
+    local output = lib.cmd.golist_data(positions_filepath)
+    assert.are.equal(1, #output)
+    assert.are.equal(positions_filepath, output[1].Dir)
+  end)
+
+  it("reuses the output within the module", function()
+    local first = lib.cmd.golist_data(tests_filepath)
+    local second = lib.cmd.golist_data(positions_filepath)
+
+    local found = nil
+    for _, item in ipairs(first) do
+      if item.Dir == positions_filepath then
+        found = item
+      end
+    end
+    assert.are.equal(found, second[1])
+  end)
+
+  it("runs go list again once invalidated", function()
+    local first = lib.cmd.golist_data(positions_filepath)
+    lib.golist.invalidate(tests_filepath)
+    local second = lib.cmd.golist_data(positions_filepath)
+
+    assert.are_not.equal(first[1], second[1])
+    assert.are.same(first, second)
+  end)
+
+  describe("watching", function()
+    local new_fs_event = vim.uv.new_fs_event
+    local notify = vim.notify
+    local started
+
+    --- Replace the file watchers with fakes.
+    --- @param fails boolean Whether starting a watcher fails
+    local function fake_watchers(fails)
+      started = {}
+      vim.uv.new_fs_event = function()
+        return {
+          start = function(_, dir)
+            if fails then
+              return nil, "ENOSPC: no space left on device", "ENOSPC"
+            end
+            table.insert(started, dir)
+            return 0
+          end,
+          stop = function() end,
+          close = function() end,
+          is_closing = function()
+            return false
+          end,
+        }
+      end
+    end
+
+    before_each(function()
+      vim.notify = function() end
+    end)
+
+    after_each(function()
+      lib.golist.clear()
+      vim.uv.new_fs_event = new_fs_event
+      vim.notify = notify
+    end)
+
+    it("only watches the module and the directories used", function()
+      fake_watchers(false)
+      lib.cmd.golist_data(positions_filepath)
+
+      table.sort(started)
+      assert.are.same({ tests_filepath, positions_filepath }, started)
+    end)
+
+    it("runs go list again for a new package in a new directory", function()
+      fake_watchers(false)
+      lib.cmd.golist_data(positions_filepath)
+
+      local new_dir = tests_filepath .. "/internal/golistnew"
+      vim.fn.mkdir(new_dir .. "/pkg", "p")
+      vim.fn.writefile({
+        "package pkg",
+        "",
+        'import "testing"',
+        "",
+        "func TestNew(t *testing.T) {}",
+      }, new_dir .. "/pkg/new_test.go")
+      local output = lib.cmd.golist_data(new_dir)
+      vim.fn.delete(new_dir, "rf")
+
+      assert.are.equal(1, #output)
+      assert.are.equal(new_dir .. "/pkg", output[1].Dir)
+    end)
+
+    it("runs go list for every run when it can't watch", function()
+      fake_watchers(true)
+      local first = lib.cmd.golist_data(positions_filepath)
+      local second = lib.cmd.golist_data(positions_filepath)
+
+      assert.are.same({}, started)
+      assert.are_not.equal(first[1], second[1])
+      assert.are.same(first, second)
+    end)
+  end)
+
+  describe("with package errors", function()
+    local module_dir
+
+    before_each(function()
+      module_dir = path.normalize_path(vim.fn.tempname())
+      vim.fn.mkdir(module_dir .. "/broken", "p")
+      vim.fn.mkdir(module_dir .. "/fine", "p")
+      vim.fn.writefile({ "module example.com/m" }, module_dir .. "/go.mod")
+      vim.fn.writefile({
+        "package broken",
+        'import _ "nonexistent/pkg"',
+      }, module_dir .. "/broken/broken.go")
+      vim.fn.writefile({ "package fine" }, module_dir .. "/fine/fine.go")
+    end)
+
+    after_each(function()
+      vim.fn.delete(module_dir, "rf")
+    end)
+
+    it("reports errors per package", function()
+      local output, errors = lib.cmd.golist_data(module_dir)
+
+      assert.are.equal(2, #output)
+      assert.is_not_nil(errors)
+      assert.are.equal(1, #errors)
+      assert.is_truthy(errors[1]:match("^go list: example.com/m/broken: "))
+      assert.is_truthy(errors[1]:match("nonexistent/pkg"))
+    end)
+
+    it("only reports errors of packages in the directory", function()
+      local output, errors = lib.cmd.golist_data(module_dir .. "/fine")
+
+      assert.are.equal(1, #output)
+      assert.is_nil(errors)
+    end)
+  end)
+end)
@@ -0,0 +1,157 @@
+local _ = require("plenary")
+local helper_diagnostics = require("neotest-golang.lib.helper_diagnostics")
+local path = require("neotest-golang.lib.path")
+local run_state = require("neotest-golang.lib.run_state")
+
+local test_lines = {
+  "package handler",
+  "",
+  "func TestCreate(t *testing.T) {",
+  '\tt.Run("valid", func(t *testing.T) {',
+  "\t\tresp := create(t)",
+  "\t\ttestutil.AssertStatus(t, resp, 201)",
+  "\t})",
+  "}",
+  "",
+  "func TestDelete(t *testing.T) {",
+  "\ttestutil.AssertStatus(t, del(t), 204)",
+  "}",
+}
+
+describe("helper_diagnostics", function()
+  it("finds the function enclosing a line", function()
+    local lines = {
+      "package testutil",
+      "",
+      "func AssertStatus(t *testing.T, resp *Response, want int) {",
+      "\tif resp.Code != want {",
+      '\t\tt.Errorf("status %d, want %d", resp.Code, want)',
+      "\t}",
+      "}",
+      "",
+      "func (s *Server) check(t *testing.T) {",
+      '\tt.Error("boom")',
+      "}",
+    }
+    assert.are.equal(
+      "AssertStatus",
+      helper_diagnostics.enclosing_function(lines, 5)
+    )
+    assert.are.equal("check", helper_diagnostics.enclosing_function(lines, 10))
+    assert.is_nil(helper_diagnostics.enclosing_function(lines, 1))
+  end)
+
+  it("finds where the test calls the helper", function()
+    assert.are.equal(
+      6,
+      helper_diagnostics.call_site(
+        test_lines,
+        "TestCreate/valid",
+        "AssertStatus"
+      )
+    )
+    assert.are.equal(
+      11,
+      helper_diagnostics.call_site(test_lines, "TestDelete", "AssertStatus")
+    )
+  end)
+
+  it("falls back to the declaration of the test", function()
+    assert.are.equal(
+      3,
+      helper_diagnostics.call_site(test_lines, "TestCreate", "assertJSON")
+    )
+    assert.are.equal(
+      10,
+      helper_diagnostics.call_site(test_lines, "TestDelete", nil)
+    )
+    assert.is_nil(helper_diagnostics.call_site(test_lines, "TestMissing", nil))
+  end)
+
+  describe("resolve", function()
+    local root, unbind
+    local test_dir, util_dir, other_dir
+
+    before_each(function()
+      unbind = run_state.bind(run_state.new())
+      root = path.normalize_path(vim.fn.tempname())
+      test_dir = root .. "/handler"
+      util_dir = root .. "/testutil"
+      other_dir = root .. "/other"
+      for _, dir in ipairs({ test_dir, util_dir, other_dir }) do
+        vim.fn.mkdir(dir, "p")
+      end
+      vim.fn.writefile({ "package handler" }, test_dir .. "/common_test.go")
+      vim.fn.writefile({ "package testutil" }, util_dir .. "/common_test.go")
+      vim.fn.writefile({ "package testutil" }, util_dir .. "/assert.go")
+      vim.fn.writefile({ "package other" }, other_dir .. "/shared.go")
+      vim.fn.writefile({ "package testutil" }, util_dir .. "/shared.go")
+    end)
+
+    after_each(function()
+      unbind()
+      vim.fn.delete(root, "rf")
+    end)
+
+    local function golist_data()
+      return {
+        { ImportPath = "example.com/handler", Dir = test_dir },
+        { ImportPath = "example.com/testutil", Dir = util_dir },
+        { ImportPath = "example.com/other", Dir = other_dir },
+      }
+    end
+
+    it("prefers the package of the test", function()
+      assert.are.equal(
+        test_dir .. "/common_test.go",
+        helper_diagnostics.resolve("common_test.go", test_dir, golist_data())
+      )
+    end)
+
+    it("finds the file in another package", function()
+      assert.are.equal(
+        util_dir .. "/assert.go",
+        helper_diagnostics.resolve("assert.go", test_dir, golist_data())
+      )
+    end)
+
+    it("skips a file name which several packages have", function()
+      assert.is_nil(
+        helper_diagnostics.resolve("shared.go", test_dir, golist_data())
+      )
+    end)
+
+    it("keeps absolute paths of the packages and their module", function()
+      local data = golist_data()
+      data[1].Module = { GoMod = root .. "/go.mod" }
+      vim.fn.mkdir(root .. "/internal", "p")
+      vim.fn.writefile({ "package internal" }, root .. "/internal/check.go")
+
+      assert.are.equal(
+        util_dir .. "/assert.go",
+        helper_diagnostics.resolve(util_dir .. "/assert.go", test_dir, data)
+      )
+      assert.are.equal(
+        root .. "/internal/check.go",
+        helper_diagnostics.resolve(root .. "/internal/check.go", test_dir, data)
+      )
+    end)
+
+    it("skips absolute paths outside of the run", function()
+      local goroot = path.normalize_path(vim.fn.tempname())
+      vim.fn.mkdir(goroot .. "/src/testing", "p")
+      vim.fn.writefile(
+        { "package testing" },
+        goroot .. "/src/testing/testing.go"
+      )
+
+      local resolved = helper_diagnostics.resolve(
+        goroot .. "/src/testing/testing.go",
+        test_dir,
+        golist_data()
+      )
+      vim.fn.delete(goroot, "rf")
+      assert.is_nil(resolved)
+    end)
+  end)
+end)
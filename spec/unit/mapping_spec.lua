@@ -1,6 +1,16 @@
 local lib = require("neotest-golang.lib")
 
 describe("mapping module", function()
+  local unbind
+
+  before_each(function()
+    unbind = lib.run_state.bind(lib.run_state.new())
+  end)
+
+  after_each(function()
+    unbind()
+  end)
+
   describe("get_position_id", function()
     local lookup_table
 
@@ -15,15 +15,31 @@ describe("Options are set up", function()
       dap_go_opts = {},
       dap_mode = "dap-go",
       dap_manual_config = {},
+      dap_dlv_opts = {},
       env = {},
       filter_dirs = { ".git", "node_modules", ".venv", "venv" },
       filter_dir_patterns = {},
       testify_enabled = false,
       testify_import_identifier = "^(suite)$",
+      diagnostic_parsers = {
+        "testify",
+        "gotest_tools",
+        "quicktest",
+        "is",
+        "gomega",
+        "gomock",
+      },
+      diagnostic_rules = {},
+      show_log_hints = true,
       colorize_test_output = true,
       warn_test_name_dupes = true,
       log_level = vim.log.levels.WARN,
       sanitize_output = false,
+      slow_test_threshold = 0,
+      export_results = {},
+      replay_path_mappings = {},
+      prebuilt_test_binaries = false,
+      persistent_discovery_cache = false,
 
       -- experimental
       dev_notifications = false,
@@ -47,15 +63,31 @@ describe("Options are set up", function()
       dap_go_opts = {},
       dap_mode = "dap-go",
       dap_manual_config = {},
+      dap_dlv_opts = {},
       env = {},
       filter_dirs = {},
       filter_dir_patterns = {},
       testify_enabled = false,
       testify_import_identifier = "^(suite)$",
+      diagnostic_parsers = {
+        "testify",
+        "gotest_tools",
+        "quicktest",
+        "is",
+        "gomega",
+        "gomock",
+      },
+      diagnostic_rules = {},
+      show_log_hints = true,
       colorize_test_output = false,
       warn_test_name_dupes = true,
       log_level = vim.log.levels.WARN,
       sanitize_output = false,
+      slow_test_threshold = 0,
+      export_results = {},
+      replay_path_mappings = {},
+      prebuilt_test_binaries = false,
+      persistent_discovery_cache = false,
 
       -- experimental
       dev_notifications = false,
@@ -87,6 +119,9 @@ describe("Options are set up", function()
       dap_manual_config = function()
         return {}
       end,
+      dap_dlv_opts = function()
+        return {}
+      end,
       env = function()
         return {}
       end,
@@ -98,10 +133,25 @@ describe("Options are set up", function()
       end,
       testify_enabled = false,
       testify_import_identifier = "^(suite)$",
+      diagnostic_parsers = {
+        "testify",
+        "gotest_tools",
+        "quicktest",
+        "is",
+        "gomega",
+        "gomock",
+      },
+      diagnostic_rules = {},
+      show_log_hints = true,
       colorize_test_output = true,
       warn_test_name_dupes = true,
       log_level = vim.log.levels.WARN,
       sanitize_output = false,
+      slow_test_threshold = 0,
+      export_results = {},
+      replay_path_mappings = {},
+      prebuilt_test_binaries = false,
+      persistent_discovery_cache = false,
 
       -- experimental
       runner = "go",
@@ -114,4 +164,29 @@ describe("Options are set up", function()
     options.setup(expected_options)
     assert.are_same(expected_options, options.get())
   end)
+
+  it("Without diagnostic rules of invalid patterns", function()
+    local notify = vim.notify
+    local warnings = {}
+    vim.notify = function(msg)
+      table.insert(warnings, msg)
+    end
+
+    options.set({
+      diagnostic_rules = {
+        { pattern = "foo(", severity = "hint" },
+        { pattern = "^retrying", severity = "info" },
+        { pattern = "error|fail", severity = "warn" },
+      },
+    })
+    vim.notify = notify
+    local rules = options.get().diagnostic_rules
+    options.set({ diagnostic_rules = {} })
+
+    assert.are_same({
+      { pattern = "^retrying", severity = "info" },
+      { pattern = "error|fail", severity = "warn" },
+    }, rules)
+    assert.are.equal(1, #warnings)
+  end)
 end)
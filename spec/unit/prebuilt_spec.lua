@@ -0,0 +1,172 @@
+local _ = require("plenary")
+local prebuilt = require("neotest-golang.lib.prebuilt")
+
+describe("prebuilt", function()
+  local module_dir
+
+  before_each(function()
+    module_dir = vim.fn.tempname()
+    vim.fn.mkdir(module_dir .. "/pkg/testdata", "p")
+    vim.fn.mkdir(module_dir .. "/dep", "p")
+    vim.fn.writefile(
+      { "module example.com/m", "", "go 1.21" },
+      module_dir .. "/go.mod"
+    )
+    vim.fn.writefile(
+      { "package dep", "", "func Answer() int { return 42 }" },
+      module_dir .. "/dep/dep.go"
+    )
+    vim.fn.writefile({
+      "package pkg",
+      "",
+      'import "example.com/m/dep"',
+      "",
+      "func Answer() int { return dep.Answer() }",
+    }, module_dir .. "/pkg/pkg.go")
+  end)
+
+  after_each(function()
+    vim.fn.delete(module_dir, "rf")
+  end)
+
+  it("keeps the key when nothing changed", function()
+    local dir = module_dir .. "/pkg"
+    assert.are.equal(prebuilt.key(dir, {}), prebuilt.key(dir, {}))
+  end)
+
+  it("changes the key when the package changes", function()
+    local dir = module_dir .. "/pkg"
+    local before = prebuilt.key(dir, {})
+    vim.fn.writefile(
+      { "package pkg", "", "func Question() string { return \"?\" }" },
+      dir .. "/question.go"
+    )
+    assert.are_not.equal(before, prebuilt.key(dir, {}))
+  end)
+
+  it("changes the key when a dependency changes", function()
+    local dir = module_dir .. "/pkg"
+    local before = prebuilt.key(dir, {})
+    vim.fn.writefile(
+      { "package dep", "", "func Answer() int { return 41 }" },
+      module_dir .. "/dep/dep.go"
+    )
+    assert.are_not.equal(before, prebuilt.key(dir, {}))
+  end)
+
+  it("keeps the key when another package of the module changes", function()
+    local dir = module_dir .. "/pkg"
+    local before = prebuilt.key(dir, {})
+    vim.fn.writefile({ "package m" }, module_dir .. "/other.go")
+    assert.are.equal(before, prebuilt.key(dir, {}))
+  end)
+
+  it("changes the key when the build flags change", function()
+    local dir = module_dir .. "/pkg"
+    assert.are_not.equal(
+      prebuilt.key(dir, {}),
+      prebuilt.key(dir, { "-tags=integration" })
+    )
+  end)
+
+  it("changes the key when the environment changes", function()
+    local dir = module_dir .. "/pkg"
+    assert.are_not.equal(
+      prebuilt.key(dir, {}),
+      prebuilt.key(dir, {}, { CGO_ENABLED = "0" })
+    )
+  end)
+
+  it("ignores Go files in testdata", function()
+    local dir = module_dir .. "/pkg"
+    local before = prebuilt.key(dir, {})
+    vim.fn.writefile({ "package x" }, dir .. "/testdata/x.go")
+    assert.are.equal(before, prebuilt.key(dir, {}))
+  end)
+
+  it("has no key when the package does not compile", function()
+    local dir = module_dir .. "/pkg"
+    vim.fn.writefile({ "package pkg", "", "func {" }, dir .. "/broken.go")
+    local key, err = prebuilt.key(dir, {})
+    assert.is_nil(key)
+    assert.is_truthy(err)
+  end)
+
+  it("names the binary after the package directory and key", function()
+    local key = string.rep("a", 64)
+    local binary = prebuilt.binary_path(module_dir .. "/pkg", {}, key)
+    assert.is_truthy(
+      vim.fs.basename(binary):match(
+        "^pkg%-%x+%-" .. key:sub(1, 16) .. "%.test$"
+      )
+    )
+    assert.are.equal(prebuilt.cache_dir(), vim.fs.dirname(binary))
+  end)
+
+  it("keeps the binaries of other build flags apart", function()
+    local dir = module_dir .. "/pkg"
+    local key = string.rep("a", 64)
+    local debuggable = prebuilt.binary_path(dir, { "-gcflags=all=-N -l" }, key)
+    assert.are_not.equal(prebuilt.binary_path(dir, {}, key), debuggable)
+  end)
+
+  it("builds the binary once and moves it into place", function()
+    local dir = module_dir .. "/pkg"
+    vim.fn.writefile({
+      "package pkg",
+      "",
+      'import "testing"',
+      "",
+      "func TestAnswer(t *testing.T) {}",
+    }, dir .. "/pkg_test.go")
+
+    local binary, err = prebuilt.ensure(dir, {}, nil)
+    assert.is_not_nil(binary, err)
+    assert.is_not_nil(vim.uv.fs_stat(binary))
+    local leftovers = vim.tbl_filter(function(name)
+      return vim.endswith(name, ".tmp")
+    end, vim.fn.readdir(prebuilt.cache_dir()))
+    assert.are.same({}, leftovers)
+    os.remove(binary)
+  end)
+end)
+
+describe("prebuilt command", function()
+  local go_test_args = require("neotest-golang.features.dap.go_test_args")
+  local options = require("neotest-golang.options")
+  local run_state = require("neotest-golang.lib.run_state")
+  local runspec_prebuilt = require("neotest-golang.runspec.prebuilt")
+  local ensure = prebuilt.ensure
+  local unbind
+
+  before_each(function()
+    prebuilt.ensure = function()
+      return "/tmp/pkg.test", nil
+    end
+    unbind = run_state.bind(run_state.new())
+  end)
+
+  after_each(function()
+    unbind()
+    prebuilt.ensure = ensure
+    options.set({ go_test_args = { "-v", "-race", "-count=1" } })
+  end)
+
+  local function command()
+    return runspec_prebuilt.command("/repo/pkg", {
+      { Dir = "/repo/pkg", ImportPath = "example.com/pkg" },
+    }, "^TestAnswer$")
+  end
+
+  it("times out after the default timeout of go test", function()
+    options.set({ go_test_args = { "-count=1" } })
+    assert.is_true(vim.tbl_contains(command(), "-test.timeout=10m"))
+  end)
+
+  it("keeps the timeout of the go test args", function()
+    options.set({ go_test_args = { "-count=1", "-timeout=30s" } })
+    local cmd = command()
+    assert.is_true(vim.tbl_contains(cmd, "-test.timeout=30s"))
+    assert.is_false(vim.tbl_contains(cmd, "-test.timeout=10m"))
+  end)
+end)
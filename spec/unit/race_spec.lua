@@ -0,0 +1,167 @@
+local _ = require("plenary")
+local file_diagnostics = require("neotest-golang.lib.file_diagnostics")
+local race = require("neotest-golang.lib.race")
+local run_state = require("neotest-golang.lib.run_state")
+local stack = require("neotest-golang.lib.stack")
+
+local test_file = "/repo/pkg/counter_test.go"
+local source_file = "/repo/pkg/counter.go"
+
+local report_lines = {
+  "==================",
+  "WARNING: DATA RACE",
+  "Write at 0x00c00001c0b8 by goroutine 8:",
+  "  example.com/repo/pkg.(*Counter).Inc()",
+  "      " .. source_file .. ":12 +0x44",
+  "  example.com/repo/pkg.TestCounter.func1()",
+  "      " .. test_file .. ":15 +0x30",
+  "",
+  "Previous read at 0x00c00001c0b8 by main goroutine:",
+  "  example.com/repo/pkg.(*Counter).Value()",
+  "      " .. source_file .. ":16 +0x3a",
+  "  example.com/repo/pkg.TestCounter()",
+  "      " .. test_file .. ":18 +0x9c",
+  "  testing.tRunner()",
+  "      /usr/local/go/src/testing/testing.go:1690 +0xf4",
+  "",
+  "Goroutine 8 (running) created at:",
+  "  example.com/repo/pkg.TestCounter()",
+  "      " .. test_file .. ":14 +0x84",
+  "  testing.tRunner()",
+  "      /usr/local/go/src/testing/testing.go:1690 +0xf4",
+  "==================",
+  "    testing.go:1399: race detected during execution of test",
+}
+
+describe("stack", function()
+  it("parses frames until the stack ends", function()
+    local frames, next_i = stack.parse_frames(report_lines, 4)
+    assert.are.same({
+      {
+        func = "example.com/repo/pkg.(*Counter).Inc",
+        filename = source_file,
+        line_number = 12,
+      },
+      {
+        func = "example.com/repo/pkg.TestCounter.func1",
+        filename = test_file,
+        line_number = 15,
+      },
+    }, frames)
+    assert.are.equal(8, next_i)
+  end)
+
+  it("drops call arguments", function()
+    assert.are.equal(
+      "example.com/repo/pkg.worker",
+      stack.parse_function("example.com/repo/pkg.worker(0xc000012345, 0x1)")
+    )
+  end)
+
+  it("skips standard library frames", function()
+    local frame = stack.first_user_frame({
+      {
+        func = "sync.(*Mutex).Lock",
+        filename = "/usr/local/go/src/sync/mutex.go",
+        line_number = 90,
+      },
+      {
+        func = "mymod/pkg.F",
+        filename = "/home/me/mymod/pkg/f.go",
+        line_number = 3,
+      },
+    })
+    assert.are.equal("mymod/pkg.F", frame.func)
+  end)
+end)
+
+describe("race", function()
+  local unbind
+
+  before_each(function()
+    unbind = run_state.bind(run_state.new())
+    file_diagnostics.reset()
+  end)
+
+  after_each(function()
+    unbind()
+  end)
+
+  it("parses the accesses and goroutine creation sites", function()
+    local reports = race.parse(report_lines)
+    assert.are.equal(1, #reports)
+
+    local report = reports[1]
+    assert.are.equal("Write", report.current.action)
+    assert.are.equal("goroutine 8", report.current.goroutine)
+    assert.are.equal(2, #report.current.frames)
+    assert.are.equal("Previous read", report.previous.action)
+    assert.are.equal("main goroutine", report.previous.goroutine)
+    assert.are.equal(3, #report.previous.frames)
+    assert.are.equal(1, #report.created)
+    assert.are.equal("Goroutine 8 (running)", report.created[1].goroutine)
+    assert.are.equal(14, report.created[1].frames[1].line_number)
+  end)
+
+  it("summarizes the raced variable", function()
+    local report = race.parse(report_lines)[1]
+    assert.are.equal(
+      "DATA RACE on (*Counter).Inc: write at counter.go:12 by goroutine 8, "
+        .. "previous read at counter.go:16 by main goroutine",
+      race.summary(report)
+    )
+
+    local lines = vim.list_slice(report_lines, 1, 21)
+    table.insert(lines, 3, "Location is global 'total' of size 8 at 0x01")
+    assert.are.equal("total", race.subject(race.parse(lines)[1]))
+  end)
+
+  it("keeps diagnostics in the test file and publishes the others", function()
+    local reports = race.parse(report_lines)
+    local errors = race.process(reports, test_file .. "::TestCounter")
+
+    assert.are.same({
+      {
+        line = 13,
+        message = "DATA RACE on (*Counter).Inc: Goroutine 8 (running) created here",
+        severity = vim.diagnostic.severity.WARN,
+      },
+      {
+        line = 14,
+        message = race.summary(reports[1]),
+        severity = vim.diagnostic.severity.ERROR,
+      },
+      {
+        line = 17,
+        message = race.summary(reports[1]),
+        severity = vim.diagnostic.severity.ERROR,
+      },
+    }, errors)
+
+    local published = file_diagnostics.get()[source_file]
+    assert.are.equal(2, #published)
+    assert.are.equal(12, published[1].line_number)
+    assert.are.equal(16, published[2].line_number)
+    assert.are.equal(vim.diagnostic.severity.ERROR, published[1].severity)
+  end)
+
+  it("puts the summary once on a line which both accesses pass", function()
+    local lines = vim.deepcopy(report_lines)
+    -- Both accesses happen in a helper called from the same test line
+    lines[7] = "      " .. test_file .. ":18 +0x30"
+    local reports = race.parse(lines)
+    local errors = race.process(reports, test_file .. "::TestCounter")
+
+    local summaries = vim.tbl_filter(function(err)
+      return err.line == 17
+    end, errors)
+    assert.are.equal(1, #summaries)
+  end)
+
+  it("ignores output without race reports", function()
+    assert.are.same(
+      {},
+      race.parse_parts({ "=== RUN   TestCounter\n", "--- PASS: TestCounter\n" })
+    )
+  end)
+end)
@@ -0,0 +1,109 @@
+local _ = require("plenary")
+local options = require("neotest-golang.options")
+local replay = require("neotest-golang.lib.replay")
+
+describe("replay", function()
+  before_each(function()
+    options.set({ replay_path_mappings = {} })
+  end)
+
+  describe("parse_path_mappings", function()
+    it("parses from=to arguments", function()
+      local mappings, invalid = replay.parse_path_mappings({
+        "/home/runner/work/repo/repo=/home/me/repo",
+        "C:\\a=/b",
+      })
+      assert.are.same({
+        ["/home/runner/work/repo/repo"] = "/home/me/repo",
+        ["C:\\a"] = "/b",
+      }, mappings)
+      assert.are.same({}, invalid)
+    end)
+
+    it("reports arguments which are not mappings", function()
+      local mappings, invalid =
+        replay.parse_path_mappings({ "nope", "=/b", "/a=" })
+      assert.are.same({}, mappings)
+      assert.are.same({ "nope", "=/b", "/a=" }, invalid)
+    end)
+  end)
+
+  describe("get_path_mappings", function()
+    it("lets given mappings take precedence over configured ones", function()
+      options.set({
+        replay_path_mappings = function()
+          return { ["/ci"] = "/configured", ["/other"] = "/x" }
+        end,
+      })
+      assert.are.same(
+        { ["/ci"] = "/given", ["/other"] = "/x" },
+        replay.get_path_mappings({ ["/ci"] = "/given" })
+      )
+    end)
+  end)
+
+  describe("remap_text", function()
+    it("replaces every occurrence, longest prefix first", function()
+      local text = "/ci/repo/a_test.go:1 /ci/b.go:2 /ci/repo/c.go:3"
+      assert.are.equal(
+        "/local/a_test.go:1 /elsewhere/b.go:2 /local/c.go:3",
+        replay.remap_text(
+          text,
+          { ["/ci"] = "/elsewhere", ["/ci/repo"] = "/local" }
+        )
+      )
+    end)
+
+    it("treats prefixes as plain text", function()
+      assert.are.equal(
+        "/local/x.go",
+        replay.remap_text("/w%(.)/x.go", { ["/w%(.)"] = "/local" })
+      )
+    end)
+  end)
+
+  describe("remap_lines", function()
+    it("remaps output of JSON events and other lines", function()
+      local lines = replay.remap_lines({
+        '{"Action":"output","Output":"Error Trace: /ci/repo/a_test.go:12\\n","Package":"pkg"}',
+        "# pkg",
+        "/ci/repo/a.go:3:1: undefined: x",
+      }, { ["/ci/repo"] = "/local" })
+
+      assert.are.same({
+        Action = "output",
+        Package = "pkg",
+        Output = "Error Trace: /local/a_test.go:12\n",
+      }, vim.json.decode(lines[1]))
+      assert.are.equal("# pkg", lines[2])
+      assert.are.equal("/local/a.go:3:1: undefined: x", lines[3])
+    end)
+
+    it("returns lines untouched without mappings", function()
+      local lines = { '{"Action":"run"}', "text" }
+      assert.are.same(lines, replay.remap_lines(lines, {}))
+    end)
+  end)
+
+  describe("exit_code", function()
+    it("is non-zero when anything failed", function()
+      assert.are.equal(
+        1,
+        replay.exit_code({
+          { Action = "pass", Package = "a" },
+          { Action = "fail", Package = "b", Test = "TestB" },
+        })
+      )
+    end)
+
+    it("is zero when nothing failed", function()
+      assert.are.equal(
+        0,
+        replay.exit_code({
+          { Action = "pass", Package = "a" },
+          { Action = "skip", Package = "b", Test = "TestB" },
+        })
+      )
+    end)
+  end)
+end)
@@ -1,4 +1,5 @@
 local _ = require("plenary")
+local run_state = require("neotest-golang.lib.run_state")
 local options = require("neotest-golang.options")
 local results_finalize = require("neotest-golang.results_finalize")
 
@@ -23,6 +24,7 @@ describe("results_finalize.test_results", function()
           pos_id = pos_id,
           golist_data = {},
           skipped = true,
+          state = run_state.new(pos_id),
           stop_filestream = function() end,
         },
       }
@@ -0,0 +1,92 @@
+local _ = require("plenary")
+local extra_args = require("neotest-golang.lib.extra_args")
+local run_state = require("neotest-golang.lib.run_state")
+
+describe("run state", function()
+  it("raises outside of a run", function()
+    run_state.new("/repo/pkg/file_test.go", { a = 1 })
+
+    -- A coroutine which no state is bound to, like a command.
+    local ok = coroutine.wrap(function()
+      return pcall(extra_args.get)
+    end)()
+
+    assert.is_false(ok)
+  end)
+
+  it("is unbound once the runspec is built, also when it raises", function()
+    local adapter = require("neotest-golang")
+    local tree = {
+      data = function()
+        return { id = "/repo/pkg/file_test.go::Ns", type = "namespace" }
+      end,
+    }
+    local notify = vim.notify
+    vim.notify = function() end
+
+    local ok = pcall(adapter.build_spec, { tree = tree })
+    vim.notify = notify
+
+    assert.is_false(ok)
+    assert.is_false(pcall(run_state.current))
+  end)
+
+  it("is bound to the coroutine which works on the run", function()
+    local first = coroutine.create(function()
+      run_state.bind(run_state.new("first", { replay = "first.json" }))
+      coroutine.yield()
+      return extra_args.get().replay
+    end)
+    local second = coroutine.create(function()
+      run_state.bind(run_state.new("second", { replay = "second.json" }))
+      coroutine.yield()
+      return extra_args.get().replay
+    end)
+
+    -- Interleave the runs, the second one is started last.
+    coroutine.resume(first)
+    coroutine.resume(second)
+    local _, first_replay = coroutine.resume(first)
+    local _, second_replay = coroutine.resume(second)
+
+    assert.are.equal("first.json", first_replay)
+    assert.are.equal("second.json", second_replay)
+  end)
+
+  it("restores the bound state after working on another run", function()
+    local state = run_state.new("first")
+    local unbind = run_state.bind(state)
+    local other = run_state.new("second")
+
+    local pos_id = run_state.with(other, function()
+      return run_state.current().pos_id
+    end)
+
+    assert.are.equal("second", pos_id)
+    assert.are.equal(state, run_state.current())
+    unbind()
+  end)
+
+  it("restores the bound state when the function raises", function()
+    local state = run_state.new("first")
+    local unbind = run_state.bind(state)
+    local other = run_state.new("second")
+
+    local ok, err = pcall(run_state.with, other, function()
+      error("boom", 0)
+    end)
+
+    assert.is_false(ok)
+    assert.are.equal("boom", err)
+    assert.are.equal(state, run_state.current())
+    unbind()
+  end)
+
+  it("keeps the state of the last finished run for commands", function()
+    local finished = run_state.new("finished")
+    run_state.finish(finished)
+    run_state.new("started")
+
+    assert.are.equal(finished, run_state.last_finished())
+  end)
+end)
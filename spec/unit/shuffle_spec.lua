@@ -0,0 +1,119 @@
+local _ = require("plenary")
+local cmd = require("neotest-golang.lib.cmd")
+local extra_args = require("neotest-golang.lib.extra_args")
+local results_stream = require("neotest-golang.results_stream")
+local run_state = require("neotest-golang.lib.run_state")
+local shuffle = require("neotest-golang.lib.shuffle")
+
+describe("shuffle", function()
+  local unbind
+
+  before_each(function()
+    unbind = run_state.bind(run_state.new())
+    shuffle.reset(nil)
+  end)
+
+  after_each(function()
+    unbind()
+  end)
+
+  describe("parse_seed", function()
+    it("parses the seed printed by a shuffled test binary", function()
+      assert.are.equal(
+        "1700000000000000000",
+        shuffle.parse_seed("-test.shuffle 1700000000000000000\n")
+      )
+      assert.are.equal("-5", shuffle.parse_seed("-test.shuffle -5\n"))
+    end)
+
+    it("ignores other output", function()
+      assert.is_nil(shuffle.parse_seed("=== RUN   TestShuffle\n"))
+      assert.is_nil(shuffle.parse_seed("    -test.shuffle 1\n"))
+    end)
+  end)
+
+  describe("process_package", function()
+    it("records the seed with the package status", function()
+      shuffle.reset("/repo/pkg/file_test.go")
+      local pkg = "example.com/repo/pkg"
+      local golist_data = { { ImportPath = pkg, Dir = "/repo/pkg" } }
+      local accum = {}
+      for _, e in ipairs({
+        { Action = "start", Package = pkg },
+        { Action = "output", Package = pkg, Output = "-test.shuffle 42\n" },
+        { Action = "fail", Package = pkg, Elapsed = 0.1 },
+      }) do
+        accum = results_stream.process_package(golist_data, accum, e, pkg)
+      end
+
+      assert.are.equal("42", accum[pkg].metadata.shuffle_seed)
+      assert.are.same({
+        pos_id = "/repo/pkg/file_test.go",
+        seeds = { { package = pkg, seed = "42", status = "failed" } },
+      }, shuffle.get())
+    end)
+
+    it("keeps the seed when a later run is not shuffled", function()
+      local pkg = "example.com/repo/pkg"
+      local golist_data = { { ImportPath = pkg, Dir = "/repo/pkg" } }
+      local function run(pos_id, events)
+        run_state.with(run_state.new(pos_id), function()
+          local accum = {}
+          for _, e in ipairs(events) do
+            accum = results_stream.process_package(golist_data, accum, e, pkg)
+          end
+        end)
+      end
+
+      run("/repo/pkg/file_test.go", {
+        { Action = "start", Package = pkg },
+        { Action = "output", Package = pkg, Output = "-test.shuffle 42\n" },
+        { Action = "fail", Package = pkg, Elapsed = 0.1 },
+      })
+      run("/repo/pkg", {
+        { Action = "start", Package = pkg },
+        { Action = "pass", Package = pkg, Elapsed = 0.1 },
+      })
+
+      assert.are.same({
+        pos_id = "/repo/pkg/file_test.go",
+        seeds = { { package = pkg, seed = "42", status = "failed" } },
+      }, shuffle.get())
+    end)
+  end)
+
+  describe("candidates", function()
+    it("prefers the seeds of failed packages", function()
+      local seeds = {
+        { package = "a", seed = "1", status = "passed" },
+        { package = "b", seed = "2", status = "failed" },
+        { package = "c", seed = "2", status = "failed" },
+      }
+      assert.are.same(
+        { { package = "b", seed = "2", status = "failed" } },
+        shuffle.candidates(seeds)
+      )
+    end)
+
+    it("falls back to all seeds when nothing failed", function()
+      local seeds = {
+        { package = "a", seed = "1", status = "passed" },
+        { package = "b", seed = "2", status = "passed" },
+      }
+      assert.are.same(seeds, shuffle.candidates(seeds))
+    end)
+  end)
+
+  describe("shuffle_args", function()
+    it("is empty unless shuffling was requested", function()
+      assert.are.same({}, cmd.shuffle_args())
+    end)
+
+    it("passes on or a seed", function()
+      extra_args.set({ shuffle = true })
+      assert.are.same({ "-shuffle=on" }, cmd.shuffle_args())
+      extra_args.set({ shuffle = "42" })
+      assert.are.same({ "-shuffle=42" }, cmd.shuffle_args())
+    end)
+  end)
+end)
@@ -1,4 +1,5 @@
 local options = require("neotest-golang.options")
+local run_state = require("neotest-golang.lib.run_state")
 local stream = require("neotest-golang.lib.stream")
 
 local package_import = "example.com/repo/pkg"
@@ -58,22 +59,31 @@ local function make_tree()
   }
 end
 
+--- Remove the output files of the results of a run.
+--- @param state RunState
+local function remove_outputs(state)
+  for _, result in pairs(stream.cached_results(state)) do
+    if result.output and vim.uv.fs_stat(result.output) then
+      vim.uv.fs_unlink(result.output)
+    end
+  end
+end
+
 describe("streaming results", function()
+  local state, unbind
+
   before_each(function()
     options.setup({
       runner = "go",
       performance_monitoring = false,
     })
-    stream.cached_results = {}
+    state = run_state.new()
+    unbind = run_state.bind(state)
   end)
 
   after_each(function()
-    for _, result in pairs(stream.cached_results) do
-      if result.output and vim.uv.fs_stat(result.output) then
-        vim.uv.fs_unlink(result.output)
-      end
-    end
-    stream.cached_results = {}
+    remove_outputs(state)
+    unbind()
   end)
 
   it(
@@ -115,4 +125,87 @@ describe("streaming results", function()
       assert.are_same("failed", second_results[file_path .. "::TestTwo"].status)
     end
   )
+
+  it("adds the elapsed time to output which is processed at once", function()
+    -- Arrange: output of a debugged test binary, converted by test2json.
+    local lines = {
+      make_event("run", "TestOne"),
+      make_event("output", "TestOne", "=== RUN   TestOne\n"),
+      vim.json.encode({
+        Action = "pass",
+        Package = package_import,
+        Test = "TestOne",
+        Elapsed = 0.52,
+      }),
+      make_event("run", "TestTwo"),
+      vim.json.encode({
+        Action = "fail",
+        Package = package_import,
+        Test = "TestTwo",
+        Elapsed = 1.5,
+      }),
+    }
+
+    -- Act
+    local results = stream.process_lines(make_tree(), {
+      { ImportPath = package_import, Dir = package_dir },
+    }, lines, state)
+
+    -- Assert: the same header and short text as with streamed output.
+    local first = results[file_path .. "::TestOne"]
+    assert.are_same("passed in 0.52s", first.short)
+    assert.are_same(
+      "=== Elapsed: 0.52s ===",
+      vim.fn.readfile(first.output)[1]
+    )
+    local second = results[file_path .. "::TestTwo"]
+    assert.are_same("failed in 1.50s", second.short)
+    assert.are_same(
+      { "=== Elapsed: 1.50s ===" },
+      vim.fn.readfile(second.output)
+    )
+  end)
+
+  it("keeps the results of overlapping runs apart", function()
+    -- Arrange: two runs, e.g. a file run and a nearest-test run.
+    local golist_data = { { ImportPath = package_import, Dir = package_dir } }
+    local first_state = run_state.new(file_path)
+    local first_lines = {}
+    local first_stream = run_state.with(first_state, function()
+      return stream.new(make_tree(), golist_data)(function()
+        return first_lines
+      end)
+    end)
+
+    local second_state = run_state.new(file_path .. "::TestTwo")
+    local second_lines = {}
+    local second_stream = run_state.with(second_state, function()
+      return stream.new(make_tree(), golist_data)(function()
+        return second_lines
+      end)
+    end)
+
+    -- Act: the output of both runs streams in interleaved.
+    first_lines = {
+      make_event("run", "TestOne"),
+      make_event("pass", "TestOne"),
+    }
+    local first_results = first_stream()
+    second_lines = {
+      make_event("run", "TestTwo"),
+      make_event("fail", "TestTwo"),
+    }
+    local second_results = second_stream()
+
+    -- Assert: each run only holds its own results.
+    assert.are.equal(first_state.cached_results, first_results)
+    assert.are.equal(second_state.cached_results, second_results)
+    assert.are_same("passed", first_results[file_path .. "::TestOne"].status)
+    assert.is_nil(first_results[file_path .. "::TestTwo"])
+    assert.are_same("failed", second_results[file_path .. "::TestTwo"].status)
+    assert.is_nil(second_results[file_path .. "::TestOne"])
+
+    remove_outputs(first_state)
+    remove_outputs(second_state)
+  end)
 end)
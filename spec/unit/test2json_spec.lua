@@ -0,0 +1,75 @@
+local _ = require("plenary")
+local test2json = require("neotest-golang.lib.test2json")
+
+describe("test2json", function()
+  it("replaces the verbosity arguments of the test binary", function()
+    assert.are.same(
+      { "-test.run", "^TestAdd$", "-test.count=1", "-test.v=test2json" },
+      test2json.with_verbose_arg({
+        "-test.run",
+        "^TestAdd$",
+        "-test.v",
+        "-test.count=1",
+      })
+    )
+  end)
+
+  it("runs a test binary through test2json", function()
+    local cmd = test2json.binary_command(
+      "example.com/pkg",
+      "/tmp/pkg.test",
+      "^TestAdd$",
+      { "-test.v", "-test.count=1" }
+    )
+    assert.are.same({
+      "go",
+      "tool",
+      "test2json",
+      "-p",
+      "example.com/pkg",
+      "-t",
+      "/tmp/pkg.test",
+      "-test.run",
+      "^TestAdd$",
+      "-test.count=1",
+      "-test.v=test2json",
+    }, cmd)
+  end)
+
+  it("converts framed output into go test -json events", function()
+    local lines = test2json.convert({
+      "\22=== RUN   TestAdd",
+      "    add_test.go:9: got 3, want 4",
+      "\22--- FAIL: TestAdd (0.00s)",
+      "\22FAIL",
+    }, "example.com/pkg")
+
+    local events = vim.tbl_map(vim.json.decode, lines)
+    local actions = vim.tbl_map(function(event)
+      return (event.Test or "") .. ":" .. event.Action
+    end, events)
+    assert.is_true(vim.tbl_contains(actions, "TestAdd:run"))
+    assert.is_true(vim.tbl_contains(actions, "TestAdd:fail"))
+    assert.is_true(vim.tbl_contains(actions, ":fail"))
+    assert.are.equal("example.com/pkg", events[1].Package)
+  end)
+
+  it("converts within an async task", function()
+    local nio = require("nio")
+    local lines = {}
+
+    nio.tests.with_async_context(function()
+      lines = test2json.convert({
+        "\22=== RUN   TestAdd",
+        "\22--- PASS: TestAdd (0.00s)",
+        "\22PASS",
+      }, "example.com/pkg")
+    end)
+
+    local actions = vim.tbl_map(function(line)
+      local event = vim.json.decode(line)
+      return (event.Test or "") .. ":" .. event.Action
+    end, lines)
+    assert.is_true(vim.tbl_contains(actions, "TestAdd:pass"))
+  end)
+end)
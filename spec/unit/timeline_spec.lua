@@ -0,0 +1,110 @@
+local _ = require("plenary")
+local run_state = require("neotest-golang.lib.run_state")
+local timeline = require("neotest-golang.lib.timeline")
+
+describe("timeline.parse_time", function()
+  it("parses go test -json timestamps", function()
+    assert.are.equal(0, timeline.parse_time("1970-01-01T00:00:00Z"))
+    assert.are.equal(
+      1714564800.5,
+      timeline.parse_time("2024-05-01T12:00:00.5Z")
+    )
+  end)
+
+  it("applies the timezone offset", function()
+    assert.are.equal(
+      timeline.parse_time("2024-05-01T10:00:00.25Z"),
+      timeline.parse_time("2024-05-01T12:00:00.25+02:00")
+    )
+    assert.are.equal(
+      timeline.parse_time("2024-05-01T17:30:00Z"),
+      timeline.parse_time("2024-05-01T12:00:00-05:30")
+    )
+  end)
+
+  it("returns nil for missing or invalid timestamps", function()
+    assert.is_nil(timeline.parse_time(nil))
+    assert.is_nil(timeline.parse_time("yesterday"))
+  end)
+end)
+
+describe("timeline.segments", function()
+  it("splits a parallel test into running and paused segments", function()
+    local events = {}
+    timeline.append(
+      events,
+      { Action = "run", Time = "2024-05-01T12:00:00Z", Test = "TestA" }
+    )
+    timeline.append(events, {
+      Action = "output",
+      Time = "2024-05-01T12:00:00Z",
+      Output = "=== RUN   TestA\n",
+    })
+    timeline.append(events, { Action = "pause", Time = "2024-05-01T12:00:01Z" })
+    timeline.append(events, { Action = "cont", Time = "2024-05-01T12:00:03Z" })
+    timeline.append(events, { Action = "pass", Time = "2024-05-01T12:00:04Z" })
+
+    assert.are.equal(4, #events) -- output is not part of the timeline
+
+    local base = timeline.parse_time("2024-05-01T12:00:00Z")
+    assert.are.same({
+      { state = "running", start = base, stop = base + 1 },
+      { state = "paused", start = base + 1, stop = base + 3 },
+      { state = "running", start = base + 3, stop = base + 4 },
+    }, timeline.segments(events))
+  end)
+end)
+
+describe("timeline.render_package", function()
+  it("renders one bar per test, in the order they started", function()
+    -- Arrange
+    local saved_width = timeline.bar_width
+    timeline.bar_width = 4
+    local tests = {
+      {
+        test = "TestB",
+        status = "passed",
+        events = {
+          { action = "run", time = 2 },
+          { action = "pass", time = 4 },
+        },
+      },
+      {
+        test = "TestA",
+        status = "failed",
+        events = {
+          { action = "run", time = 0 },
+          { action = "pause", time = 1 },
+          { action = "cont", time = 2 },
+          { action = "fail", time = 3 },
+        },
+      },
+    }
+
+    -- Act
+    local lines = timeline.render_package("example.com/pkg", tests)
+    timeline.bar_width = saved_width
+
+    -- Assert
+    assert.are.same({
+      "example.com/pkg (4.00s wall clock)",
+      "  TestA |█░█ | +0.00s, ran 2.00s, paused 1.00s (failed)",
+      "  TestB |  ██| +2.00s, ran 2.00s, paused 0.00s (passed)",
+    }, lines)
+  end)
+
+  it("records and resets the last finished run", function()
+    local state = run_state.new()
+    run_state.with(state, function()
+      timeline.reset()
+      timeline.record("pkg", "TestA", {}, "passed")
+    end)
+    run_state.finish(state)
+    assert.are.same(
+      { pkg = { { test = "TestA", status = "passed", events = {} } } },
+      timeline.get()
+    )
+    run_state.with(state, timeline.reset)
+    assert.are.same({}, timeline.get())
+  end)
+end)
@@ -55,3 +55,9 @@ func ExampleCalculator_Add() {
 	fmt.Println(result)
 	// Output: 100
 }
+
+// Example without output comment (compiled, but never run)
+func ExampleCalculator() {
+	var c Calculator
+	fmt.Println(c.Add(1, 2))
+}
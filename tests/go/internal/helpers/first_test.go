@@ -0,0 +1,7 @@
+package helpers
+
+import "testing"
+
+func TestFirst(t *testing.T) {
+	check(t, "first")
+}
@@ -0,0 +1,7 @@
+package helpers
+
+import "testing"
+
+func TestSecond(t *testing.T) {
+	check(t, "second")
+}